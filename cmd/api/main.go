@@ -3,19 +3,57 @@ package main
 import (
 	"flag"
 	"log"
+	"mime"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 
+	"github.com/bbawn/boredgames/internal/dao"
 	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/dictionary"
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/rooms"
 	"github.com/bbawn/boredgames/internal/router"
 	"github.com/bbawn/boredgames/services"
 )
 
-var addr = flag.String("addr", ":8080", "http service address")
+var (
+	addr       = flag.String("addr", ":8080", "http service address")
+	adminToken = flag.String("admin-token", "", "bearer token granting the admin API role, disabled if empty")
+	roomTTL    = flag.Duration("room-ttl", 7*24*time.Hour, "idle time after which a room is deleted, never if 0")
+	gameTTL    = flag.Duration("game-ttl", 24*time.Hour, "idle time after which a game is deleted, never if 0")
+	sessionTTL = flag.Duration("session-ttl", 24*time.Hour, "idle time after which a session ends, releasing its username, never if 0")
+	sweepEvery = flag.Duration("sweep-interval", 10*time.Minute, "interval between sweeps for idle rooms and games")
+
+	presenceTimeout = flag.Duration("presence-timeout", 45*time.Second, "time without a heartbeat after which a user is disconnected, never if 0")
+	presenceRemove  = flag.Duration("presence-remove-after", 0, "time after which a disconnected user is removed from the room, never if 0")
+
+	dictDirs = flag.String("dict-dirs", "", "comma-separated directories of word lists, named <language code>.txt, replacing the built-in ones")
+)
+
+// acceptsEventStream returns true if the request accepts an event stream,
+// among any other media types it lists
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mt := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mt))
+			if err == nil && mediaType == "text/event-stream" {
+				return true
+			}
+		}
+	}
+	return false
+}
 
 func logHandler(fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Println("Request", r.Method, r.RequestURI)
+		if acceptsEventStream(r) {
+			// Event streams never complete, so can't be recorded
+			fn(w, r)
+			return
+		}
 		rec := httptest.NewRecorder()
 		fn(rec, r)
 		log.Println("Response StatusCode", rec.Result().StatusCode)
@@ -29,24 +67,55 @@ func logHandler(fn http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func newTableRouter() *router.TableRouter {
-	daoRooms := ram.NewRooms()
-	daoSets := ram.NewSets()
+func newTableRouter(daoRooms dao.Rooms, daoMessages dao.Messages, daoSets dao.Sets, daoBoggles dao.Boggles, daoRRobots dao.RRobots, daoActions dao.Actions, daoSessions dao.Sessions, dicts *dictionary.Registry) (*router.TableRouter, *services.Rooms, *services.Sessions) {
 	tr := new(router.TableRouter)
 
+	sessions := services.SessionsAddRoutes(daoSessions, tr)
+
 	// API routes
-	services.RoomsAddRoutes(daoRooms, tr)
-	services.SetsAddRoutes(daoSets, tr)
+	sets := services.SetsAddRoutes(daoSets, daoActions, daoRooms, tr)
+	boggles := services.BogglesAddRoutes(daoBoggles, daoActions, daoRooms, dicts, tr)
+	rrobots := services.RRobotsAddRoutes(daoRRobots, daoActions, daoRooms, tr)
+	types := games.NewRegistry()
+	types.Register(rooms.Set, sets.GameType())
+	types.Register(rooms.Boggle, boggles.GameType())
+	types.Register(rooms.RRobots, rrobots.GameType())
+	rms := services.RoomsAddRoutes(daoRooms, daoMessages, types, tr)
+	services.GamesAddRoutes(types, rms, daoActions, tr)
 
 	// static routes
 	tr.AddRoute("GET", "/.*", http.StripPrefix("/", http.FileServer(http.Dir("ui"))))
-	return tr
+	return tr, rms, sessions
 }
 
 func main() {
 	flag.Parse()
-	tr := newTableRouter()
-	srv := &http.Server{Addr: *addr, Handler: logHandler(tr.ServeHTTP)}
+	dicts, err := dictionary.NewRegistry()
+	if err != nil {
+		log.Fatalf("ERROR: failed to load built-in dictionaries: %s", err)
+	}
+	if *dictDirs != "" {
+		for _, dir := range strings.Split(*dictDirs, ",") {
+			if err := dicts.LoadDir(dir); err != nil {
+				log.Fatalf("ERROR: failed to load dictionaries from %s: %s", dir, err)
+			}
+		}
+	}
+	daoRooms := ram.NewRooms()
+	daoMessages := ram.NewMessages()
+	daoSets := ram.NewSets()
+	daoBoggles := ram.NewBoggles()
+	daoRRobots := ram.NewRRobots()
+	daoActions := ram.NewActions()
+	daoSessions := ram.NewSessions()
+	tr, rms, sessions := newTableRouter(daoRooms, daoMessages, daoSets, daoBoggles, daoRRobots, daoActions, daoSessions, dicts)
+	rp := &reaper{rooms: daoRooms, messages: daoMessages, sets: daoSets, boggles: daoBoggles, rrobots: daoRRobots, actions: daoActions, sessions: daoSessions, roomTTL: *roomTTL, gameTTL: *gameTTL, sessionTTL: *sessionTTL}
+	go rp.run(*sweepEvery)
+	if *presenceTimeout > 0 {
+		ps := &presenceSweeper{rooms: rms, timeout: *presenceTimeout, removeAfter: *presenceRemove}
+		go ps.run(*presenceTimeout / 3)
+	}
+	srv := &http.Server{Addr: *addr, Handler: logHandler(services.AdminAuth(*adminToken, sessions.Auth(tr)).ServeHTTP)}
 
 	log.Printf("INFO: ListenAndServe(): addr: %s", *addr)
 	if err := srv.ListenAndServe(); err != nil {
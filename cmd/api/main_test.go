@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestAcceptsEventStream(t *testing.T) {
+	g := NewGomegaWithT(t)
+	for accept, exp := range map[string]bool{
+		"":                                   false,
+		"application/json":                   false,
+		"text/event-stream":                  true,
+		"text/event-stream, */*":             true,
+		"text/html, text/event-stream;q=0.9": true,
+		"*/*":                                false,
+	} {
+		r := httptest.NewRequest("GET", "http://example.com/sets/x/events", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		g.Expect(acceptsEventStream(r)).To(Equal(exp), accept)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bbawn/boredgames/services"
+)
+
+// presenceSweeper periodically marks users who have stopped sending
+// heartbeats as disconnected and, optionally, removes them from their rooms
+type presenceSweeper struct {
+	rooms *services.Rooms
+	// timeout is the time without a heartbeat after which a user is
+	// disconnected
+	timeout time.Duration
+	// removeAfter is the time after which a disconnected user is removed
+	// from the room, never if zero
+	removeAfter time.Duration
+}
+
+// run sweeps at the given interval, forever
+func (ps *presenceSweeper) run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for now := range t.C {
+		ps.rooms.SweepPresence(now, ps.timeout, ps.removeAfter)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao"
+	"github.com/bbawn/boredgames/internal/rooms"
+)
+
+// reaper deletes rooms, games and sessions that have been idle for longer
+// than their TTLs, keeping the datastore bounded on a long-running server
+type reaper struct {
+	rooms    dao.Rooms
+	messages dao.Messages
+	sets     dao.Sets
+	boggles  dao.Boggles
+	rrobots  dao.RRobots
+	// actions are the logs of the games' actions, deleted with the games
+	actions  dao.Actions
+	sessions dao.Sessions
+	// roomTTL, gameTTL and sessionTTL are the idle times after which rooms,
+	// games and sessions are deleted, never if zero
+	roomTTL    time.Duration
+	gameTTL    time.Duration
+	sessionTTL time.Duration
+}
+
+// run sweeps at the given interval, forever
+func (rp *reaper) run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for now := range t.C {
+		rp.sweep(now)
+	}
+}
+
+// sweep deletes the rooms, games and sessions idle as of the given time
+func (rp *reaper) sweep(now time.Time) {
+	// Games first, so rooms no longer refer to expired games
+	if rp.gameTTL > 0 {
+		ids, err := rp.sets.Expire(now.Add(-rp.gameTTL))
+		if err != nil {
+			log.Printf("WARN: reaper: failed to expire set games: %s", err)
+		}
+		if len(ids) > 0 {
+			log.Printf("INFO: reaper: expired %d set games: %v", len(ids), ids)
+		}
+		rp.expired(ids)
+		ids, err = rp.boggles.Expire(now.Add(-rp.gameTTL))
+		if err != nil {
+			log.Printf("WARN: reaper: failed to expire boggle games: %s", err)
+		}
+		if len(ids) > 0 {
+			log.Printf("INFO: reaper: expired %d boggle games: %v", len(ids), ids)
+		}
+		rp.expired(ids)
+		ids, err = rp.rrobots.Expire(now.Add(-rp.gameTTL))
+		if err != nil {
+			log.Printf("WARN: reaper: failed to expire rrobots games: %s", err)
+		}
+		if len(ids) > 0 {
+			log.Printf("INFO: reaper: expired %d rrobots games: %v", len(ids), ids)
+		}
+		rp.expired(ids)
+	}
+	if rp.roomTTL > 0 {
+		rp.touchRooms()
+		names, err := rp.rooms.Expire(now.Add(-rp.roomTTL))
+		if err != nil {
+			log.Printf("WARN: reaper: failed to expire rooms: %s", err)
+		}
+		if len(names) > 0 {
+			log.Printf("INFO: reaper: expired %d rooms: %v", len(names), names)
+		}
+		for _, name := range names {
+			if err := rp.messages.Delete(name); err != nil {
+				log.Printf("WARN: reaper: failed to delete messages of expired room %s: %s", name, err)
+			}
+		}
+	}
+	if rp.sessionTTL > 0 {
+		usernames, err := rp.sessions.Expire(now.Add(-rp.sessionTTL))
+		if err != nil {
+			log.Printf("WARN: reaper: failed to expire sessions: %s", err)
+		}
+		if len(usernames) > 0 {
+			log.Printf("INFO: reaper: expired %d sessions: %v", len(usernames), usernames)
+		}
+	}
+}
+
+// expired deletes the action logs of the expired games and ends them in
+// the rooms playing them
+func (rp *reaper) expired(ids []uuid.UUID) {
+	for _, id := range ids {
+		if err := rp.actions.Delete(id); err != nil {
+			log.Printf("WARN: reaper: failed to delete actions of expired game %s: %s", id, err)
+		}
+		if _, err := rp.rooms.ClearGame(id); err != nil {
+			log.Printf("WARN: reaper: failed to clear expired game %s from rooms: %s", id, err)
+		}
+	}
+}
+
+// touchRooms records the last activity of each room's current game as
+// activity in the room, so a room is not idle while its game is played
+func (rp *reaper) touchRooms() {
+	rs, err := rp.rooms.List()
+	if err != nil {
+		log.Printf("WARN: reaper: failed to list rooms: %s", err)
+		return
+	}
+	for _, r := range rs {
+		if r.CurrentGame() == nil {
+			continue
+		}
+		t, err := rp.gameActivity(r.GameType, r.GameID)
+		if err != nil {
+			log.Printf("WARN: reaper: failed to get game %s of room %s: %s", r.GameID, r.Name, err)
+			continue
+		}
+		if _, err := rp.rooms.Touch(r.Name, t); err != nil {
+			log.Printf("WARN: reaper: failed to touch room %s: %s", r.Name, err)
+		}
+	}
+}
+
+// gameActivity returns the time the given game was last saved
+func (rp *reaper) gameActivity(typ rooms.GameType, id uuid.UUID) (time.Time, error) {
+	switch typ {
+	case rooms.Set:
+		g, err := rp.sets.Get(id)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return g.LastActivity, nil
+	case rooms.Boggle:
+		g, err := rp.boggles.Get(id)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return g.LastActivity, nil
+	case rooms.RRobots:
+		g, err := rp.rrobots.Get(id)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return g.LastActivity, nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported game type: %d", typ)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/games/set"
+	"github.com/bbawn/boredgames/internal/rooms"
+)
+
+func newTestReaper() *reaper {
+	return &reaper{
+		rooms:    ram.NewRooms(),
+		messages: ram.NewMessages(),
+		sets:     ram.NewSets(),
+		boggles:  ram.NewBoggles(),
+		rrobots:  ram.NewRRobots(),
+		actions:  ram.NewActions(),
+		sessions: ram.NewSessions(),
+	}
+}
+
+func TestReaperExpiredGame(t *testing.T) {
+	g := NewGomegaWithT(t)
+	rp := newTestReaper()
+	rp.roomTTL = 2 * time.Hour
+	rp.gameTTL = time.Hour
+
+	game, err := set.NewGame("p1", "p2")
+	g.Expect(err).To(BeNil())
+	g.Expect(rp.sets.Insert(game)).To(Succeed())
+	g.Expect(rp.actions.Insert(&games.Result{GameID: game.ID, Seq: 1})).To(Succeed())
+	g.Expect(rp.rooms.Insert(rooms.NewRoom("n1", map[string]bool{"p1": true, "p2": true}))).To(Succeed())
+	_, err = rp.rooms.SetGame("n1", rooms.Set, game.ID)
+	g.Expect(err).To(BeNil())
+
+	t.Log("Expiring the game ends it in the room, which remains")
+	rp.sweep(time.Now().Add(90 * time.Minute))
+	_, err = rp.sets.Get(game.ID)
+	g.Expect(err).NotTo(BeNil())
+	results, err := rp.actions.List(game.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(results).To(BeEmpty())
+	room, err := rp.rooms.Get("n1")
+	g.Expect(err).To(BeNil())
+	g.Expect(room.GameType).To(Equal(rooms.None))
+	g.Expect(room.CurrentGame()).To(BeNil())
+	g.Expect(room.Games).To(HaveLen(1))
+	g.Expect(room.Games[0].End).NotTo(BeNil())
+
+	t.Log("The room may start another game")
+	_, err = rp.rooms.SetGame("n1", rooms.Set, game.ID)
+	g.Expect(err).To(BeNil())
+}
+
+func TestReaperPlayedGame(t *testing.T) {
+	g := NewGomegaWithT(t)
+	rp := newTestReaper()
+	rp.roomTTL = time.Hour
+
+	g.Expect(rp.rooms.Insert(rooms.NewRoom("idle", map[string]bool{"p1": true}))).To(Succeed())
+	game, err := set.NewGame("p1", "p2")
+	g.Expect(err).To(BeNil())
+	g.Expect(rp.sets.Insert(game)).To(Succeed())
+	g.Expect(rp.rooms.Insert(rooms.NewRoom("n1", map[string]bool{"p1": true, "p2": true}))).To(Succeed())
+	room, err := rp.rooms.SetGame("n1", rooms.Set, game.ID)
+	g.Expect(err).To(BeNil())
+
+	t.Log("Playing the room's game after its last activity keeps the room")
+	g.Expect(rp.sets.Update(game)).To(Succeed())
+	game, err = rp.sets.Get(game.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.LastActivity.After(room.LastActivity)).To(BeTrue())
+	rp.sweep(room.LastActivity.Add(rp.roomTTL).Add(time.Nanosecond))
+	_, err = rp.rooms.Get("idle")
+	g.Expect(err).NotTo(BeNil())
+	room, err = rp.rooms.Get("n1")
+	g.Expect(err).To(BeNil())
+	g.Expect(room.LastActivity).To(Equal(game.LastActivity))
+}
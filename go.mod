@@ -1,15 +1,17 @@
 module github.com/bbawn/boredgames
 
-go 1.15
+go 1.16
 
 require (
 	github.com/go-delve/delve v1.5.1 // indirect
 	github.com/google/uuid v1.1.3
 	github.com/onsi/gomega v1.10.4
 	github.com/yuin/goldmark v1.3.1 // indirect
+	golang.org/x/crypto v0.0.0-20210220033148-5ea612d1eb83
 	golang.org/x/mod v0.4.1 // indirect
 	golang.org/x/net v0.0.0-20210119194325-5f4716e94777 // indirect
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a // indirect
 	golang.org/x/sys v0.0.0-20210124154548-22da62e12c0c // indirect
+	golang.org/x/text v0.3.3
 	golang.org/x/tools v0.1.0 // indirect
 )
@@ -0,0 +1,18 @@
+package dao
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/games"
+)
+
+// Actions provides persistence operations for the log of the actions
+// applied to games
+type Actions interface {
+	// Insert stores the result, setting its Seq to the next in its game
+	Insert(r *games.Result) error
+	// List returns the results of the game, in Seq order
+	List(gameID uuid.UUID) ([]*games.Result, error)
+	// Delete deletes all the results of the game
+	Delete(gameID uuid.UUID) error
+}
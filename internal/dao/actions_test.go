@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/games"
+)
+
+// TestRamActions tests the ram implementation of Actions
+func TestRamActions(t *testing.T) {
+	ram := ram.NewActions()
+	testActions(t, ram)
+}
+
+// testActions tests the given implementor of Actions
+func testActions(t *testing.T, as Actions) {
+	id0, id1 := uuid.New(), uuid.New()
+
+	// Empty list
+	results, err := as.List(id0)
+	if err != nil {
+		t.Errorf("List returned unexpected err %#v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("List returned %#v, expected none", results)
+	}
+
+	// Insert results of two games
+	now := time.Now().UTC()
+	var exp []*games.Result
+	for i, typ := range []string{"claim", "next", "expand"} {
+		version := i
+		r := &games.Result{
+			GameID:  id0,
+			Action:  games.Action{Type: typ, Player: "p0", Payload: json.RawMessage(`{"n":1}`), ClientVersion: &version, Seed: int64(i)},
+			Time:    now,
+			Version: i + 1,
+		}
+		err = as.Insert(r)
+		if err != nil {
+			t.Errorf("Unexpected err %s on Insert", err)
+		}
+		if r.Seq != i+1 {
+			t.Errorf("Insert set Seq %d, expected %d", r.Seq, i+1)
+		}
+		exp = append(exp, r)
+	}
+	r1 := &games.Result{GameID: id1, Action: games.Action{Type: "bid", Player: "p1"}, Time: now, Error: "no bids"}
+	err = as.Insert(r1)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Insert", err)
+	}
+	if r1.Seq != 1 {
+		t.Errorf("Insert set Seq %d, expected 1", r1.Seq)
+	}
+
+	results, err = as.List(id0)
+	if err != nil {
+		t.Errorf("List returned unexpected err %#v", err)
+	}
+	if !reflect.DeepEqual(results, exp) {
+		t.Errorf("List returned %#v, expected %#v", results, exp)
+	}
+	results, err = as.List(id1)
+	if err != nil {
+		t.Errorf("List returned unexpected err %#v", err)
+	}
+	if !reflect.DeepEqual(results, []*games.Result{r1}) {
+		t.Errorf("List returned %#v, expected %#v", results, []*games.Result{r1})
+	}
+
+	// Delete a game's results
+	err = as.Delete(id0)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Delete", err)
+	}
+	results, err = as.List(id0)
+	if err != nil {
+		t.Errorf("List returned unexpected err %#v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("List returned %#v, expected none", results)
+	}
+	results, err = as.List(id1)
+	if err != nil || len(results) != 1 {
+		t.Errorf("List returned %#v, %v, expected other game's result", results, err)
+	}
+}
@@ -0,0 +1,23 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/games/boggle"
+)
+
+// Boggles provides persistence operations for boggle games.
+// Implementations set a Game's LastActivity to the current time on each
+// write.
+type Boggles interface {
+	List() ([]*boggle.Game, error)
+	Insert(g *boggle.Game) error
+	Get(uuid uuid.UUID) (*boggle.Game, error)
+	Update(g *boggle.Game) error
+	Delete(uuid uuid.UUID) error
+	// Expire deletes the games with LastActivity before the given time and
+	// returns their IDs
+	Expire(before time.Time) ([]uuid.UUID, error)
+}
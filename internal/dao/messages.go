@@ -0,0 +1,17 @@
+package dao
+
+import (
+	"github.com/bbawn/boredgames/internal/rooms"
+)
+
+// Messages provides persistence operations for room chat messages
+type Messages interface {
+	// Insert stores the message, setting its ID to the next in its room
+	// and its Time to the current time
+	Insert(m *rooms.Message) error
+	// List returns the latest limit messages in the room with IDs less
+	// than before, or of all IDs if before is 0, oldest first
+	List(room string, before int64, limit int) ([]*rooms.Message, error)
+	// Delete deletes all the messages in the room
+	Delete(room string) error
+}
@@ -0,0 +1,103 @@
+package dao
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/rooms"
+)
+
+// TestRamMessages tests the ram implementation of Messages
+func TestRamMessages(t *testing.T) {
+	ram := ram.NewMessages()
+	testMessages(t, ram)
+}
+
+// testMessages tests the given implementor of Messages
+func testMessages(t *testing.T, ms Messages) {
+	// Empty list
+	msgs, err := ms.List("r0", 0, 10)
+	if err != nil {
+		t.Errorf("List returned unexpected err %#v", err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("List returned %#v, expected none", msgs)
+	}
+
+	// Insert messages in two rooms
+	var exp []*rooms.Message
+	for i, text := range []string{"hi", "hello", "how are you", "fine"} {
+		m, err := rooms.NewMessage("r0", "p0", text)
+		if err != nil {
+			t.Fatalf("Unexpected err %s on NewMessage", err)
+		}
+		err = ms.Insert(m)
+		if err != nil {
+			t.Errorf("Unexpected err %s on Insert", err)
+		}
+		if m.ID != int64(i+1) {
+			t.Errorf("Insert set ID %d, expected %d", m.ID, i+1)
+		}
+		if i > 0 && m.Time.Before(exp[i-1].Time) {
+			t.Errorf("Insert set Time %s before previous %s", m.Time, exp[i-1].Time)
+		}
+		exp = append(exp, m)
+	}
+	m1, _ := rooms.NewMessage("r1", "p1", "other room")
+	err = ms.Insert(m1)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Insert", err)
+	}
+	if m1.ID != 1 {
+		t.Errorf("Insert set ID %d, expected 1", m1.ID)
+	}
+
+	// List all, latest and earlier pages
+	pages := []struct {
+		before int64
+		limit  int
+		exp    []*rooms.Message
+	}{
+		{0, 0, exp},
+		{0, 10, exp},
+		{0, 2, exp[2:]},
+		{3, 2, exp[:2]},
+		{2, 2, exp[:1]},
+		{1, 2, []*rooms.Message{}},
+		{9, 0, exp},
+	}
+	for _, p := range pages {
+		msgs, err = ms.List("r0", p.before, p.limit)
+		if err != nil {
+			t.Errorf("List returned unexpected err %#v", err)
+		}
+		if !reflect.DeepEqual(msgs, p.exp) {
+			t.Errorf("List(%d, %d) returned %#v, expected %#v", p.before, p.limit, msgs, p.exp)
+		}
+	}
+	msgs, err = ms.List("r1", 0, 10)
+	if err != nil {
+		t.Errorf("List returned unexpected err %#v", err)
+	}
+	if !reflect.DeepEqual(msgs, []*rooms.Message{m1}) {
+		t.Errorf("List returned %#v, expected %#v", msgs, []*rooms.Message{m1})
+	}
+
+	// Delete a room's messages
+	err = ms.Delete("r0")
+	if err != nil {
+		t.Errorf("Unexpected err %s on Delete", err)
+	}
+	msgs, err = ms.List("r0", 0, 10)
+	if err != nil {
+		t.Errorf("List returned unexpected err %#v", err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("List returned %#v, expected none", msgs)
+	}
+	msgs, err = ms.List("r1", 0, 10)
+	if err != nil || len(msgs) != 1 {
+		t.Errorf("List returned %#v, %v, expected other room's message", msgs, err)
+	}
+}
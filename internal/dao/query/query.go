@@ -0,0 +1,100 @@
+// Package query defines the queries datastores support beyond lookup by
+// key, shared by the dao interfaces and their implementations
+package query
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bbawn/boredgames/internal/rooms"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// Order is the order of the results of a query
+type Order string
+
+const (
+	// ByName orders rooms by name, regardless of case
+	ByName Order = "name"
+	// ByActivity orders rooms most recently active first
+	ByActivity Order = "activity"
+	// BySize orders rooms with the most players first
+	BySize Order = "size"
+)
+
+// Rooms selects a page of rooms. Rooms with equal sort values are ordered
+// by name so pages are stable.
+type Rooms struct {
+	// Prefix selects the rooms with names starting with it, regardless of
+	// case
+	Prefix string
+	// GameType, if not nil, selects the rooms currently set to play it
+	GameType *rooms.GameType
+	// OpenSeats selects the rooms that are not full
+	OpenSeats bool
+	// Viewer is the user making the query. Private rooms are selected only
+	// if Viewer is in them, unless AllPrivate.
+	Viewer     string
+	AllPrivate bool
+	// Order is the order of the results, ByName if empty
+	Order Order
+	// After, if not nil, selects the rooms after this position in Order
+	After *Cursor
+	// Limit is the most rooms returned, 0 for no limit
+	Limit int
+}
+
+// Cursor is the position of a room in the results of a Rooms query: the
+// values it is sorted on
+type Cursor struct {
+	// Key is the validate.Key of the room name
+	Key      string    `json:"k"`
+	Activity time.Time `json:"a,omitempty"`
+	Size     int       `json:"s,omitempty"`
+}
+
+// Match returns whether the query selects the room, ignoring After and
+// Limit
+func (q *Rooms) Match(r *rooms.Room) bool {
+	if q.Prefix != "" && !strings.HasPrefix(validate.Key(r.Name), validate.Key(q.Prefix)) {
+		return false
+	}
+	if q.GameType != nil && r.GameType != *q.GameType {
+		return false
+	}
+	if q.OpenSeats && r.Full() {
+		return false
+	}
+	if r.Private && !q.AllPrivate && r.Role(q.Viewer) == "" {
+		return false
+	}
+	return true
+}
+
+// Cursor returns the position of the room in the query's Order
+func (q *Rooms) Cursor(r *rooms.Room) Cursor {
+	c := Cursor{Key: validate.Key(r.Name)}
+	switch q.Order {
+	case ByActivity:
+		c.Activity = r.LastActivity
+	case BySize:
+		c.Size = len(r.Usernames)
+	}
+	return c
+}
+
+// Less returns whether position a comes before position b in the query's
+// Order
+func (q *Rooms) Less(a, b Cursor) bool {
+	switch q.Order {
+	case ByActivity:
+		if !a.Activity.Equal(b.Activity) {
+			return a.Activity.After(b.Activity)
+		}
+	case BySize:
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+	}
+	return a.Key < b.Key
+}
@@ -0,0 +1,58 @@
+package ram
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/games"
+)
+
+// Actions is the collection of fake dao game action results
+type Actions struct {
+	m sync.RWMutex
+	// results stores json-serialized Results keyed on game ID, in Seq
+	// order
+	results map[uuid.UUID][][]byte
+}
+
+func NewActions() *Actions {
+	return &Actions{results: make(map[uuid.UUID][][]byte)}
+}
+
+func (as *Actions) Insert(r *games.Result) error {
+	as.m.Lock()
+	defer as.m.Unlock()
+	r.Seq = len(as.results[r.GameID]) + 1
+	jResult, err := json.Marshal(r)
+	if err != nil {
+		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json result: %s/%d err %s", r.GameID, r.Seq, err)}
+	}
+	as.results[r.GameID] = append(as.results[r.GameID], jResult)
+	return nil
+}
+
+func (as *Actions) List(gameID uuid.UUID) ([]*games.Result, error) {
+	as.m.RLock()
+	defer as.m.RUnlock()
+	// Empty slice, not nil so we can always unmarshal to json array
+	results := []*games.Result{}
+	for _, jResult := range as.results[gameID] {
+		var r *games.Result
+		if err := json.Unmarshal(jResult, &r); err != nil {
+			return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json result: %s err: %s", jResult, err)}
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (as *Actions) Delete(gameID uuid.UUID) error {
+	as.m.Lock()
+	defer as.m.Unlock()
+	delete(as.results, gameID)
+	return nil
+}
@@ -0,0 +1,127 @@
+package ram
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/games/boggle"
+)
+
+type Boggles struct {
+	m sync.RWMutex
+	// games stores json-serialized boggle Games
+	// This avoids shared-object confusion if we used unserialized Games
+	games map[uuid.UUID][]byte
+}
+
+func NewBoggles() *Boggles {
+	return &Boggles{games: make(map[uuid.UUID][]byte)}
+}
+
+func (bs *Boggles) List() ([]*boggle.Game, error) {
+	// Empty slice, not nil so we can always unmarshal to json array
+	gs := []*boggle.Game{}
+	bs.m.Lock()
+	defer bs.m.Unlock()
+	for _, jGame := range bs.games {
+		var g *boggle.Game
+		err := json.Unmarshal(jGame, &g)
+		if err != nil {
+			return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s", jGame)}
+		}
+		gs = append(gs, g)
+	}
+	return gs, nil
+}
+
+func (bs *Boggles) Insert(g *boggle.Game) error {
+	bs.m.Lock()
+	defer bs.m.Unlock()
+	_, ok := bs.games[g.ID]
+	if ok {
+		return errors.AlreadyExistsError{Key: g.ID.String()}
+	}
+	g.LastActivity = now()
+	jGame, err := json.Marshal(g)
+	if err != nil {
+		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s err %s", g.ID, err)}
+	}
+	bs.games[g.ID] = jGame
+	return nil
+}
+
+func (bs *Boggles) Get(uuid uuid.UUID) (*boggle.Game, error) {
+	bs.m.Lock()
+	defer bs.m.Unlock()
+	jGame, ok := bs.games[uuid]
+	if !ok {
+		return nil, errors.NotFoundError{Key: uuid.String()}
+	}
+	var g *boggle.Game
+	err := json.Unmarshal(jGame, &g)
+	if err != nil {
+		return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s err: %s", jGame, err)}
+	}
+	return g, nil
+}
+
+func (bs *Boggles) Update(g *boggle.Game) error {
+	bs.m.Lock()
+	defer bs.m.Unlock()
+	_, ok := bs.games[g.ID]
+	if !ok {
+		return errors.NotFoundError{Key: g.ID.String()}
+	}
+	g.LastActivity = now()
+	jGame, err := json.Marshal(g)
+	if err != nil {
+		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s", g.ID)}
+	}
+	bs.games[g.ID] = jGame
+	return nil
+}
+
+func (bs *Boggles) Delete(uuid uuid.UUID) error {
+	bs.m.Lock()
+	defer bs.m.Unlock()
+	if _, ok := bs.games[uuid]; !ok {
+		return errors.NotFoundError{Key: uuid.String()}
+	}
+	delete(bs.games, uuid)
+	return nil
+}
+
+func (bs *Boggles) Expire(before time.Time) ([]uuid.UUID, error) {
+	// Empty slice, not nil so we can always unmarshal to json array
+	ids := []uuid.UUID{}
+	bs.m.Lock()
+	defer bs.m.Unlock()
+	for id, jGame := range bs.games {
+		var g *boggle.Game
+		err := json.Unmarshal(jGame, &g)
+		if err != nil {
+			return ids, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s err: %s", jGame, err)}
+		}
+		if g.LastActivity.Before(before) {
+			delete(bs.games, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (bs *Boggles) Dump() string {
+	var b strings.Builder
+	bs.m.Lock()
+	defer bs.m.Unlock()
+	for uuid, game := range bs.games {
+		b.WriteString(fmt.Sprintf("uuid %s: game %s\n", uuid, game))
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package ram
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/rooms"
+)
+
+// Messages is the collection of fake dao room chat messages
+type Messages struct {
+	m sync.RWMutex
+	// messages stores json-serialized Messages keyed on room name, in ID
+	// order
+	messages map[string][][]byte
+	// lastIDs are the IDs of the last message inserted in each room
+	lastIDs map[string]int64
+}
+
+func NewMessages() *Messages {
+	return &Messages{messages: make(map[string][][]byte), lastIDs: make(map[string]int64)}
+}
+
+func (ms *Messages) Insert(m *rooms.Message) error {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	m.ID = ms.lastIDs[m.Room] + 1
+	m.Time = now()
+	jMessage, err := json.Marshal(m)
+	if err != nil {
+		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json message: %d err %s", m.ID, err)}
+	}
+	ms.lastIDs[m.Room] = m.ID
+	ms.messages[m.Room] = append(ms.messages[m.Room], jMessage)
+	return nil
+}
+
+func (ms *Messages) List(room string, before int64, limit int) ([]*rooms.Message, error) {
+	ms.m.RLock()
+	defer ms.m.RUnlock()
+	jMessages := ms.messages[room]
+	// IDs are consecutive from the first message still stored
+	end := len(jMessages)
+	if before > 0 && len(jMessages) > 0 {
+		var first *rooms.Message
+		if err := json.Unmarshal(jMessages[0], &first); err != nil {
+			return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json message: %s err: %s", jMessages[0], err)}
+		}
+		if i := int(before - first.ID); i < end {
+			end = i
+		}
+		if end < 0 {
+			end = 0
+		}
+	}
+	start := 0
+	if limit > 0 && end-limit > 0 {
+		start = end - limit
+	}
+	// Empty slice, not nil so we can always unmarshal to json array
+	msgs := []*rooms.Message{}
+	for _, jMessage := range jMessages[start:end] {
+		var m *rooms.Message
+		if err := json.Unmarshal(jMessage, &m); err != nil {
+			return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json message: %s err: %s", jMessage, err)}
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+func (ms *Messages) Delete(room string) error {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	delete(ms.messages, room)
+	delete(ms.lastIDs, room)
+	return nil
+}
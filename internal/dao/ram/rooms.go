@@ -3,19 +3,24 @@ package ram
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/dao/query"
 	"github.com/bbawn/boredgames/internal/rooms"
+	"github.com/bbawn/boredgames/internal/validate"
 )
 
 // Rooms is the collection of fake dao roomms
 type Rooms struct {
 	m sync.RWMutex
-	// rooms stores json-serialized set Rooms keyed on name
+	// rooms stores json-serialized set Rooms keyed on the validate.Key of
+	// their names, so names differing only in case name the same room
 	// This avoids future shared-object confusion if we used unserialized Rooms
 	rooms map[string][]byte
 }
@@ -30,124 +35,277 @@ func (rms *Rooms) List() ([]*rooms.Room, error) {
 	rms.m.Lock()
 	defer rms.m.Unlock()
 	for _, jRoom := range rms.rooms {
-		var r *rooms.Room
-		err := json.Unmarshal(jRoom, &r)
+		r, err := unmarshalRoom(jRoom)
 		if err != nil {
-			return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s", jRoom)}
+			return nil, err
 		}
 		rs = append(rs, r)
 	}
 	return rs, nil
 }
 
+func (rms *Rooms) Query(q query.Rooms) ([]*rooms.Room, *query.Cursor, error) {
+	// Empty slice, not nil so we can always unmarshal to json array
+	rs := []*rooms.Room{}
+	rms.m.Lock()
+	defer rms.m.Unlock()
+	for _, jRoom := range rms.rooms {
+		r, err := unmarshalRoom(jRoom)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !q.Match(r) || (q.After != nil && !q.Less(*q.After, q.Cursor(r))) {
+			continue
+		}
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return q.Less(q.Cursor(rs[i]), q.Cursor(rs[j]))
+	})
+	if q.Limit == 0 || len(rs) <= q.Limit {
+		return rs, nil, nil
+	}
+	rs = rs[:q.Limit]
+	next := q.Cursor(rs[len(rs)-1])
+	return rs, &next, nil
+}
+
 func (rms *Rooms) Insert(r *rooms.Room) error {
 	rms.m.Lock()
 	defer rms.m.Unlock()
-	_, ok := rms.rooms[r.Name]
+	_, ok := rms.rooms[validate.Key(r.Name)]
 	if ok {
 		return errors.AlreadyExistsError{Key: r.Name}
 	}
-	jRoom, err := json.Marshal(r)
-	if err != nil {
-		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s err %s", r.Name, err)}
-	}
-	rms.rooms[r.Name] = jRoom
-	return nil
+	r.LastActivity = now()
+	return rms.put(r)
 }
 
 func (rms *Rooms) Get(name string) (*rooms.Room, error) {
 	rms.m.Lock()
 	defer rms.m.Unlock()
-	jRoom, ok := rms.rooms[name]
-	if !ok {
-		return nil, errors.NotFoundError{Key: name}
-	}
-	var r *rooms.Room
-	err := json.Unmarshal(jRoom, &r)
-	if err != nil {
-		return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s err: %s", jRoom, err)}
-	}
-	return r, nil
+	return rms.get(name)
 }
 
 func (rms *Rooms) Delete(name string) error {
 	rms.m.Lock()
 	defer rms.m.Unlock()
-	if _, ok := rms.rooms[name]; !ok {
+	if _, ok := rms.rooms[validate.Key(name)]; !ok {
 		return errors.NotFoundError{Key: name}
 	}
-	delete(rms.rooms, name)
+	delete(rms.rooms, validate.Key(name))
 	return nil
 }
 
 func (rms *Rooms) AddPlayer(name, username string) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		if _, ok := r.Usernames[username]; ok || r.Waiting(username) {
+			return errors.AlreadyExistsError{Key: username}
+		}
+		r.AddPlayer(username)
+		return nil
+	})
+}
+
+func (rms *Rooms) DeletePlayer(name, username string) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		if _, ok := r.Roles[username]; !ok && !r.Usernames[username] && !r.Waiting(username) {
+			return errors.NotFoundError{Key: username}
+		}
+		r.RemoveUser(username)
+		return nil
+	})
+}
+
+func (rms *Rooms) SetMaxPlayers(name string, max int) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		r.SetMaxPlayers(max)
+		return nil
+	})
+}
+
+func (rms *Rooms) Heartbeat(name, username string, idle bool) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		if r.Role(username) == "" && !r.Waiting(username) {
+			return errors.NotFoundError{Key: username}
+		}
+		r.Heartbeat(username, idle, now())
+		return nil
+	})
+}
+
+func (rms *Rooms) SetGame(name string, typ rooms.GameType, id uuid.UUID) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		r.StartGame(typ, id, now())
+		return nil
+	})
+}
+
+func (rms *Rooms) EndGame(name string, scores map[string]int) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		if !r.EndGame(scores, now()) {
+			return errors.NotFoundError{Key: name + "/game"}
+		}
+		return nil
+	})
+}
+
+func (rms *Rooms) ClearGame(id uuid.UUID) ([]*rooms.Room, error) {
+	return rms.updateAll(func(r *rooms.Room) bool {
+		if cur := r.CurrentGame(); cur == nil || cur.GameID != id {
+			return false
+		}
+		r.EndGame(nil, now())
+		return true
+	})
+}
+
+func (rms *Rooms) Touch(name string, t time.Time) (*rooms.Room, error) {
 	rms.m.Lock()
 	defer rms.m.Unlock()
-	jRoom, ok := rms.rooms[name]
-	if !ok {
-		return nil, errors.NotFoundError{Key: name}
-	}
-	var r *rooms.Room
-	err := json.Unmarshal(jRoom, &r)
+	r, err := rms.get(name)
 	if err != nil {
-		return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s err: %s", jRoom, err)}
+		return nil, err
 	}
-	if _, ok := r.Usernames[username]; ok {
-		return nil, errors.AlreadyExistsError{Key: username}
+	if !t.After(r.LastActivity) {
+		return r, nil
 	}
-	r.Usernames[username] = true
-	jRoom, err = json.Marshal(r)
-	if err != nil {
-		return r, errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s err %s", r.Name, err)}
-	}
-	rms.rooms[r.Name] = jRoom
-	return r, nil
+	r.LastActivity = t
+	return r, rms.put(r)
 }
 
-func (rms *Rooms) DeletePlayer(name, username string) (*rooms.Room, error) {
+func (rms *Rooms) SetRole(name, username string, role rooms.Role) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		if !r.SetRole(username, role) {
+			return errors.NotFoundError{Key: username}
+		}
+		return nil
+	})
+}
+
+func (rms *Rooms) SetOwner(name, username string) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		if !r.SetOwner(username) {
+			return errors.NotFoundError{Key: username}
+		}
+		return nil
+	})
+}
+
+func (rms *Rooms) SetAccess(name string, private bool, passwordHash string) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		r.SetAccess(private, passwordHash)
+		return nil
+	})
+}
+
+func (rms *Rooms) AddInvite(name, hash string) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		r.AddInvite(hash, now())
+		return nil
+	})
+}
+
+func (rms *Rooms) RevokeInvite(name, hash string) (*rooms.Room, error) {
+	return rms.update(name, func(r *rooms.Room) error {
+		if !r.RevokeInvite(hash) {
+			return errors.NotFoundError{Key: name + "/invites/" + hash}
+		}
+		return nil
+	})
+}
+
+func (rms *Rooms) Disconnect(before time.Time) ([]*rooms.Room, error) {
+	t := now()
+	return rms.updateAll(func(r *rooms.Room) bool {
+		return len(r.Disconnect(before, t)) > 0
+	})
+}
+
+func (rms *Rooms) Expire(before time.Time) ([]string, error) {
+	// Empty slice, not nil so we can always unmarshal to json array
+	names := []string{}
 	rms.m.Lock()
 	defer rms.m.Unlock()
-	jRoom, ok := rms.rooms[name]
-	if !ok {
-		return nil, errors.NotFoundError{Key: name}
+	for key, jRoom := range rms.rooms {
+		r, err := unmarshalRoom(jRoom)
+		if err != nil {
+			return names, err
+		}
+		if r.LastActivity.Before(before) {
+			delete(rms.rooms, key)
+			names = append(names, r.Name)
+		}
 	}
-	var r *rooms.Room
-	err := json.Unmarshal(jRoom, &r)
+	return names, nil
+}
+
+// update applies f to the named room and, unless it returns an error,
+// records the activity and stores the room. The caller must not hold m.
+func (rms *Rooms) update(name string, f func(r *rooms.Room) error) (*rooms.Room, error) {
+	rms.m.Lock()
+	defer rms.m.Unlock()
+	r, err := rms.get(name)
 	if err != nil {
-		return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s err: %s", jRoom, err)}
-	}
-	if _, ok := r.Usernames[username]; !ok {
-		return nil, errors.NotFoundError{Key: username}
+		return nil, err
 	}
-	// Remove element from players
-	delete(r.Usernames, username)
-	jRoom, err = json.Marshal(r)
+	err = f(r)
 	if err != nil {
-		return r, errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s err %s", r.Name, err)}
+		return nil, err
 	}
-	rms.rooms[r.Name] = jRoom
-	return r, nil
+	r.LastActivity = now()
+	return r, rms.put(r)
 }
 
-func (rms *Rooms) SetGame(name string, typ rooms.GameType, id uuid.UUID) (*rooms.Room, error) {
+// updateAll applies f to every room, stores those it reports changed and
+// returns them. This is not activity, so leaves LastActivity unchanged.
+func (rms *Rooms) updateAll(f func(r *rooms.Room) bool) ([]*rooms.Room, error) {
+	// Empty slice, not nil so we can always unmarshal to json array
+	rs := []*rooms.Room{}
 	rms.m.Lock()
 	defer rms.m.Unlock()
-	jRoom, ok := rms.rooms[name]
+	for _, jRoom := range rms.rooms {
+		r, err := unmarshalRoom(jRoom)
+		if err != nil {
+			return rs, err
+		}
+		if !f(r) {
+			continue
+		}
+		err = rms.put(r)
+		if err != nil {
+			return rs, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// get returns the named room. The caller must hold m.
+func (rms *Rooms) get(name string) (*rooms.Room, error) {
+	jRoom, ok := rms.rooms[validate.Key(name)]
 	if !ok {
 		return nil, errors.NotFoundError{Key: name}
 	}
-	var r *rooms.Room
-	err := json.Unmarshal(jRoom, &r)
+	return unmarshalRoom(jRoom)
+}
+
+// put stores the room. The caller must hold m.
+func (rms *Rooms) put(r *rooms.Room) error {
+	jRoom, err := json.Marshal(r)
 	if err != nil {
-		return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s err: %s", jRoom, err)}
+		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json room: %s err %s", r.Name, err)}
 	}
-	r.GameType = typ
-	r.GameID = id
-	jRoom, err = json.Marshal(r)
+	rms.rooms[validate.Key(r.Name)] = jRoom
+	return nil
+}
+
+func unmarshalRoom(jRoom []byte) (*rooms.Room, error) {
+	var r *rooms.Room
+	err := json.Unmarshal(jRoom, &r)
 	if err != nil {
-		return r, errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s err %s", r.Name, err)}
+		return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json room: %s err: %s", jRoom, err)}
 	}
-	rms.rooms[r.Name] = jRoom
 	return r, nil
 }
 
@@ -0,0 +1,127 @@
+package ram
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/games/rrobots"
+)
+
+type RRobots struct {
+	m sync.RWMutex
+	// games stores json-serialized rrobots Games
+	// This avoids shared-object confusion if we used unserialized Games
+	games map[uuid.UUID][]byte
+}
+
+func NewRRobots() *RRobots {
+	return &RRobots{games: make(map[uuid.UUID][]byte)}
+}
+
+func (rs *RRobots) List() ([]*rrobots.Game, error) {
+	// Empty slice, not nil so we can always unmarshal to json array
+	gs := []*rrobots.Game{}
+	rs.m.Lock()
+	defer rs.m.Unlock()
+	for _, jGame := range rs.games {
+		var g *rrobots.Game
+		err := json.Unmarshal(jGame, &g)
+		if err != nil {
+			return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s", jGame)}
+		}
+		gs = append(gs, g)
+	}
+	return gs, nil
+}
+
+func (rs *RRobots) Insert(g *rrobots.Game) error {
+	rs.m.Lock()
+	defer rs.m.Unlock()
+	_, ok := rs.games[g.ID]
+	if ok {
+		return errors.AlreadyExistsError{Key: g.ID.String()}
+	}
+	g.LastActivity = now()
+	jGame, err := json.Marshal(g)
+	if err != nil {
+		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s err %s", g.ID, err)}
+	}
+	rs.games[g.ID] = jGame
+	return nil
+}
+
+func (rs *RRobots) Get(uuid uuid.UUID) (*rrobots.Game, error) {
+	rs.m.Lock()
+	defer rs.m.Unlock()
+	jGame, ok := rs.games[uuid]
+	if !ok {
+		return nil, errors.NotFoundError{Key: uuid.String()}
+	}
+	var g *rrobots.Game
+	err := json.Unmarshal(jGame, &g)
+	if err != nil {
+		return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s err: %s", jGame, err)}
+	}
+	return g, nil
+}
+
+func (rs *RRobots) Update(g *rrobots.Game) error {
+	rs.m.Lock()
+	defer rs.m.Unlock()
+	_, ok := rs.games[g.ID]
+	if !ok {
+		return errors.NotFoundError{Key: g.ID.String()}
+	}
+	g.LastActivity = now()
+	jGame, err := json.Marshal(g)
+	if err != nil {
+		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s", g.ID)}
+	}
+	rs.games[g.ID] = jGame
+	return nil
+}
+
+func (rs *RRobots) Delete(uuid uuid.UUID) error {
+	rs.m.Lock()
+	defer rs.m.Unlock()
+	if _, ok := rs.games[uuid]; !ok {
+		return errors.NotFoundError{Key: uuid.String()}
+	}
+	delete(rs.games, uuid)
+	return nil
+}
+
+func (rs *RRobots) Expire(before time.Time) ([]uuid.UUID, error) {
+	// Empty slice, not nil so we can always unmarshal to json array
+	ids := []uuid.UUID{}
+	rs.m.Lock()
+	defer rs.m.Unlock()
+	for id, jGame := range rs.games {
+		var g *rrobots.Game
+		err := json.Unmarshal(jGame, &g)
+		if err != nil {
+			return ids, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s err: %s", jGame, err)}
+		}
+		if g.LastActivity.Before(before) {
+			delete(rs.games, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (rs *RRobots) Dump() string {
+	var b strings.Builder
+	rs.m.Lock()
+	defer rs.m.Unlock()
+	for uuid, game := range rs.games {
+		b.WriteString(fmt.Sprintf("uuid %s: game %s\n", uuid, game))
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package ram
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/sessions"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// Sessions is the collection of fake dao user sessions
+type Sessions struct {
+	m sync.RWMutex
+	// sessions stores json-serialized Sessions keyed on ID
+	sessions map[string][]byte
+	// ids are the IDs of the sessions keyed on the validate.Key of their
+	// usernames, so a username has at most one session
+	ids map[string]string
+}
+
+func NewSessions() *Sessions {
+	return &Sessions{sessions: make(map[string][]byte), ids: make(map[string]string)}
+}
+
+func (ss *Sessions) Insert(s *sessions.Session) error {
+	ss.m.Lock()
+	defer ss.m.Unlock()
+	if _, ok := ss.ids[validate.Key(s.Username)]; ok {
+		return errors.AlreadyExistsError{Key: s.Username}
+	}
+	if _, ok := ss.sessions[s.ID]; ok {
+		return errors.AlreadyExistsError{Key: s.ID}
+	}
+	s.Created = now()
+	s.LastActivity = s.Created
+	jSession, err := json.Marshal(s)
+	if err != nil {
+		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json session: %s err %s", s.Username, err)}
+	}
+	ss.sessions[s.ID] = jSession
+	ss.ids[validate.Key(s.Username)] = s.ID
+	return nil
+}
+
+func (ss *Sessions) Get(id string) (*sessions.Session, error) {
+	ss.m.RLock()
+	defer ss.m.RUnlock()
+	return ss.get(id)
+}
+
+// get returns the session with the given ID, the lock being held
+func (ss *Sessions) get(id string) (*sessions.Session, error) {
+	jSession, ok := ss.sessions[id]
+	if !ok {
+		return nil, errors.NotFoundError{Key: id}
+	}
+	var s *sessions.Session
+	err := json.Unmarshal(jSession, &s)
+	if err != nil {
+		return nil, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json session: %s err: %s", jSession, err)}
+	}
+	return s, nil
+}
+
+func (ss *Sessions) Touch(id string) (*sessions.Session, error) {
+	ss.m.Lock()
+	defer ss.m.Unlock()
+	s, err := ss.get(id)
+	if err != nil {
+		return nil, err
+	}
+	s.LastActivity = now()
+	jSession, err := json.Marshal(s)
+	if err != nil {
+		return s, errors.InternalError{Details: fmt.Sprintf("Could not Marshal json session: %s err %s", s.Username, err)}
+	}
+	ss.sessions[id] = jSession
+	return s, nil
+}
+
+func (ss *Sessions) Delete(id string) error {
+	ss.m.Lock()
+	defer ss.m.Unlock()
+	s, err := ss.get(id)
+	if err != nil {
+		return err
+	}
+	delete(ss.sessions, id)
+	delete(ss.ids, validate.Key(s.Username))
+	return nil
+}
+
+func (ss *Sessions) Expire(before time.Time) ([]string, error) {
+	// Empty slice, not nil so we can always unmarshal to json array
+	usernames := []string{}
+	ss.m.Lock()
+	defer ss.m.Unlock()
+	for id := range ss.sessions {
+		s, err := ss.get(id)
+		if err != nil {
+			return usernames, err
+		}
+		if s.LastActivity.Before(before) {
+			delete(ss.sessions, id)
+			delete(ss.ids, validate.Key(s.Username))
+			usernames = append(usernames, s.Username)
+		}
+	}
+	return usernames, nil
+}
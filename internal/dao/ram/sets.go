@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -46,6 +47,7 @@ func (s *Sets) Insert(g *set.Game) error {
 	if ok {
 		return errors.AlreadyExistsError{Key: g.ID.String()}
 	}
+	g.LastActivity = now()
 	jGame, err := json.Marshal(g)
 	if err != nil {
 		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s err %s", g.ID, err)}
@@ -76,6 +78,7 @@ func (s *Sets) Update(g *set.Game) error {
 	if !ok {
 		return errors.NotFoundError{Key: g.ID.String()}
 	}
+	g.LastActivity = now()
 	jGame, err := json.Marshal(g)
 	if err != nil {
 		return errors.InternalError{Details: fmt.Sprintf("Could not Marshal json game: %s", g.ID)}
@@ -94,6 +97,25 @@ func (s *Sets) Delete(uuid uuid.UUID) error {
 	return nil
 }
 
+func (s *Sets) Expire(before time.Time) ([]uuid.UUID, error) {
+	// Empty slice, not nil so we can always unmarshal to json array
+	ids := []uuid.UUID{}
+	s.m.Lock()
+	defer s.m.Unlock()
+	for id, jGame := range s.sets {
+		var g *set.Game
+		err := json.Unmarshal(jGame, &g)
+		if err != nil {
+			return ids, errors.InternalError{Details: fmt.Sprintf("Could not Unmarshal json game: %s err: %s", jGame, err)}
+		}
+		if g.LastActivity.Before(before) {
+			delete(s.sets, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 func (s *Sets) Dump() string {
 	var b strings.Builder
 	s.m.Lock()
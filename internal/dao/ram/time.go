@@ -0,0 +1,11 @@
+package ram
+
+import (
+	"time"
+)
+
+// now returns the current time as it will be after a json round trip, so
+// objects returned from writes compare equal to those later retrieved
+func now() time.Time {
+	return time.Now().UTC().Round(0)
+}
@@ -1,18 +1,66 @@
 package dao
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 
+	"github.com/bbawn/boredgames/internal/dao/query"
 	"github.com/bbawn/boredgames/internal/rooms"
 )
 
-// Rooms provides persistence operations for game rooms
+// Rooms provides persistence operations for game rooms. Implementations set
+// a Room's LastActivity to the current time on each write.
 type Rooms interface {
 	List() ([]*rooms.Room, error)
+	// Query returns the page of rooms selected by the query, in its order,
+	// and the cursor of the last if there are more, else nil
+	Query(q query.Rooms) ([]*rooms.Room, *query.Cursor, error)
 	Insert(r *rooms.Room) error
 	Get(name string) (*rooms.Room, error)
 	Delete(name string) error
+	// AddPlayer adds a player to the room or, if the room is full, to the
+	// end of its waitlist
 	AddPlayer(name, username string) (*rooms.Room, error)
+	// DeletePlayer removes a user from the room or its waitlist, promoting
+	// waiting users into any place freed
 	DeletePlayer(name, username string) (*rooms.Room, error)
+	// SetMaxPlayers sets the room's player limit, 0 for none, promoting
+	// waiting users into any places opened
+	SetMaxPlayers(name string, max int) (*rooms.Room, error)
+	// SetGame makes the given game the room's current game, starting it
+	// now, and ends any other game in progress without results
 	SetGame(name string, typ rooms.GameType, id uuid.UUID) (*rooms.Room, error)
+	// EndGame ends the room's current game now with the given final scores
+	EndGame(name string, scores map[string]int) (*rooms.Room, error)
+	// ClearGame ends now without results the given game in every room whose
+	// current game it is, as when the game has expired, and returns the
+	// rooms changed. This is not activity, so leaves LastActivity unchanged.
+	ClearGame(id uuid.UUID) ([]*rooms.Room, error)
+	// Touch records activity in the room at the given time, as when its
+	// game was played, unless its LastActivity is already later
+	Touch(name string, t time.Time) (*rooms.Room, error)
+	// SetRole sets the role of a user already in the room, other than the
+	// owner, to moderator, player or spectator
+	SetRole(name, username string, role rooms.Role) (*rooms.Room, error)
+	// SetOwner transfers ownership of the room to a user already in it
+	SetOwner(name, username string) (*rooms.Room, error)
+	// SetAccess sets whether the room is private and the hash of its
+	// password, if any
+	SetAccess(name string, private bool, passwordHash string) (*rooms.Room, error)
+	// AddInvite adds an invite code, given by its hash, to the room
+	AddInvite(name, hash string) (*rooms.Room, error)
+	// RevokeInvite deletes an invite code, given by its hash, from the
+	// room, or all of them if hash is empty
+	RevokeInvite(name, hash string) (*rooms.Room, error)
+	// Heartbeat records a heartbeat now from a user in the room or its
+	// waitlist, reporting whether they are idle
+	Heartbeat(name, username string, idle bool) (*rooms.Room, error)
+	// Disconnect marks as disconnected now the users in every room whose
+	// last heartbeat was before the given time and returns the rooms
+	// changed. This is not activity, so leaves LastActivity unchanged.
+	Disconnect(before time.Time) ([]*rooms.Room, error)
+	// Expire deletes the rooms with LastActivity before the given time and
+	// returns their names
+	Expire(before time.Time) ([]string, error)
 }
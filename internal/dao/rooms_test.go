@@ -3,10 +3,12 @@ package dao
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/dao/query"
 	"github.com/bbawn/boredgames/internal/dao/ram"
 	"github.com/bbawn/boredgames/internal/rooms"
 )
@@ -17,6 +19,12 @@ func TestRamRooms(t *testing.T) {
 	testRooms(t, ram)
 }
 
+// TestRamRoomsQuery tests the ram implementation of Rooms.Query
+func TestRamRoomsQuery(t *testing.T) {
+	ram := ram.NewRooms()
+	testRoomsQuery(t, ram)
+}
+
 // testRooms tests the given implementor of Rooms
 func testRooms(t *testing.T, rms Rooms) {
 	// Empty list
@@ -43,6 +51,22 @@ func testRooms(t *testing.T, rms Rooms) {
 		t.Errorf("Expected err %s to be of type AlreadyExistsError", err)
 	}
 
+	// Insert of a case variant name fails
+	err = rms.Insert(rooms.NewRoom("R0", map[string]bool{}))
+	_, ok = err.(errors.AlreadyExistsError)
+	if !ok {
+		t.Errorf("Expected case variant Insert err %s to be of type AlreadyExistsError", err)
+	}
+
+	// Get by case variant name returns the room
+	r, err := rms.Get("R0")
+	if err != nil {
+		t.Errorf("Unexpected err %s on case variant Get", err)
+	}
+	if r == nil || r.Name != r0.Name {
+		t.Errorf("Case variant Get returned %#v, expected room %s", r, r0.Name)
+	}
+
 	// Insert room without players
 	r1 := rooms.NewRoom("r1", map[string]bool{})
 	err = rms.Insert(r1)
@@ -51,7 +75,7 @@ func testRooms(t *testing.T, rms Rooms) {
 	}
 
 	// Retrieve existing room
-	r, err := rms.Get(r0.Name)
+	r, err = rms.Get(r0.Name)
 	if err != nil {
 		t.Errorf("Unexpected err %s on Get", err)
 	}
@@ -84,6 +108,7 @@ func testRooms(t *testing.T, rms Rooms) {
 	if err != nil {
 		t.Errorf("Unexpected err %s on AddPlayer", err)
 	}
+	updateActivity(t, r, r0)
 	if !reflect.DeepEqual(r, r0) {
 		t.Errorf("Get returned %#v, expected %#v", r, r0)
 	}
@@ -103,10 +128,228 @@ func testRooms(t *testing.T, rms Rooms) {
 	if err != nil {
 		t.Errorf("Unexpected err %s on DeletePlayer", err)
 	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("Get returned %#v, expected %#v", r, r0)
+	}
+
+	// Retrieve existing room
+	r, err = rms.Get(r0.Name)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Get", err)
+	}
 	if !reflect.DeepEqual(r, r0) {
 		t.Errorf("Get returned %#v, expected %#v", r, r0)
 	}
 
+	// Set a user's role
+	r0.SetRole("p1", rooms.Spectator)
+	r, err = rms.SetRole(r0.Name, "p1", rooms.Spectator)
+	if err != nil {
+		t.Errorf("Unexpected err %s on SetRole", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("SetRole returned %#v, expected %#v", r, r0)
+	}
+
+	// Set the role of a user not in the room
+	_, err = rms.SetRole(r0.Name, "p9", rooms.Spectator)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected SetRole err %s to be of type NotFoundError", err)
+	}
+
+	// Transfer ownership
+	r0.SetOwner("p1")
+	r, err = rms.SetOwner(r0.Name, "p1")
+	if err != nil {
+		t.Errorf("Unexpected err %s on SetOwner", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("SetOwner returned %#v, expected %#v", r, r0)
+	}
+
+	// Transfer ownership to a user not in the room
+	_, err = rms.SetOwner(r0.Name, "p9")
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected SetOwner err %s to be of type NotFoundError", err)
+	}
+
+	// Retrieve existing room
+	r, err = rms.Get(r0.Name)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Get", err)
+	}
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("Get returned %#v, expected %#v", r, r0)
+	}
+
+	// Limit the room's players and waitlist a player
+	r0.SetMaxPlayers(1)
+	r, err = rms.SetMaxPlayers(r0.Name, 1)
+	if err != nil {
+		t.Errorf("Unexpected err %s on SetMaxPlayers", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("SetMaxPlayers returned %#v, expected %#v", r, r0)
+	}
+	r0.AddPlayer("p3")
+	r, err = rms.AddPlayer(r0.Name, "p3")
+	if err != nil {
+		t.Errorf("Unexpected err %s on AddPlayer", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) || !r.Waiting("p3") {
+		t.Errorf("AddPlayer returned %#v, expected %#v with p3 waiting", r, r0)
+	}
+
+	// Duplicate waitlisting fails
+	_, err = rms.AddPlayer(r0.Name, "p3")
+	_, ok = err.(errors.AlreadyExistsError)
+	if !ok {
+		t.Errorf("Expected AddPlayer err %s to be of type AlreadyExistsError", err)
+	}
+
+	// Raising the limit promotes the waiting player
+	r0.SetMaxPlayers(2)
+	r, err = rms.SetMaxPlayers(r0.Name, 2)
+	if err != nil {
+		t.Errorf("Unexpected err %s on SetMaxPlayers", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) || !r.Usernames["p3"] {
+		t.Errorf("SetMaxPlayers returned %#v, expected %#v with p3 playing", r, r0)
+	}
+
+	// Leaving promotes the waiting player
+	r0.AddPlayer("p4")
+	r0.RemoveUser("p3")
+	_, err = rms.AddPlayer(r0.Name, "p4")
+	if err != nil {
+		t.Errorf("Unexpected err %s on AddPlayer", err)
+	}
+	r, err = rms.DeletePlayer(r0.Name, "p3")
+	if err != nil {
+		t.Errorf("Unexpected err %s on DeletePlayer", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) || !r.Usernames["p4"] {
+		t.Errorf("DeletePlayer returned %#v, expected %#v with p4 playing", r, r0)
+	}
+	r0.RemoveUser("p4")
+	r0.SetMaxPlayers(0)
+	_, err = rms.DeletePlayer(r0.Name, "p4")
+	if err != nil {
+		t.Errorf("Unexpected err %s on DeletePlayer", err)
+	}
+	r, err = rms.SetMaxPlayers(r0.Name, 0)
+	if err != nil {
+		t.Errorf("Unexpected err %s on SetMaxPlayers", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("SetMaxPlayers returned %#v, expected %#v", r, r0)
+	}
+
+	// Record heartbeats
+	r, err = rms.Heartbeat(r0.Name, "p0", false)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Heartbeat", err)
+	}
+	if r.Presence["p0"].Status != rooms.Online {
+		t.Fatalf("Heartbeat returned presence %#v, expected online", r.Presence["p0"])
+	}
+	r0.Heartbeat("p0", false, r.Presence["p0"].LastSeen)
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("Heartbeat returned %#v, expected %#v", r, r0)
+	}
+	_, err = rms.Heartbeat(r0.Name, "p9", false)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Heartbeat err %s to be of type NotFoundError", err)
+	}
+
+	// Disconnect users without recent heartbeats
+	rs, err = rms.Disconnect(r0.Presence["p0"].LastSeen)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Disconnect", err)
+	}
+	if len(rs) != 0 {
+		t.Errorf("Disconnect returned %#v, expected none", rs)
+	}
+	rs, err = rms.Disconnect(r0.Presence["p0"].LastSeen.Add(time.Nanosecond))
+	if err != nil {
+		t.Errorf("Unexpected err %s on Disconnect", err)
+	}
+	if len(rs) != 1 || rs[0].Presence["p0"].Status != rooms.Disconnected {
+		t.Fatalf("Disconnect returned %#v, expected r0 with p0 disconnected", rs)
+	}
+	r0.Disconnect(r0.Presence["p0"].LastSeen.Add(time.Nanosecond), rs[0].Presence["p0"].Since)
+	if !reflect.DeepEqual(rs[0], r0) {
+		t.Errorf("Disconnect returned %#v, expected %#v", rs[0], r0)
+	}
+
+	// Restrict access to the room
+	r0.SetAccess(true, "pwhash")
+	r, err = rms.SetAccess(r0.Name, true, "pwhash")
+	if err != nil {
+		t.Errorf("Unexpected err %s on SetAccess", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("SetAccess returned %#v, expected %#v", r, r0)
+	}
+
+	// Add invite codes
+	for _, hash := range []string{"invite0", "invite1"} {
+		r, err = rms.AddInvite(r0.Name, hash)
+		if err != nil {
+			t.Errorf("Unexpected err %s on AddInvite", err)
+		}
+		if _, ok := r.Invites[hash]; !ok {
+			t.Fatalf("AddInvite returned room without invite %s", hash)
+		}
+		r0.AddInvite(hash, r.Invites[hash])
+		updateActivity(t, r, r0)
+		if !reflect.DeepEqual(r, r0) {
+			t.Errorf("AddInvite returned %#v, expected %#v", r, r0)
+		}
+	}
+
+	// Revoke an invite code
+	r0.RevokeInvite("invite0")
+	r, err = rms.RevokeInvite(r0.Name, "invite0")
+	if err != nil {
+		t.Errorf("Unexpected err %s on RevokeInvite", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("RevokeInvite returned %#v, expected %#v", r, r0)
+	}
+
+	// Revoke a non-existing invite code
+	_, err = rms.RevokeInvite(r0.Name, "invite0")
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected RevokeInvite err %s to be of type NotFoundError", err)
+	}
+
+	// Revoke all invite codes
+	r0.RevokeInvite("")
+	r, err = rms.RevokeInvite(r0.Name, "")
+	if err != nil {
+		t.Errorf("Unexpected err %s on RevokeInvite", err)
+	}
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("RevokeInvite returned %#v, expected %#v", r, r0)
+	}
+
 	// Retrieve existing room
 	r, err = rms.Get(r0.Name)
 	if err != nil {
@@ -117,12 +360,16 @@ func testRooms(t *testing.T, rms Rooms) {
 	}
 
 	// Set room's game
-	r0.GameType = rooms.Set
-	r0.GameID = uuid.New()
-	r, err = rms.SetGame(r0.Name, r0.GameType, r0.GameID)
+	id0 := uuid.New()
+	r, err = rms.SetGame(r0.Name, rooms.Set, id0)
 	if err != nil {
 		t.Errorf("Unexpected err %s on SetTame", err)
 	}
+	if len(r.Games) != 1 {
+		t.Fatalf("SetGame returned %d games, expected 1", len(r.Games))
+	}
+	r0.StartGame(rooms.Set, id0, r.Games[0].Start)
+	updateActivity(t, r, r0)
 	if !reflect.DeepEqual(r, r0) {
 		t.Errorf("Get returned %#v, expected %#v", r, r0)
 	}
@@ -136,6 +383,110 @@ func testRooms(t *testing.T, rms Rooms) {
 		t.Errorf("Get returned %#v, expected %#v", r, r0)
 	}
 
+	// End room's game
+	scores := map[string]int{"p0": 3, "p1": 2}
+	r, err = rms.EndGame(r0.Name, scores)
+	if err != nil {
+		t.Errorf("Unexpected err %s on EndGame", err)
+	}
+	if r.Games[0].End == nil {
+		t.Fatalf("EndGame returned game with no End")
+	}
+	r0.EndGame(scores, *r.Games[0].End)
+	updateActivity(t, r, r0)
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("EndGame returned %#v, expected %#v", r, r0)
+	}
+
+	// Retrieve existing room
+	r, err = rms.Get(r0.Name)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Get", err)
+	}
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("Get returned %#v, expected %#v", r, r0)
+	}
+
+	// End room's game when there is none
+	_, err = rms.EndGame(r0.Name, scores)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected EndGame err %s to be of type NotFoundError", err)
+	}
+
+	// Clear a game that is no room's current game
+	rs, err = rms.ClearGame(id0)
+	if err != nil {
+		t.Errorf("Unexpected err %s on ClearGame", err)
+	}
+	if len(rs) != 0 {
+		t.Errorf("ClearGame returned %#v, expected no rooms", rs)
+	}
+
+	// Clear the room's current game
+	id1 := uuid.New()
+	r, err = rms.SetGame(r0.Name, rooms.Set, id1)
+	if err != nil {
+		t.Errorf("Unexpected err %s on SetGame", err)
+	}
+	r0.StartGame(rooms.Set, id1, r.Games[1].Start)
+	updateActivity(t, r, r0)
+	rs, err = rms.ClearGame(id1)
+	if err != nil {
+		t.Errorf("Unexpected err %s on ClearGame", err)
+	}
+	if len(rs) != 1 || rs[0].Games[1].End == nil {
+		t.Fatalf("ClearGame returned %#v, expected r0 with its game ended", rs)
+	}
+	r0.EndGame(nil, *rs[0].Games[1].End)
+	if !reflect.DeepEqual(rs[0], r0) {
+		t.Errorf("ClearGame returned %#v, expected %#v", rs[0], r0)
+	}
+
+	// Touch a room with an earlier time
+	r, err = rms.Touch(r0.Name, r0.LastActivity.Add(-time.Minute))
+	if err != nil {
+		t.Errorf("Unexpected err %s on Touch", err)
+	}
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("Touch returned %#v, expected %#v", r, r0)
+	}
+
+	// Touch a room with a later time
+	r0.LastActivity = r0.LastActivity.Add(time.Minute)
+	r, err = rms.Touch(r0.Name, r0.LastActivity)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Touch", err)
+	}
+	if !reflect.DeepEqual(r, r0) {
+		t.Errorf("Touch returned %#v, expected %#v", r, r0)
+	}
+
+	// Touch non-existing room
+	_, err = rms.Touch("r9", time.Now())
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Touch err %s to be of type NotFoundError", err)
+	}
+
+	// Expire rooms idle since before r0 was last updated
+	names, err := rms.Expire(r0.LastActivity)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Expire", err)
+	}
+	if !reflect.DeepEqual(names, []string{r1.Name}) {
+		t.Errorf("Expire returned %v, expected %v", names, []string{r1.Name})
+	}
+	_, err = rms.Get(r1.Name)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Get err %s of expired room to be of type NotFoundError", err)
+	}
+	_, err = rms.Get(r0.Name)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Get of unexpired room", err)
+	}
+
 	// Delete existing room
 	err = rms.Delete(r0.Name)
 	if err != nil {
@@ -157,6 +508,97 @@ func testRooms(t *testing.T, rms Rooms) {
 	}
 }
 
+// testRoomsQuery tests the Query method of the given implementor of Rooms,
+// which must be empty
+func testRoomsQuery(t *testing.T, rms Rooms) {
+	alpha := rooms.NewRoom("alpha", map[string]bool{"p1": true})
+	alpha.GameType = rooms.Set
+	alpha.MaxPlayers = 1
+	alps := rooms.NewRoom("Alps", map[string]bool{"p1": true, "p2": true})
+	alps.Private = true
+	beta := rooms.NewRoom("beta", map[string]bool{"p1": true, "p2": true, "p3": true})
+	beta.GameType = rooms.Set
+	bravo := rooms.NewRoom("Bravo", map[string]bool{})
+	for _, r := range []*rooms.Room{beta, alpha, bravo, alps} {
+		err := rms.Insert(r)
+		if err != nil {
+			t.Errorf("Unexpected err %s on Insert", err)
+		}
+	}
+	names := func(rs []*rooms.Room) []string {
+		ns := []string{}
+		for _, r := range rs {
+			ns = append(ns, r.Name)
+		}
+		return ns
+	}
+	set := rooms.Set
+	tests := []struct {
+		desc string
+		q    query.Rooms
+		exp  []string
+	}{
+		{"all public", query.Rooms{}, []string{"alpha", "beta", "Bravo"}},
+		{"all", query.Rooms{AllPrivate: true}, []string{"alpha", "Alps", "beta", "Bravo"}},
+		{"viewer in private room", query.Rooms{Viewer: "p2"}, []string{"alpha", "Alps", "beta", "Bravo"}},
+		{"prefix", query.Rooms{Prefix: "AL", Viewer: "p2"}, []string{"alpha", "Alps"}},
+		{"game type", query.Rooms{GameType: &set}, []string{"alpha", "beta"}},
+		{"open seats", query.Rooms{OpenSeats: true}, []string{"beta", "Bravo"}},
+		{"by size", query.Rooms{Order: query.BySize, AllPrivate: true}, []string{"beta", "Alps", "alpha", "Bravo"}},
+		{"after", query.Rooms{After: &query.Cursor{Key: "alpha"}}, []string{"beta", "Bravo"}},
+		{"after by size", query.Rooms{Order: query.BySize, After: &query.Cursor{Key: "beta", Size: 3}}, []string{"alpha", "Bravo"}},
+	}
+	for _, test := range tests {
+		rs, next, err := rms.Query(test.q)
+		if err != nil {
+			t.Errorf("Unexpected err %s on Query %s", err, test.desc)
+		}
+		if !reflect.DeepEqual(names(rs), test.exp) {
+			t.Errorf("Query %s returned %v, expected %v", test.desc, names(rs), test.exp)
+		}
+		if next != nil {
+			t.Errorf("Query %s returned next %#v, expected nil", test.desc, next)
+		}
+	}
+
+	// Page through rooms by activity
+	q := query.Rooms{Order: query.ByActivity, AllPrivate: true, Limit: 3}
+	rs, next, err := rms.Query(q)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Query", err)
+	}
+	if len(rs) != 3 || next == nil {
+		t.Fatalf("Query returned %v and next %#v, expected 3 rooms and a next cursor", names(rs), next)
+	}
+	q.After = next
+	more, next, err := rms.Query(q)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Query", err)
+	}
+	if len(more) != 1 || next != nil {
+		t.Errorf("Query returned %v and next %#v, expected 1 room and no next cursor", names(more), next)
+	}
+	rs = append(rs, more...)
+	for i := 1; i < len(rs); i++ {
+		if rs[i].LastActivity.After(rs[i-1].LastActivity) {
+			t.Errorf("Query returned %s active at %s after %s active at %s, expected most recent first",
+				rs[i-1].Name, rs[i-1].LastActivity, rs[i].Name, rs[i].LastActivity)
+		}
+	}
+	if !roomsEqual(rs, []*rooms.Room{alpha, alps, beta, bravo}) {
+		t.Errorf("Query pages returned %v, expected each room once", names(rs))
+	}
+}
+
+// updateActivity checks that r, as returned by a write to the datastore, was
+// updated no earlier than expected room exp, then updates exp to match
+func updateActivity(t *testing.T, r, exp *rooms.Room) {
+	if r.LastActivity.Before(exp.LastActivity) {
+		t.Errorf("Write set LastActivity %s, expected no earlier than %s", r.LastActivity, exp.LastActivity)
+	}
+	exp.LastActivity = r.LastActivity
+}
+
 func roomsEqual(rs1, rs2 []*rooms.Room) bool {
 	if len(rs1) != len(rs2) {
 		return false
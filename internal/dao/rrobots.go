@@ -0,0 +1,23 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/games/rrobots"
+)
+
+// RRobots provides persistence operations for ricochet robots games.
+// Implementations set a Game's LastActivity to the current time on each
+// write.
+type RRobots interface {
+	List() ([]*rrobots.Game, error)
+	Insert(g *rrobots.Game) error
+	Get(uuid uuid.UUID) (*rrobots.Game, error)
+	Update(g *rrobots.Game) error
+	Delete(uuid uuid.UUID) error
+	// Expire deletes the games with LastActivity before the given time and
+	// returns their IDs
+	Expire(before time.Time) ([]uuid.UUID, error)
+}
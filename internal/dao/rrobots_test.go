@@ -0,0 +1,139 @@
+package dao
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/games/rrobots"
+)
+
+// TestRamRRobots tests the ram implementation of RRobots
+func TestRamRRobots(t *testing.T) {
+	ram := ram.NewRRobots()
+	testRRobots(t, ram)
+}
+
+// testRRobots tests the given implementor of RRobots
+func testRRobots(t *testing.T, rs RRobots) {
+	// Empty list
+	expGs := []*rrobots.Game{}
+	gs, err := rs.List()
+	if err != nil {
+		t.Errorf("List returned error %#v", err)
+	}
+	if !rrobotsEqual(gs, expGs) {
+		t.Errorf("List returned %#v, expected %#v", gs, expGs)
+	}
+
+	// Insert game with players
+	g0, _ := rrobots.NewGame("p0", "p1")
+	err = rs.Insert(g0)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Insert", err)
+	}
+
+	// Duplicate Insert fails
+	err = rs.Insert(g0)
+	_, ok := err.(errors.AlreadyExistsError)
+	if !ok {
+		t.Errorf("Expected err %s to be of type AlreadyExistsError", err)
+	}
+
+	// Insert game without players
+	g1, _ := rrobots.NewGame()
+	err = rs.Insert(g1)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Insert", err)
+	}
+
+	// Retrieve existing game
+	g, err := rs.Get(g0.ID)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Get", err)
+	}
+	if !reflect.DeepEqual(g, g0) {
+		t.Errorf("Get returned %#v, expected %#v", g, g0)
+	}
+
+	// List all games
+	expGs = []*rrobots.Game{g0, g1}
+	gs, err = rs.List()
+	if err != nil {
+		t.Errorf("List returned error %#v", err)
+	}
+	if !rrobotsEqual(gs, expGs) {
+		t.Errorf("List returned %#v, expected %#v", gs, expGs)
+	}
+
+	// Update game
+	g0.Skip()
+	err = rs.Update(g0)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Update", err)
+	}
+	g, err = rs.Get(g0.ID)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Get", err)
+	}
+	if !reflect.DeepEqual(g, g0) {
+		t.Errorf("Get returned %#v, expected equal to %#v", g, g0)
+	}
+
+	// Expire games idle since before g0 was last updated
+	ids, err := rs.Expire(g0.LastActivity)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Expire", err)
+	}
+	if !reflect.DeepEqual(ids, []uuid.UUID{g1.ID}) {
+		t.Errorf("Expire returned %v, expected %v", ids, []uuid.UUID{g1.ID})
+	}
+	_, err = rs.Get(g1.ID)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Get err %s of expired game to be of type NotFoundError", err)
+	}
+
+	// Delete existing game
+	err = rs.Delete(g0.ID)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Delete", err)
+	}
+
+	// Retrieve non-existing game
+	_, err = rs.Get(g0.ID)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Get err %s to be of type NotFoundError", err)
+	}
+
+	// Update non-existing game
+	err = rs.Update(g0)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Update err %s to be of type NotFoundError", err)
+	}
+
+	// Delete non-existing game
+	err = rs.Delete(g0.ID)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Delete err %s to be of type NotFoundError", err)
+	}
+}
+
+func rrobotsEqual(gs1, gs2 []*rrobots.Game) bool {
+	if len(gs1) != len(gs2) {
+		return false
+	}
+	m1 := make(map[uuid.UUID]*rrobots.Game)
+	m2 := make(map[uuid.UUID]*rrobots.Game)
+	for i := 0; i < len(gs1); i++ {
+		m1[gs1[i].ID] = gs1[i]
+		m2[gs2[i].ID] = gs2[i]
+	}
+	return reflect.DeepEqual(m1, m2)
+}
@@ -0,0 +1,27 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/bbawn/boredgames/internal/sessions"
+)
+
+// Sessions provides persistence operations for user sessions.
+// Implementations set a Session's LastActivity to the current time on each
+// write.
+type Sessions interface {
+	// Insert stores the session, setting its Created time to the current
+	// time. An AlreadyExistsError is returned if another session has the
+	// same username, ignoring case.
+	Insert(s *sessions.Session) error
+	// Get returns the session with the given ID
+	Get(id string) (*sessions.Session, error)
+	// Touch records the use of the session with the given ID now and
+	// returns it
+	Touch(id string) (*sessions.Session, error)
+	// Delete deletes the session with the given ID, releasing its username
+	Delete(id string) error
+	// Expire deletes the sessions with LastActivity before the given time
+	// and returns their usernames
+	Expire(before time.Time) ([]string, error)
+}
@@ -0,0 +1,125 @@
+package dao
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/sessions"
+)
+
+// TestRamSessions tests the ram implementation of Sessions
+func TestRamSessions(t *testing.T) {
+	ram := ram.NewSessions()
+	testSessions(t, ram)
+}
+
+// testSessions tests the given implementor of Sessions
+func testSessions(t *testing.T, ss Sessions) {
+	// Insert a session
+	s0, _, err := sessions.NewSession("Joe")
+	if err != nil {
+		t.Fatalf("Unexpected err %s on NewSession", err)
+	}
+	err = ss.Insert(s0)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Insert", err)
+	}
+	if s0.Created.IsZero() || s0.LastActivity != s0.Created {
+		t.Errorf("Insert set Created %s and LastActivity %s, expected both now", s0.Created, s0.LastActivity)
+	}
+
+	// Insert of another session for a case variant username fails
+	s1, _, _ := sessions.NewSession("JOE")
+	err = ss.Insert(s1)
+	_, ok := err.(errors.AlreadyExistsError)
+	if !ok {
+		t.Errorf("Expected Insert err %s to be of type AlreadyExistsError", err)
+	}
+
+	// Retrieve existing session
+	s, err := ss.Get(s0.ID)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Get", err)
+	}
+	if !reflect.DeepEqual(s, s0) {
+		t.Errorf("Get returned %#v, expected %#v", s, s0)
+	}
+
+	// Touch existing session
+	s, err = ss.Touch(s0.ID)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Touch", err)
+	}
+	if s.LastActivity.Before(s0.LastActivity) {
+		t.Errorf("Touch set LastActivity %s, expected no earlier than %s", s.LastActivity, s0.LastActivity)
+	}
+	s0.LastActivity = s.LastActivity
+	s, err = ss.Get(s0.ID)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Get", err)
+	}
+	if !reflect.DeepEqual(s, s0) {
+		t.Errorf("Get returned %#v, expected %#v", s, s0)
+	}
+
+	// Touch non-existing session
+	_, err = ss.Touch(s1.ID)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Touch err %s to be of type NotFoundError", err)
+	}
+
+	// Expire sessions idle since before s0 was last used
+	s2, _, _ := sessions.NewSession("Maria")
+	err = ss.Insert(s2)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Insert", err)
+	}
+	_, err = ss.Touch(s2.ID)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Touch", err)
+	}
+	usernames, err := ss.Expire(s2.LastActivity)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Expire", err)
+	}
+	if !reflect.DeepEqual(usernames, []string{"Joe"}) {
+		t.Errorf("Expire returned %v, expected %v", usernames, []string{"Joe"})
+	}
+	_, err = ss.Get(s0.ID)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Get err %s of expired session to be of type NotFoundError", err)
+	}
+
+	// The username of an expired session may be claimed again
+	err = ss.Insert(s1)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Insert", err)
+	}
+
+	// Delete existing session, releasing its username
+	err = ss.Delete(s1.ID)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Delete", err)
+	}
+	_, err = ss.Get(s1.ID)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Get err %s to be of type NotFoundError", err)
+	}
+	s3, _, _ := sessions.NewSession("joe")
+	err = ss.Insert(s3)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Insert", err)
+	}
+
+	// Delete non-existing session
+	err = ss.Delete(s1.ID)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Delete err %s to be of type NotFoundError", err)
+	}
+}
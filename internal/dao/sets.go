@@ -1,16 +1,22 @@
 package dao
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 
 	"github.com/bbawn/boredgames/internal/games/set"
 )
 
-// Sets provides persistences operations for set games
+// Sets provides persistences operations for set games. Implementations set
+// a Game's LastActivity to the current time on each write.
 type Sets interface {
 	List() ([]*set.Game, error)
 	Insert(g *set.Game) error
 	Get(uuid uuid.UUID) (*set.Game, error)
 	Update(g *set.Game) error
 	Delete(uuid uuid.UUID) error
+	// Expire deletes the games with LastActivity before the given time and
+	// returns their IDs
+	Expire(before time.Time) ([]uuid.UUID, error)
 }
@@ -97,6 +97,24 @@ func daoTest(t *testing.T, s Sets) {
 		t.Errorf("Get returned %#v, expected equal to %#v", g, g0)
 	}
 
+	// Expire games idle since before g0 was last updated
+	ids, err := s.Expire(g0.LastActivity)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Expire", err)
+	}
+	if !reflect.DeepEqual(ids, []uuid.UUID{g1.ID}) {
+		t.Errorf("Expire returned %v, expected %v", ids, []uuid.UUID{g1.ID})
+	}
+	_, err = s.Get(g1.ID)
+	_, ok = err.(errors.NotFoundError)
+	if !ok {
+		t.Errorf("Expected Get err %s of expired game to be of type NotFoundError", err)
+	}
+	_, err = s.Get(g0.ID)
+	if err != nil {
+		t.Errorf("Unexpected err %s on Get of unexpired game", err)
+	}
+
 	// Delete existing game
 	err = s.Delete(g0.ID)
 	if err != nil {
@@ -0,0 +1,113 @@
+package dictionary
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestNormalize(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tests := []struct {
+		lang, word, exp string
+		ok              bool
+	}{
+		{"en", " Cat ", "CAT", true},
+		{"en", "café", "", false},
+		{"en", "cat's", "", false},
+		{"en", "", "", false},
+		{"fr", "Été", "ETE", true},
+		{"fr", "cœur", "COEUR", true},
+		{"fr", "e\u0301te\u0301", "ETE", true},
+		{"de", "Grün", "GRÜN", true},
+		{"de", "Fuß", "FUSS", true},
+		{"es", "Año", "AÑO", true},
+		{"es", "árbol", "ARBOL", true},
+		{"es", "straße", "", false},
+	}
+	for _, test := range tests {
+		w, ok := Languages[test.lang].Normalize(test.word)
+		g.Expect(ok).To(Equal(test.ok), test.word)
+		g.Expect(w).To(Equal(test.exp), test.word)
+	}
+}
+
+func TestDictionary(t *testing.T) {
+	g := NewGomegaWithT(t)
+	d := New(Languages["fr"])
+	g.Expect(d.Len()).To(Equal(0))
+	g.Expect(d.Contains("thé")).To(BeFalse())
+	g.Expect(d.HasPrefix("")).To(BeTrue())
+
+	err := d.Load(strings.NewReader("# comment\n\nthé\n  théâtre \nthe\nñu\n"))
+	g.Expect(err).To(BeNil())
+	// "the" is the same word as "thé" in French, "ñu" is not French
+	g.Expect(d.Len()).To(Equal(2))
+	g.Expect(d.Contains("THE")).To(BeTrue())
+	g.Expect(d.Contains("Théâtre")).To(BeTrue())
+	g.Expect(d.Contains("théâ")).To(BeFalse())
+	g.Expect(d.Contains("ñu")).To(BeFalse())
+	g.Expect(d.HasPrefix("théâ")).To(BeTrue())
+	g.Expect(d.HasPrefix("TH")).To(BeTrue())
+	g.Expect(d.HasPrefix("ta")).To(BeFalse())
+	g.Expect(d.HasPrefix("ñ")).To(BeFalse())
+
+	g.Expect(d.Add("thé")).To(BeTrue())
+	g.Expect(d.Len()).To(Equal(2))
+	g.Expect(d.Add("")).To(BeFalse())
+}
+
+func TestRegistry(t *testing.T) {
+	g := NewGomegaWithT(t)
+	reg, err := NewRegistry()
+	g.Expect(err).To(BeNil())
+
+	// Every word of the default lists is valid in its language
+	for _, code := range LanguageCodes() {
+		d, err := reg.Get(code)
+		g.Expect(err).To(BeNil())
+		f, err := defaults.Open("words/" + code + ".txt")
+		g.Expect(err).To(BeNil())
+		n := 0
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			w := scanner.Text()
+			if w != "" && !strings.HasPrefix(w, "#") {
+				g.Expect(d.Contains(w)).To(BeTrue(), code+" "+w)
+				n++
+			}
+		}
+		f.Close()
+		g.Expect(d.Len()).To(Equal(n), code)
+	}
+	_, err = reg.Get("xx")
+	g.Expect(err).To(Equal(LanguageError{"xx"}))
+
+	// Word lists in a directory replace the defaults
+	dir, err := ioutil.TempDir("", "dictionary")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "en.txt"), []byte("zyzzyva\nquixotic\n"), 0644)
+	g.Expect(err).To(BeNil())
+	err = ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a word list"), 0644)
+	g.Expect(err).To(BeNil())
+	g.Expect(reg.LoadDir(dir)).To(Succeed())
+	en, err := reg.Get("en")
+	g.Expect(err).To(BeNil())
+	g.Expect(en.Len()).To(Equal(2))
+	g.Expect(en.Contains("zyzzyva")).To(BeTrue())
+	g.Expect(en.Contains("cat")).To(BeFalse())
+	fr, err := reg.Get("fr")
+	g.Expect(err).To(BeNil())
+	g.Expect(fr.Contains("café")).To(BeTrue())
+
+	// Word lists of unsupported languages are an error
+	err = ioutil.WriteFile(filepath.Join(dir, "xx.txt"), []byte("word\n"), 0644)
+	g.Expect(err).To(BeNil())
+	g.Expect(reg.LoadDir(dir)).To(MatchError(ContainSubstring(`unsupported language: "xx"`)))
+}
@@ -0,0 +1,168 @@
+// Package dictionary provides the word lists and letters of the languages
+// word games are played in
+package dictionary
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Language is a language word games may be played in
+type Language struct {
+	// Code is the ISO 639-1 code of the language, e.g. "en"
+	Code string
+	Name string
+	// Alphabet are the upper case letters words are spelled with
+	Alphabet string
+	// Fold maps upper case letters outside the Alphabet to the letters
+	// they are played as, e.g. É to E in French, where accents are ignored
+	Fold map[rune]string
+	// Dice are the specs of the language's letter dice, keyed on the size
+	// of the board they fill. Each spec is the faces of a die in order, a
+	// face being an upper case letter followed by any lower case ones, or
+	// a '-' for a blank face, so "HIMNQuU" is a die with a "Qu" face.
+	Dice map[int][]string
+}
+
+// LanguageError indicates a language is not supported
+type LanguageError struct {
+	Code string
+}
+
+func (e LanguageError) Error() string {
+	return fmt.Sprintf("unsupported language: %q", e.Code)
+}
+
+// DefaultLanguage is the code of the language games are played in unless
+// another is selected
+const DefaultLanguage = "en"
+
+const latin = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// classicDice are the dice of the standard 4x4 English game
+var classicDice = []string{
+	"AAEEGN", "ABBJOO", "ACHOPS", "AFFKPS",
+	"AOOTTW", "CIMOTU", "DEILRX", "DELRVY",
+	"DISTTY", "EEGHNW", "EEINSU", "EHRTVW",
+	"EIOSST", "ELRTTY", "HIMNQuU", "HLNNRZ",
+}
+
+// bigDice are the dice of the 5x5 English game, Big Boggle
+var bigDice = []string{
+	"AAAFRS", "AAEEEE", "AAFIRS", "ADENNN", "AEEEEM",
+	"AEEGMU", "AEGMNN", "AFIRSY", "BJKQuXZ", "CCENST",
+	"CEIILT", "CEILPT", "CEIPST", "DDHNOT", "DHHLOR",
+	"DHLNOR", "DHLNOR", "EIIITT", "EMOTTT", "ENSSSU",
+	"FIPRSY", "GORRVW", "IPRRRY", "NOOTUW", "OOOTTU",
+}
+
+// superBigDice are the dice of the 6x6 English game, Super Big Boggle,
+// which has a die of two letter faces and one with blank faces
+var superBigDice = []string{
+	"AAAFRS", "AAEEEE", "AAEEOO", "AAFIRS", "ABDEIO", "ADENNN",
+	"AEEEEM", "AEEGMU", "AEGMNN", "AEILMN", "AEINOU", "AFIRSY",
+	"AnErHeInQuTh", "BBJKXZ", "CCENST", "CDDLNN", "CEIITT", "CEIPST",
+	"CFGNUY", "DDHNOT", "DHHLOR", "DHHNOW", "DHLNOR", "EHILRS",
+	"EIILST", "EILPST", "EIO---", "EMTTTO", "ENSSSU", "GORRVW",
+	"HIRSTV", "HOPRST", "IPRSYY", "JKQuWXZ", "NOOTUW", "OOOTTU",
+}
+
+// Languages are the supported languages, keyed on code. The French,
+// German and Spanish dice are the classic dice adapted to the letters of
+// each language.
+var Languages = map[string]*Language{
+	"en": {
+		Code:     "en",
+		Name:     "English",
+		Alphabet: latin,
+		Dice:     map[int][]string{4: classicDice, 5: bigDice, 6: superBigDice},
+	},
+	"fr": {
+		Code:     "fr",
+		Name:     "Français",
+		Alphabet: latin,
+		Fold: map[rune]string{
+			'À': "A", 'Â': "A", 'Ä': "A", 'Æ': "AE", 'Ç': "C",
+			'É': "E", 'È': "E", 'Ê': "E", 'Ë': "E", 'Î': "I", 'Ï': "I",
+			'Ô': "O", 'Ö': "O", 'Œ': "OE", 'Ù': "U", 'Û': "U", 'Ü': "U", 'Ÿ': "Y",
+		},
+		Dice: map[int][]string{4: {
+			"AAEEGN", "ABBJOE", "ACHOPS", "AFFLPS",
+			"AOIETT", "CIMOTU", "DEILRX", "DELRVE",
+			"DISTTA", "EEGHNU", "EEINSU", "EHRTVE",
+			"EIOSST", "ELRTTU", "HIMNQuU", "LLNNRZ",
+		}},
+	},
+	"de": {
+		Code:     "de",
+		Name:     "Deutsch",
+		Alphabet: latin + "ÄÖÜ",
+		Fold:     map[rune]string{'ß': "SS", 'ẞ': "SS"},
+		Dice: map[int][]string{4: {
+			"AAEEGN", "ABBJOÖ", "ACHOPS", "AFFKPS",
+			"AÄOTTW", "CIMOTU", "DEILRX", "DELRVI",
+			"DISTTN", "EEGHNW", "EEINSU", "EHRTVW",
+			"EIOSST", "ELRTTÜ", "HIMNQuU", "HLNNRZ",
+		}},
+	},
+	"es": {
+		Code:     "es",
+		Name:     "Español",
+		Alphabet: latin + "Ñ",
+		Fold: map[rune]string{
+			'Á': "A", 'É': "E", 'Í': "I", 'Ó': "O", 'Ú': "U", 'Ü': "U",
+		},
+		Dice: map[int][]string{4: {
+			"AAEEGN", "ABBJOO", "ACHOPS", "AFFÑPS",
+			"AOOTTA", "CIMOTU", "DEILRX", "DELRVY",
+			"DISTTA", "EEGHNO", "EEINSU", "EHRTVA",
+			"EIOSST", "ELRTTA", "HIMNQuU", "LLNÑRZ",
+		}},
+	},
+}
+
+// GetLanguage returns the language with the given code, or a LanguageError
+// if it is not supported
+func GetLanguage(code string) (*Language, error) {
+	l, ok := Languages[code]
+	if !ok {
+		return nil, LanguageError{code}
+	}
+	return l, nil
+}
+
+// LanguageCodes returns the codes of the supported languages, sorted
+func LanguageCodes() []string {
+	codes := make([]string, 0, len(Languages))
+	for c := range Languages {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Normalize returns the given word as it is played in the language: in
+// upper case, with letters outside the Alphabet folded. It returns false if
+// the word is empty or has letters that neither are in the Alphabet nor
+// fold to it.
+func (l *Language) Normalize(word string) (string, bool) {
+	word = strings.ToUpper(norm.NFC.String(strings.TrimSpace(word)))
+	if word == "" {
+		return "", false
+	}
+	var b strings.Builder
+	for _, r := range word {
+		switch {
+		case strings.ContainsRune(l.Alphabet, r):
+			b.WriteRune(r)
+		case l.Fold[r] != "":
+			b.WriteString(l.Fold[r])
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
@@ -0,0 +1,95 @@
+package dictionary
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaults are the default word lists, words/<code>.txt for each language.
+// They are short lists of common words, enough to play with; configure
+// directories of full lists for real games.
+//
+//go:embed words/*.txt
+var defaults embed.FS
+
+// Registry holds the dictionary of each language
+type Registry struct {
+	m     sync.RWMutex
+	dicts map[string]*Dictionary
+}
+
+// NewRegistry returns a registry of the default dictionaries
+func NewRegistry() (*Registry, error) {
+	reg := &Registry{dicts: make(map[string]*Dictionary)}
+	for _, code := range LanguageCodes() {
+		f, err := defaults.Open("words/" + code + ".txt")
+		if err != nil {
+			return nil, fmt.Errorf("no default word list for language %s: %s", code, err)
+		}
+		err = reg.load(code, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+// LoadDir loads the word lists in the given directory, each named
+// <code>.txt for the language with that code, replacing the dictionaries
+// of those languages. Files of unsupported languages are an error, other
+// files are ignored.
+func (reg *Registry) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		code := strings.TrimSuffix(filepath.Base(path), ".txt")
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = reg.load(code, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %s", path, err)
+		}
+	}
+	return nil
+}
+
+// load replaces the dictionary of the language with the given code with
+// the words read from r
+func (reg *Registry) load(code string, r io.Reader) error {
+	lang, err := GetLanguage(code)
+	if err != nil {
+		return err
+	}
+	d := New(lang)
+	err = d.Load(r)
+	if err != nil {
+		return err
+	}
+	reg.m.Lock()
+	defer reg.m.Unlock()
+	reg.dicts[code] = d
+	return nil
+}
+
+// Get returns the dictionary of the language with the given code, or a
+// LanguageError if there is none
+func (reg *Registry) Get(code string) (*Dictionary, error) {
+	reg.m.RLock()
+	defer reg.m.RUnlock()
+	d, ok := reg.dicts[code]
+	if !ok {
+		return nil, LanguageError{code}
+	}
+	return d, nil
+}
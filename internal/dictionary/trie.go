@@ -0,0 +1,106 @@
+package dictionary
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Dictionary is the list of valid words of a language, stored in a trie
+// for fast prefix lookup by word game solvers
+type Dictionary struct {
+	Language *Language
+	root     *node
+	len      int
+}
+
+// node is a node of the trie: the prefix spelled by the path to it from the
+// root, and whether that is a word
+type node struct {
+	children map[rune]*node
+	word     bool
+}
+
+// New returns an empty dictionary of the given language
+func New(lang *Language) *Dictionary {
+	return &Dictionary{Language: lang, root: new(node)}
+}
+
+// Len returns the number of words in the dictionary
+func (d *Dictionary) Len() int {
+	return d.len
+}
+
+// Add adds the given word, normalized per the dictionary's Language, and
+// returns whether it is valid in the Language
+func (d *Dictionary) Add(word string) bool {
+	word, ok := d.Language.Normalize(word)
+	if !ok {
+		return false
+	}
+	n := d.root
+	for _, r := range word {
+		child := n.children[r]
+		if child == nil {
+			child = new(node)
+			if n.children == nil {
+				n.children = make(map[rune]*node)
+			}
+			n.children[r] = child
+		}
+		n = child
+	}
+	if !n.word {
+		n.word = true
+		d.len++
+	}
+	return true
+}
+
+// Load adds the words read from r, one per line. Blank lines, lines
+// starting with '#' and words not valid in the Language are skipped.
+func (d *Dictionary) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		d.Add(line)
+	}
+	return scanner.Err()
+}
+
+// find returns the node for the given normalized prefix, or nil if no word
+// starts with it
+func (d *Dictionary) find(prefix string) *node {
+	n := d.root
+	for _, r := range prefix {
+		n = n.children[r]
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// Contains returns whether the given word, normalized per the dictionary's
+// Language, is in the dictionary
+func (d *Dictionary) Contains(word string) bool {
+	word, ok := d.Language.Normalize(word)
+	if !ok {
+		return false
+	}
+	n := d.find(word)
+	return n != nil && n.word
+}
+
+// HasPrefix returns whether any word in the dictionary starts with the
+// given prefix, normalized per the dictionary's Language
+func (d *Dictionary) HasPrefix(prefix string) bool {
+	if strings.TrimSpace(prefix) == "" {
+		return true
+	}
+	normalized, ok := d.Language.Normalize(prefix)
+	return ok && d.find(normalized) != nil
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"sync"
+)
+
+// subscriberBuffer is the number of messages buffered for each subscriber
+// before further messages to it are dropped
+const subscriberBuffer = 16
+
+// Broker fans out messages published on a topic to every subscriber of
+// that topic. Publish never blocks: a subscriber that falls more than
+// subscriberBuffer messages behind loses its oldest messages rather than
+// stalling the publisher, so the most recent message is always delivered.
+type Broker struct {
+	m    sync.Mutex
+	subs map[string]map[chan []byte]bool
+}
+
+// NewBroker creates a Broker with no subscribers
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[string]map[chan []byte]bool)}
+}
+
+// Subscribe returns a channel receiving the messages subsequently
+// published on topic and a function that cancels the subscription and
+// closes the channel
+func (b *Broker) Subscribe(topic string) (<-chan []byte, func()) {
+	ch := make(chan []byte, subscriberBuffer)
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[chan []byte]bool)
+	}
+	b.subs[topic][ch] = true
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.m.Lock()
+			defer b.m.Unlock()
+			delete(b.subs[topic], ch)
+			if len(b.subs[topic]) == 0 {
+				delete(b.subs, topic)
+			}
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Publish sends msg to every current subscriber of topic
+func (b *Broker) Publish(topic string, msg []byte) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- msg:
+		default:
+			// Slow subscriber, drop its oldest message to make room
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- msg
+		}
+	}
+}
+
+// Subscribers returns the number of current subscribers of topic
+func (b *Broker) Subscribers(topic string) int {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return len(b.subs[topic])
+}
@@ -0,0 +1,137 @@
+package games
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Action is a move in a game of any type, in the envelope all the game
+// types share
+type Action struct {
+	// Type is the kind of action, one of those of the game's type
+	Type string `json:"type"`
+	// Player is the username of the player making the action
+	Player string `json:"player"`
+	// Payload are the arguments of the action, which actions without
+	// arguments ignore
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// ClientVersion is the version of the game the action was made
+	// against, or nil to apply it whatever the version
+	ClientVersion *int `json:"clientVersion,omitempty"`
+	// Seed seeds whatever the action does at random, such as rolling the
+	// board of a new round. The service applying the action sets it, so
+	// the action replays the same.
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// Actions every game type has, which change who is in the game. Their
+// Player is the user joining or leaving.
+const (
+	// JoinAction adds the player to the game
+	JoinAction = "join"
+	// LeaveAction removes the player from the game, discarding their
+	// winnings unless the game type's payload says otherwise
+	LeaveAction = "leave"
+	// WatchAction adds the player to the game as a spectator
+	WatchAction = "watch"
+	// UnwatchAction removes the spectator from the game
+	UnwatchAction = "unwatch"
+)
+
+// MembershipAction returns true if the action type is one of those that
+// change who is in the game
+func MembershipAction(actionType string) bool {
+	switch actionType {
+	case JoinAction, LeaveAction, WatchAction, UnwatchAction:
+		return true
+	default:
+		return false
+	}
+}
+
+// Result is the record of an action applied to a game, successfully or
+// not
+type Result struct {
+	GameID uuid.UUID `json:"gameID"`
+	// Seq numbers the results of the game from 1, in the order applied
+	Seq    int    `json:"seq"`
+	Action Action `json:"action"`
+	// Time is the time the action was applied at
+	Time time.Time `json:"time"`
+	// Version is the version of the game after the action: the number of
+	// its actions that succeeded
+	Version int `json:"version"`
+	// Error is why the action failed, or "" if it succeeded
+	Error string `json:"error,omitempty"`
+}
+
+// Succeeded returns true if the action was applied without error
+func (r *Result) Succeeded() bool {
+	return r.Error == ""
+}
+
+// ActionError indicates an action is not one of the game's or its payload
+// is invalid
+type ActionError struct {
+	Action  string
+	Details string
+}
+
+func (e ActionError) Error() string {
+	return fmt.Sprintf("Invalid action: %s detail: %s", e.Action, e.Details)
+}
+
+// UnknownAction returns the ActionError for an action a game does not have
+func UnknownAction(action string) ActionError {
+	return ActionError{action, "no such action"}
+}
+
+// Redactor is implemented by games whose action results reveal what their
+// View hides from some users
+type Redactor interface {
+	// Redact returns the results as the given user may see them, without
+	// modifying them
+	Redact(results []*Result, username string) []*Result
+}
+
+// Validator is implemented by action payloads that check their arguments
+// themselves, before the action is applied
+type Validator interface {
+	Validate() error
+}
+
+// DecodePayload unmarshals the JSON payload of the action into v and, if v
+// is a Validator, validates it. An ActionError is returned if the payload
+// is missing, including if null, or invalid.
+func DecodePayload(a Action, v interface{}) error {
+	if len(a.Payload) == 0 || string(a.Payload) == "null" {
+		return ActionError{a.Type, "missing payload"}
+	}
+	if err := json.Unmarshal(a.Payload, v); err != nil {
+		return ActionError{a.Type, fmt.Sprintf("invalid payload: %s", err)}
+	}
+	if val, ok := v.(Validator); ok {
+		if err := val.Validate(); err != nil {
+			return ActionError{a.Type, fmt.Sprintf("invalid payload: %s", err)}
+		}
+	}
+	return nil
+}
+
+// Replay applies the actions of the given results that succeeded to the
+// game, in order and at the times they were first applied, so a game
+// created as the original was ends up in the same state
+func Replay(g Game, results []*Result) error {
+	for _, r := range results {
+		if !r.Succeeded() {
+			continue
+		}
+		if err := g.Apply(r.Action, r.Time); err != nil {
+			return fmt.Errorf("replaying result %d: %s", r.Seq, err)
+		}
+	}
+	return nil
+}
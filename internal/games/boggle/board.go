@@ -0,0 +1,133 @@
+package boggle
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"unicode/utf8"
+)
+
+// The sizes of board, the number of its rows and of its columns, there are
+// English dice for
+const (
+	// DefaultSize is the size of the standard board
+	DefaultSize = 4
+	// BigSize is the size of the Big Boggle board
+	BigSize = 5
+	// SuperBigSize is the size of the Super Big Boggle board
+	SuperBigSize = 6
+)
+
+// MinWordLen returns the fewest letters in a word that scores on a board of
+// the given size: 3 on the standard board, 4 on larger ones, where short
+// words are too easy to find
+func MinWordLen(size int) int {
+	if size <= DefaultSize {
+		return 3
+	}
+	return 4
+}
+
+// Board is a square grid of die faces, indexed by row then column
+type Board [][]string
+
+// Cell is the position of a die on a Board
+type Cell struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// Roll returns the board of the given dice, which must be a square number
+// of them, shuffled and rolled by a random source with the given seed. The
+// same dice and seed always roll the same board.
+func Roll(dice []Die, seed int64) Board {
+	size := int(math.Sqrt(float64(len(dice))))
+	if size*size != len(dice) {
+		panic("boggle: dice do not fill a square board")
+	}
+	rng := rand.New(rand.NewSource(seed))
+	order := rng.Perm(len(dice))
+	b := make(Board, size)
+	for row := range b {
+		b[row] = make([]string, size)
+		for col := range b[row] {
+			d := dice[order[row*size+col]]
+			b[row][col] = d[rng.Intn(len(d))]
+		}
+	}
+	return b
+}
+
+// Size returns the number of rows, and of columns, of the board
+func (b Board) Size() int {
+	return len(b)
+}
+
+// Path returns a path of adjacent cells, horizontally, vertically or
+// diagonally, none used twice nor blank, whose faces spell the given word
+// regardless of case, or nil if there is none
+func (b Board) Path(word string) []Cell {
+	word = strings.ToUpper(word)
+	if word == "" {
+		return nil
+	}
+	used := make([][]bool, len(b))
+	for row := range b {
+		used[row] = make([]bool, len(b[row]))
+	}
+	for row := range b {
+		for col := range b[row] {
+			if path := b.pathFrom(Cell{row, col}, word, used, nil); path != nil {
+				return path
+			}
+		}
+	}
+	return nil
+}
+
+// pathFrom returns path extended from the given unused cell by cells
+// spelling the rest of word, or nil if there are none
+func (b Board) pathFrom(c Cell, rest string, used [][]bool, path []Cell) []Cell {
+	face := strings.ToUpper(b[c.Row][c.Col])
+	if face == "" || !strings.HasPrefix(rest, face) {
+		return nil
+	}
+	path = append(path, c)
+	rest = rest[len(face):]
+	if rest == "" {
+		return path
+	}
+	used[c.Row][c.Col] = true
+	defer func() { used[c.Row][c.Col] = false }()
+	for row := c.Row - 1; row <= c.Row+1; row++ {
+		for col := c.Col - 1; col <= c.Col+1; col++ {
+			if row < 0 || row >= len(b) || col < 0 || col >= len(b[row]) || used[row][col] {
+				continue
+			}
+			if p := b.pathFrom(Cell{row, col}, rest, used, path); p != nil {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// Score returns the standard score of a word of the given letters: 1 for 3
+// or 4 letters, 2 for 5, 3 for 6, 5 for 7 and 11 for 8 or more. Words
+// shorter than the MinWordLen of the standard board score 0.
+func Score(word string) int {
+	switch n := utf8.RuneCountInString(word); {
+	case n < MinWordLen(DefaultSize):
+		return 0
+	case n <= 4:
+		return 1
+	case n == 5:
+		return 2
+	case n == 6:
+		return 3
+	case n == 7:
+		return 5
+	default:
+		return 11
+	}
+}
@@ -0,0 +1,123 @@
+package boggle
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dictionary"
+)
+
+// testBoard returns a fixed board:
+//
+//	C A T S
+//	O Qu E R
+//	D I N G
+//	E S T A
+func testBoard() Board {
+	return Board{
+		{"C", "A", "T", "S"},
+		{"O", "Qu", "E", "R"},
+		{"D", "I", "N", "G"},
+		{"E", "S", "T", "A"},
+	}
+}
+
+func TestRoll(t *testing.T) {
+	g := NewGomegaWithT(t)
+	b := Roll(ClassicDice, 42)
+	g.Expect(b.Size()).To(Equal(4))
+	g.Expect(Roll(ClassicDice, 42)).To(Equal(b))
+
+	// Each face is on one of the dice
+	faces := make(map[string]bool)
+	for _, d := range ClassicDice {
+		for _, f := range d {
+			faces[f] = true
+		}
+	}
+	for _, row := range b {
+		g.Expect(row).To(HaveLen(4))
+		for _, face := range row {
+			g.Expect(faces).To(HaveKey(face))
+		}
+	}
+
+	// Other seeds roll other boards
+	g.Expect(Roll(ClassicDice, 43)).NotTo(Equal(b))
+}
+
+func TestPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	b := testBoard()
+	g.Expect(b.Path("cat")).To(Equal([]Cell{{0, 0}, {0, 1}, {0, 2}}))
+	g.Expect(b.Path("CATS")).To(Equal([]Cell{{0, 0}, {0, 1}, {0, 2}, {0, 3}}))
+	// Diagonal adjacency and multi-letter faces
+	g.Expect(b.Path("quit")).To(Equal([]Cell{{1, 1}, {2, 1}, {3, 2}}))
+	g.Expect(b.Path("SING")).To(Equal([]Cell{{3, 1}, {2, 1}, {2, 2}, {2, 3}}))
+	g.Expect(b.Path("SEND")).To(BeNil())
+	// Q alone is not a face
+	g.Expect(b.Path("QIT")).To(BeNil())
+	// A cell may not be used twice
+	g.Expect(b.Path("CATAC")).To(BeNil())
+	// Cells must be adjacent
+	g.Expect(b.Path("CAR")).To(BeNil())
+	g.Expect(b.Path("")).To(BeNil())
+}
+
+func TestScore(t *testing.T) {
+	g := NewGomegaWithT(t)
+	for word, exp := range map[string]int{
+		"":         0,
+		"AT":       0,
+		"CAT":      1,
+		"CATS":     1,
+		"QUITE":    2,
+		"TINGED":   3,
+		"DINGERS":  5,
+		"SCATTERS": 11,
+		"ÉTÉS":     1,
+	} {
+		g.Expect(Score(word)).To(Equal(exp), word)
+	}
+}
+
+func TestDice(t *testing.T) {
+	g := NewGomegaWithT(t)
+	d, err := newDie("HIMNQuU")
+	g.Expect(err).To(BeNil())
+	g.Expect(d).To(Equal(Die{"H", "I", "M", "N", "Qu", "U"}))
+	d, err = newDie("AÄOTTW")
+	g.Expect(err).To(BeNil())
+	g.Expect(d[1]).To(Equal("Ä"))
+	_, err = newDie("uABCDE")
+	g.Expect(err).To(MatchError("die uABCDE has a face starting with lower case u"))
+	_, err = newDie("ABCDE")
+	g.Expect(err).To(MatchError("die ABCDE has 5 faces, not 6"))
+	_, err = newDie("ABCDEFG")
+	g.Expect(err).To(MatchError("die ABCDEFG has more than 6 faces"))
+	d, err = newDie("EIO---")
+	g.Expect(err).To(BeNil())
+	g.Expect(d).To(Equal(Die{"E", "I", "O", "", "", ""}))
+	_, err = newDie("AB-cDE")
+	g.Expect(err).To(MatchError("die AB-cDE has a face starting with lower case c"))
+
+	// Every language has valid dice for the default board
+	for _, code := range dictionary.LanguageCodes() {
+		dice, err := Dice(code, DefaultSize)
+		g.Expect(err).To(BeNil(), code)
+		g.Expect(dice).To(HaveLen(DefaultSize*DefaultSize), code)
+	}
+	// English has dice for the larger boards too
+	for _, size := range []int{BigSize, SuperBigSize} {
+		dice, err := Dice("en", size)
+		g.Expect(err).To(BeNil(), "%d", size)
+		g.Expect(dice).To(HaveLen(size * size))
+	}
+	_, err = Dice("fr", BigSize)
+	g.Expect(err).To(Equal(InvalidArgError{"size", "5 has no Français dice"}))
+	_, err = Dice("xx", DefaultSize)
+	g.Expect(err).To(Equal(InvalidArgError{"language", "xx"}))
+	_, err = Dice("en", 3)
+	g.Expect(err).To(Equal(InvalidArgError{"size", "3 has no English dice"}))
+}
@@ -0,0 +1,74 @@
+package boggle
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/bbawn/boredgames/internal/dictionary"
+)
+
+// Die is a letter die. A face is usually one letter but may be more, as
+// the "Qu" face is, since Q is useless alone, or none, if it is blank.
+type Die [6]string
+
+// newDie returns the die with the faces given by the spec, see
+// dictionary.Language.Dice
+func newDie(spec string) (Die, error) {
+	var (
+		d Die
+		i = -1
+	)
+	for _, r := range spec {
+		if r != '-' && !unicode.IsUpper(r) {
+			if i < 0 || d[i] == "" {
+				return d, fmt.Errorf("die %s has a face starting with lower case %c", spec, r)
+			}
+			d[i] += string(r)
+			continue
+		}
+		i++
+		if i == len(d) {
+			return d, fmt.Errorf("die %s has more than %d faces", spec, len(d))
+		}
+		if r != '-' {
+			d[i] = string(r)
+		}
+	}
+	if i != len(d)-1 {
+		return d, fmt.Errorf("die %s has %d faces, not %d", spec, i+1, len(d))
+	}
+	return d, nil
+}
+
+// Dice returns the dice of the language with the given code for a board of
+// the given size, or an InvalidArgError if there are none
+func Dice(lang string, size int) ([]Die, error) {
+	l, err := dictionary.GetLanguage(lang)
+	if err != nil {
+		return nil, InvalidArgError{"language", lang}
+	}
+	specs, ok := l.Dice[size]
+	if !ok {
+		return nil, InvalidArgError{"size", fmt.Sprintf("%d has no %s dice", size, l.Name)}
+	}
+	dice := make([]Die, len(specs))
+	for i, spec := range specs {
+		dice[i], err = newDie(spec)
+		if err != nil {
+			return nil, InvalidArgError{"dice", err.Error()}
+		}
+	}
+	return dice, nil
+}
+
+// mustDice returns Dice(lang, size), panicking on error
+func mustDice(lang string, size int) []Die {
+	dice, err := Dice(lang, size)
+	if err != nil {
+		panic(err)
+	}
+	return dice
+}
+
+// ClassicDice are the 16 dice of the standard 4x4 English game
+var ClassicDice = mustDice(dictionary.DefaultLanguage, DefaultSize)
@@ -0,0 +1,417 @@
+package boggle
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dictionary"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+type State byte
+
+const (
+	// Playing rounds accept words from players
+	Playing State = iota
+	// RoundOver rounds have been scored and await NextRound
+	RoundOver
+)
+
+//go:generate stringer -type=State
+
+// Mode is the way a game is played
+type Mode byte
+
+const (
+	// Classic games have players find words on a rolled board
+	Classic Mode = iota
+	// Reverse games have players place letters on a shared, initially
+	// blank, board to spell clue words, see PlaceLetter
+	Reverse
+)
+
+//go:generate stringer -type=Mode
+
+// Player is a participant in a boggle game
+type Player struct {
+	Username string `json:"username"`
+	// Words are the words the player has found this round, in the order
+	// submitted
+	Words []string `json:"words"`
+	// RoundScore is the player's score for the last round ended
+	RoundScore int `json:"roundScore"`
+	// Score is the player's total score over all rounds ended
+	Score int `json:"score"`
+}
+
+// Game is an instance of a boggle game
+type Game struct {
+	ID      uuid.UUID          `json:"id"`
+	Players map[string]*Player `json:"players"`
+	// Spectators are users watching the game who may not submit words
+	Spectators map[string]bool `json:"spectators"`
+	// Language is the code of the dictionary.Language the game is played
+	// in
+	Language string `json:"language"`
+	// Size is the number of rows, and of columns, of the Board
+	Size int  `json:"size"`
+	Mode Mode `json:"mode"`
+	// Round is the number of the current round, from 1
+	Round int   `json:"round"`
+	State State `json:"state"`
+	// Seed is the seed the current round's Board was rolled with, which in
+	// a Reverse game may be derived from the one given to deal enough clues
+	Seed  int64 `json:"seed"`
+	Board Board `json:"board"`
+	// Target is the board rolled with Seed in a Reverse game, whose
+	// words the Clues are, hidden from players during the round
+	Target Board `json:"target,omitempty"`
+	// Clues are the words to spell on the Board in a Reverse game
+	Clues []*Clue `json:"clues,omitempty"`
+	// Cancelled are the words found by more than one player in the last
+	// round ended, which score for none of them, sorted
+	Cancelled []string `json:"cancelled,omitempty"`
+	// Solution are all the words on the Board of the last round ended,
+	// revealed once it is over, sorted
+	Solution []string `json:"solution,omitempty"`
+	// LastActivity is the time the game was last saved
+	LastActivity time.Time `json:"lastActivity"`
+}
+
+// InvalidArgError indicates an argument is invalid
+type InvalidArgError struct {
+	Arg   string
+	Value string
+}
+
+func (e InvalidArgError) Error() string {
+	return fmt.Sprintf("Invalid value: %s for arg: %s", e.Value, e.Arg)
+}
+
+// InvalidStateError indicates the Method was called for an object that is not in
+// the right state for it
+type InvalidStateError struct {
+	Method  string
+	Details string
+}
+
+func (e InvalidStateError) Error() string {
+	return fmt.Sprintf("Invalid method: %s detail: %s", e.Method, e.Details)
+}
+
+// Dictionary decides which words are valid
+type Dictionary interface {
+	// Contains returns whether the given word is valid
+	Contains(word string) bool
+}
+
+// Options are the settings of a new game
+type Options struct {
+	// Language is the code of the dictionary.Language to play in,
+	// dictionary.DefaultLanguage if empty
+	Language string `json:"language"`
+	// Size is the number of rows, and of columns, of the board,
+	// DefaultSize if 0. The Language must have dice for it.
+	Size int  `json:"size"`
+	Mode Mode `json:"mode"`
+	// Lexicon deals the clues of Reverse games, which need one
+	Lexicon Lexicon `json:"-"`
+	// Seed is the seed to roll the first board with, random if 0
+	Seed int64 `json:"seed"`
+}
+
+// NewGame returns a game in the default language with the given players
+// playing its first round on a randomly rolled board
+func NewGame(usernames ...string) (*Game, error) {
+	return NewGameOptions(Options{}, usernames...)
+}
+
+// NewGameOptions returns a game with the given options and players playing
+// its first round. An InvalidArgError is returned if the options are
+// invalid.
+func NewGameOptions(opts Options, usernames ...string) (*Game, error) {
+	g := new(Game)
+	g.ID = uuid.New()
+	g.Players = make(map[string]*Player)
+	g.Spectators = make(map[string]bool)
+	for _, u := range usernames {
+		if err := g.AddPlayer(u); err != nil {
+			return nil, err
+		}
+	}
+	g.Language = opts.Language
+	if g.Language == "" {
+		g.Language = dictionary.DefaultLanguage
+	}
+	g.Size = opts.Size
+	if g.Size == 0 {
+		g.Size = DefaultSize
+	}
+	if _, err := Dice(g.Language, g.Size); err != nil {
+		return nil, err
+	}
+	g.Mode = opts.Mode
+	if g.Mode != Classic && g.Mode != Reverse {
+		return nil, InvalidArgError{"mode", fmt.Sprintf("%d", g.Mode)}
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	if err := g.roll(seed, opts.Lexicon); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// roll starts the next round on the board rolled with the given seed or,
+// in a Reverse game, on a blank board with clues dealt by the lexicon. A
+// Reverse board with fewer than MinClues words is re-rolled with a seed
+// derived from the last, up to maxRolls times.
+func (g *Game) roll(seed int64, lex Lexicon) error {
+	if g.Mode == Reverse && lex == nil {
+		return InvalidArgError{"lexicon", "none to deal reverse game clues"}
+	}
+	board := Roll(mustDice(g.Language, g.Size), seed)
+	var clues []*Clue
+	if g.Mode == Reverse {
+		for i := 0; ; i++ {
+			clues = dealClues(board, lex, MinWordLen(g.Size))
+			if len(clues) >= MinClues {
+				break
+			}
+			if i == maxRolls {
+				return InvalidArgError{"lexicon", fmt.Sprintf("too few words to deal %d clues", MinClues)}
+			}
+			seed = rand.New(rand.NewSource(seed)).Int63()
+			board = Roll(mustDice(g.Language, g.Size), seed)
+		}
+	}
+	g.Round++
+	g.State = Playing
+	g.Seed = seed
+	g.Board = board
+	if g.Mode == Reverse {
+		g.Target = g.Board
+		g.Clues = clues
+		g.Board = blankBoard(g.Size)
+	}
+	return nil
+}
+
+// AddPlayer adds a player with no words to the game, which may be in
+// progress. The username must be valid per validate.Username, in whose
+// normal form it is stored, and no player or other spectator may have the
+// same username but for case. A spectator who joins becomes a player and is
+// no longer a spectator.
+func (g *Game) AddPlayer(username string) error {
+	username, err := validate.Username(username)
+	if err != nil {
+		return err
+	}
+	if _, present := g.Players[username]; present {
+		return InvalidArgError{"username", username + " already present"}
+	}
+	if other := g.user(username); other != "" && !g.Spectators[username] {
+		return InvalidArgError{"username", username + " already present as " + other}
+	}
+	delete(g.Spectators, username)
+	g.Players[username] = &Player{Username: username, Words: []string{}}
+	return nil
+}
+
+// RemovePlayer removes a player, and the words they found, from the game
+func (g *Game) RemovePlayer(username string) error {
+	username = validate.Normal(username)
+	if _, present := g.Players[username]; !present {
+		return InvalidArgError{"username", username}
+	}
+	delete(g.Players, username)
+	return nil
+}
+
+// AddSpectator adds a user who receives game updates but may not submit
+// words. The username must be valid per validate.Username and, regardless
+// of case, not already a player or spectator.
+func (g *Game) AddSpectator(username string) error {
+	username, err := validate.Username(username)
+	if err != nil {
+		return err
+	}
+	if _, present := g.Players[username]; present {
+		return InvalidArgError{"username", username + " is a player"}
+	}
+	if g.Spectators[username] {
+		return InvalidArgError{"username", username + " already present"}
+	}
+	if other := g.user(username); other != "" {
+		return InvalidArgError{"username", username + " already present as " + other}
+	}
+	if g.Spectators == nil {
+		g.Spectators = make(map[string]bool)
+	}
+	g.Spectators[username] = true
+	return nil
+}
+
+// RemoveSpectator removes the given spectator from the game
+func (g *Game) RemoveSpectator(username string) error {
+	username = validate.Normal(username)
+	if !g.Spectators[username] {
+		return InvalidArgError{"username", username + " is not a spectator"}
+	}
+	delete(g.Spectators, username)
+	return nil
+}
+
+// player returns the player with the given username, or an
+// InvalidArgError if they are not playing
+func (g *Game) player(username string) (*Player, error) {
+	if g.Spectators[username] {
+		return nil, InvalidArgError{"username", username + " is a spectator"}
+	}
+	p, present := g.Players[username]
+	if !present {
+		return nil, InvalidArgError{"username", username}
+	}
+	return p, nil
+}
+
+// user returns the player or spectator with the same username as the
+// given one but for case, or "" if there is none
+func (g *Game) user(username string) string {
+	key := validate.Key(username)
+	for u := range g.Players {
+		if validate.Key(u) == key {
+			return u
+		}
+	}
+	for u := range g.Spectators {
+		if validate.Key(u) == key {
+			return u
+		}
+	}
+	return ""
+}
+
+// SubmitWord validates a word found by a player and adds it, normalized
+// per the game's Language, to their words for the round.
+//
+// If the round is over or the game is not Classic, an InvalidStateError is
+// returned.
+//
+// If the given username is not a player in the Game, an
+// InvalidArgError(Arg="username") is returned.
+//
+// If the word is not spelled with letters of the Language, is shorter than
+// the MinWordLen of the game's Size, was already submitted by the player,
+// cannot be traced on the board (see Board.Path) or is not in the given
+// dictionary, unless it is nil, an InvalidArgError(Arg="word") is
+// returned.
+func (g *Game) SubmitWord(username, word string, dict Dictionary) error {
+	username = validate.Normal(username)
+	if g.State != Playing {
+		return InvalidStateError{"SubmitWord", "round is over"}
+	}
+	if g.Mode != Classic {
+		return InvalidStateError{"SubmitWord", "game is not classic"}
+	}
+	p, err := g.player(username)
+	if err != nil {
+		return err
+	}
+	lang, err := dictionary.GetLanguage(g.Language)
+	if err != nil {
+		return InvalidStateError{"SubmitWord", err.Error()}
+	}
+	normalized, ok := lang.Normalize(word)
+	if !ok {
+		return InvalidArgError{"word", word + " is not spelled with " + lang.Name + " letters"}
+	}
+	word = normalized
+	if min := MinWordLen(g.Size); utf8.RuneCountInString(word) < min {
+		return InvalidArgError{"word", fmt.Sprintf("%s is shorter than %d letters", word, min)}
+	}
+	for _, w := range p.Words {
+		if w == word {
+			return InvalidArgError{"word", word + " already submitted"}
+		}
+	}
+	if g.Board.Path(word) == nil {
+		return InvalidArgError{"word", word + " is not on the board"}
+	}
+	if dict != nil && !dict.Contains(word) {
+		return InvalidArgError{"word", word + " is not in the dictionary"}
+	}
+	p.Words = append(p.Words, word)
+	return nil
+}
+
+// EndRound ends the round and scores each player's words, except that
+// words found by more than one player are cancelled and score for none.
+// Unless the given lexicon is nil, the Solution of a Classic round is
+// revealed.
+func (g *Game) EndRound(lex Lexicon) error {
+	if g.State != Playing {
+		return InvalidStateError{"EndRound", "round is already over"}
+	}
+	finders := make(map[string]int)
+	for _, p := range g.Players {
+		for _, w := range p.Words {
+			finders[w]++
+		}
+	}
+	g.Cancelled = nil
+	for w, n := range finders {
+		if n > 1 {
+			g.Cancelled = append(g.Cancelled, w)
+		}
+	}
+	sort.Strings(g.Cancelled)
+	for _, p := range g.Players {
+		p.RoundScore = 0
+		for _, w := range p.Words {
+			if finders[w] == 1 {
+				p.RoundScore += Score(w)
+			}
+		}
+		p.Score += p.RoundScore
+	}
+	if lex != nil && g.Mode == Classic {
+		g.Solution = g.Board.Solve(lex, MinWordLen(g.Size))
+	}
+	g.State = RoundOver
+	return nil
+}
+
+// NextRound starts a new round on a board rolled with the given seed,
+// clearing the players' words. The lexicon deals the clues of a Reverse
+// game, which needs one.
+func (g *Game) NextRound(seed int64, lex Lexicon) error {
+	if g.State != RoundOver {
+		return InvalidStateError{"NextRound", "round is not over"}
+	}
+	if err := g.roll(seed, lex); err != nil {
+		return err
+	}
+	for _, p := range g.Players {
+		p.Words = []string{}
+	}
+	g.Cancelled = nil
+	g.Solution = nil
+	return nil
+}
+
+// Scores returns each player's total score, keyed on username
+func (g *Game) Scores() map[string]int {
+	scores := make(map[string]int)
+	for u, p := range g.Players {
+		scores[u] = p.Score
+	}
+	return scores
+}
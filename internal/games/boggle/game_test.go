@@ -0,0 +1,217 @@
+package boggle
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dictionary"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+func getUsernames() []string {
+	return []string{"Joe", "Natasha", "Maria"}
+}
+
+func TestNewGame(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGameOptions(Options{Seed: 42}, getUsernames()...)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).To(HaveLen(3))
+	g.Expect(game.Round).To(Equal(1))
+	g.Expect(game.State).To(Equal(Playing))
+	g.Expect(game.Language).To(Equal("en"))
+	g.Expect(game.Seed).To(Equal(int64(42)))
+	g.Expect(game.Board).To(Equal(Roll(ClassicDice, 42)))
+
+	_, err = NewGameOptions(Options{Language: "xx"})
+	g.Expect(err).To(Equal(InvalidArgError{"language", "xx"}))
+	_, err = NewGame("Joe", "")
+	g.Expect(err).To(Equal(validate.Error{Field: "username", Value: "", Reason: "empty"}))
+	_, err = NewGame("Joe", "JOE")
+	g.Expect(err).To(Equal(InvalidArgError{"username", "JOE already present as Joe"}))
+}
+
+func TestMembership(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+
+	g.Expect(game.AddSpectator("Joe")).To(Equal(InvalidArgError{"username", "Joe is a player"}))
+	g.Expect(game.AddSpectator("Ivan")).To(Succeed())
+	g.Expect(game.AddSpectator("Ivan")).To(Equal(InvalidArgError{"username", "Ivan already present"}))
+	g.Expect(game.SubmitWord("Ivan", "cat", nil)).To(Equal(InvalidArgError{"username", "Ivan is a spectator"}))
+
+	// A spectator who joins becomes a player
+	g.Expect(game.AddPlayer("Ivan")).To(Succeed())
+	g.Expect(game.Spectators).NotTo(HaveKey("Ivan"))
+	g.Expect(game.Players).To(HaveKey("Ivan"))
+	g.Expect(game.RemoveSpectator("Ivan")).To(Equal(InvalidArgError{"username", "Ivan is not a spectator"}))
+
+	g.Expect(game.RemovePlayer("Ivan")).To(Succeed())
+	g.Expect(game.RemovePlayer("Ivan")).To(Equal(InvalidArgError{"username", "Ivan"}))
+
+	// Usernames are found when given in another normal form
+	game.Board = testBoard()
+	g.Expect(game.AddPlayer("Jos\u00e9")).To(Succeed())
+	g.Expect(game.SubmitWord("Jose\u0301", "cats", nil)).To(Succeed())
+	g.Expect(game.View("Jose\u0301").Players["Jos\u00e9"].Words).To(Equal([]string{"CATS"}))
+	g.Expect(game.RemovePlayer("Jose\u0301")).To(Succeed())
+	g.Expect(game.Players).NotTo(HaveKey("Jos\u00e9"))
+	g.Expect(game.AddSpectator("Rene\u0301")).To(Succeed())
+	g.Expect(game.RemoveSpectator("Ren\u00e9")).To(Succeed())
+	g.Expect(game.Spectators).To(BeEmpty())
+}
+
+func TestSubmitWord(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	game.Board = testBoard()
+
+	g.Expect(game.SubmitWord("Joe", " cats ", nil)).To(Succeed())
+	g.Expect(game.SubmitWord("Joe", "Quit", nil)).To(Succeed())
+	g.Expect(game.Players["Joe"].Words).To(Equal([]string{"CATS", "QUIT"}))
+
+	g.Expect(game.SubmitWord("Ivan", "cat", nil)).To(Equal(InvalidArgError{"username", "Ivan"}))
+	g.Expect(game.SubmitWord("Joe", "cats", nil)).To(Equal(InvalidArgError{"word", "CATS already submitted"}))
+	g.Expect(game.SubmitWord("Joe", "at", nil)).To(Equal(InvalidArgError{"word", "AT is shorter than 3 letters"}))
+	g.Expect(game.SubmitWord("Joe", "cat's", nil)).To(Equal(InvalidArgError{"word", "cat's is not spelled with English letters"}))
+	g.Expect(game.SubmitWord("Joe", "send", nil)).To(Equal(InvalidArgError{"word", "SEND is not on the board"}))
+	g.Expect(game.Players["Joe"].Words).To(HaveLen(2))
+}
+
+func TestSubmitWordLanguage(t *testing.T) {
+	g := NewGomegaWithT(t)
+	reg, err := dictionary.NewRegistry()
+	g.Expect(err).To(BeNil())
+	en, err := reg.Get("en")
+	g.Expect(err).To(BeNil())
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	game.Board = testBoard()
+
+	// Words must be in the dictionary, if given
+	g.Expect(game.SubmitWord("Joe", "cats", en)).To(Succeed())
+	g.Expect(game.SubmitWord("Joe", "tinge", en)).To(Succeed())
+	g.Expect(game.SubmitWord("Joe", "aqui", en)).To(Equal(InvalidArgError{"word", "AQUI is not in the dictionary"}))
+
+	// Accents fold to the letters of French, on the board of French dice
+	fr, err := reg.Get("fr")
+	g.Expect(err).To(BeNil())
+	game, err = NewGameOptions(Options{Language: "fr"}, getUsernames()...)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Board).To(Equal(Roll(mustDice("fr", DefaultSize), game.Seed)))
+	game.Board = Board{
+		{"E", "T", "E", "S"},
+		{"C", "A", "F", "X"},
+		{"X", "X", "X", "X"},
+		{"X", "X", "X", "X"},
+	}
+	g.Expect(game.SubmitWord("Joe", "été", fr)).To(Succeed())
+	g.Expect(game.SubmitWord("Joe", "Café", fr)).To(Succeed())
+	g.Expect(game.Players["Joe"].Words).To(Equal([]string{"ETE", "CAFE"}))
+	g.Expect(game.SubmitWord("Joe", "año", fr)).To(Equal(InvalidArgError{"word", "año is not spelled with Français letters"}))
+}
+
+func TestSize(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGameOptions(Options{Size: BigSize}, getUsernames()...)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Size).To(Equal(BigSize))
+	g.Expect(game.Board).To(Equal(Roll(mustDice("en", BigSize), game.Seed)))
+	_, err = NewGameOptions(Options{Language: "fr", Size: SuperBigSize})
+	g.Expect(err).To(Equal(InvalidArgError{"size", "6 has no Français dice"}))
+
+	// Larger boards need longer words
+	game.Board = testBoard()
+	g.Expect(game.SubmitWord("Joe", "cat", nil)).To(Equal(InvalidArgError{"word", "CAT is shorter than 4 letters"}))
+	g.Expect(game.SubmitWord("Joe", "cats", nil)).To(Succeed())
+
+	// The end of the round reveals the words on the board, which the next
+	// round clears
+	g.Expect(game.EndRound(testLexicon())).To(Succeed())
+	g.Expect(game.Solution).To(Equal([]string{"CATS", "QUIT", "SING", "TINGE"}))
+	g.Expect(game.View("").Solution).To(Equal(game.Solution))
+	g.Expect(game.NextRound(1, nil)).To(Succeed())
+	g.Expect(game.Solution).To(BeNil())
+	g.Expect(game.Board.Size()).To(Equal(BigSize))
+}
+
+func TestRounds(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	game.Board = testBoard()
+
+	g.Expect(game.NextRound(1, nil)).To(Equal(InvalidStateError{"NextRound", "round is not over"}))
+	for _, w := range []string{"cats", "quit", "tinge"} {
+		g.Expect(game.SubmitWord("Joe", w, nil)).To(Succeed())
+	}
+	for _, w := range []string{"cats", "sing"} {
+		g.Expect(game.SubmitWord("Natasha", w, nil)).To(Succeed())
+	}
+	g.Expect(game.SubmitWord("Maria", "tinge", nil)).To(Succeed())
+
+	// Words found by more than one player score for none of them
+	g.Expect(game.EndRound(nil)).To(Succeed())
+	g.Expect(game.State).To(Equal(RoundOver))
+	g.Expect(game.Cancelled).To(Equal([]string{"CATS", "TINGE"}))
+	g.Expect(game.Players["Joe"].RoundScore).To(Equal(1))
+	g.Expect(game.Players["Natasha"].RoundScore).To(Equal(1))
+	g.Expect(game.Players["Maria"].RoundScore).To(Equal(0))
+	g.Expect(game.Scores()).To(Equal(map[string]int{"Joe": 1, "Natasha": 1, "Maria": 0}))
+
+	g.Expect(game.EndRound(nil)).To(Equal(InvalidStateError{"EndRound", "round is already over"}))
+	g.Expect(game.SubmitWord("Joe", "sing", nil)).To(Equal(InvalidStateError{"SubmitWord", "round is over"}))
+
+	// The next round is rolled with the given seed, clears words and keeps
+	// total scores
+	g.Expect(game.NextRound(7, nil)).To(Succeed())
+	g.Expect(game.Seed).To(Equal(int64(7)))
+	g.Expect(game.Board).To(Equal(Roll(mustDice("en", DefaultSize), 7)))
+	g.Expect(game.Round).To(Equal(2))
+	g.Expect(game.State).To(Equal(Playing))
+	g.Expect(game.Cancelled).To(BeNil())
+	g.Expect(game.Board).To(Equal(Roll(ClassicDice, game.Seed)))
+	g.Expect(game.Players["Joe"].Words).To(BeEmpty())
+	g.Expect(game.Scores()).To(Equal(map[string]int{"Joe": 1, "Natasha": 1, "Maria": 0}))
+}
+
+func TestGameJSON(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	game.Board = testBoard()
+	g.Expect(game.SubmitWord("Joe", "quit", nil)).To(Succeed())
+	b, err := json.Marshal(game)
+	g.Expect(err).To(BeNil())
+	var game2 *Game
+	g.Expect(json.Unmarshal(b, &game2)).To(Succeed())
+	g.Expect(game2).To(Equal(game))
+}
+
+func TestView(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	game.Board = testBoard()
+	g.Expect(game.SubmitWord("Joe", "cats", nil)).To(Succeed())
+	g.Expect(game.SubmitWord("Maria", "quit", nil)).To(Succeed())
+
+	// Only the viewer's words are shown during the round
+	v := game.View("Joe")
+	g.Expect(v.Board).To(Equal(game.Board))
+	g.Expect(v.Players["Joe"].Words).To(Equal([]string{"CATS"}))
+	g.Expect(v.Players["Maria"].Words).To(BeNil())
+	g.Expect(v.Players["Maria"].WordCount).To(Equal(1))
+	g.Expect(game.View("").Players["Joe"].Words).To(BeNil())
+
+	// All words are shown once it is over
+	g.Expect(game.EndRound(nil)).To(Succeed())
+	v = game.View("")
+	g.Expect(v.Players["Joe"].Words).To(Equal([]string{"CATS"}))
+	g.Expect(v.Players["Maria"].Words).To(Equal([]string{"QUIT"}))
+	g.Expect(v.Players["Maria"].Score).To(Equal(1))
+}
@@ -0,0 +1,139 @@
+package boggle
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// Actions of the game in the generic game API
+const (
+	// WordAction submits a word found on the board, with a WordPayload
+	WordAction = "word"
+	// LetterAction places a die face on the board of a reverse game, with a
+	// LetterPayload
+	LetterAction = "letter"
+	// EndAction ends and scores the round
+	EndAction = "end"
+	// NextAction starts the next round
+	NextAction = "next"
+)
+
+// WordPayload is the payload of WordAction
+type WordPayload struct {
+	Word string `json:"word"`
+}
+
+// Validate returns an error if the word is empty
+func (p WordPayload) Validate() error {
+	if p.Word == "" {
+		return InvalidArgError{"word", "empty word"}
+	}
+	return nil
+}
+
+// LetterPayload is the payload of LetterAction
+type LetterPayload struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+	// Face is the die face to place, "" to blank the cell
+	Face string `json:"face"`
+}
+
+// Validate returns an error if the cell is off every board
+func (p LetterPayload) Validate() error {
+	if p.Row < 0 || p.Col < 0 {
+		return InvalidArgError{"cell", fmt.Sprintf("%d,%d is off the board", p.Row, p.Col)}
+	}
+	return nil
+}
+
+// Generic adapts a Game to the games.Game interface
+type Generic struct {
+	*Game
+	// Lexicon is the dictionary of the game's language, which words are
+	// checked against and rounds are solved and dealt clues with
+	Lexicon Lexicon
+}
+
+func (g Generic) GameID() uuid.UUID {
+	return g.ID
+}
+
+func (g Generic) Apply(a games.Action, now time.Time) error {
+	switch a.Type {
+	case WordAction:
+		var p WordPayload
+		if err := games.DecodePayload(a, &p); err != nil {
+			return err
+		}
+		return g.SubmitWord(a.Player, p.Word, g.Lexicon)
+	case LetterAction:
+		var p LetterPayload
+		if err := games.DecodePayload(a, &p); err != nil {
+			return err
+		}
+		return g.PlaceLetter(a.Player, Cell{p.Row, p.Col}, p.Face)
+	case EndAction:
+		return g.EndRound(g.Lexicon)
+	case NextAction:
+		return g.NextRound(a.Seed, g.Lexicon)
+	case games.JoinAction:
+		return g.AddPlayer(a.Player)
+	case games.LeaveAction:
+		return g.RemovePlayer(a.Player)
+	case games.WatchAction:
+		return g.AddSpectator(a.Player)
+	case games.UnwatchAction:
+		return g.RemoveSpectator(a.Player)
+	default:
+		return games.UnknownAction(a.Type)
+	}
+}
+
+func (g Generic) View(username string) interface{} {
+	return g.Game.View(username)
+}
+
+// hiddenError replaces the error of a redacted result, which may quote
+// the word
+const hiddenError = "hidden until the round is over"
+
+// Redact hides the words other players submitted in the round being
+// played from the given user, as View does, including those rejected. The
+// round started with the last NextAction that succeeded, if any.
+func (g Generic) Redact(results []*games.Result, username string) []*games.Result {
+	if g.State != Playing {
+		return results
+	}
+	username = validate.Normal(username)
+	start := 0
+	for i, r := range results {
+		if r.Action.Type == NextAction && r.Succeeded() {
+			start = i + 1
+		}
+	}
+	redacted := make([]*games.Result, len(results))
+	copy(redacted, results)
+	for i := start; i < len(results); i++ {
+		r := *results[i]
+		if r.Action.Type != WordAction || validate.Normal(r.Action.Player) == username {
+			continue
+		}
+		r.Action.Payload = nil
+		if !r.Succeeded() {
+			r.Error = hiddenError
+		}
+		redacted[i] = &r
+	}
+	return redacted
+}
+
+// Status never reports the game over, as there is always another round
+func (g Generic) Status() games.Status {
+	return games.Status{State: g.State.String(), Scores: g.Scores()}
+}
@@ -0,0 +1,90 @@
+package boggle
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/games"
+)
+
+func TestGeneric(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	game.Board = testBoard()
+	var gg games.Game = Generic{Game: game}
+	g.Expect(gg.GameID()).To(Equal(game.ID))
+	g.Expect(gg.Status()).To(Equal(games.Status{
+		State:  "Playing",
+		Scores: map[string]int{"Joe": 0, "Natasha": 0, "Maria": 0},
+	}))
+	g.Expect(gg.View("Joe")).To(Equal(game.View("Joe")))
+
+	now := time.Now()
+	g.Expect(gg.Apply(games.Action{Type: "shake", Player: "Joe"}, now)).To(Equal(games.UnknownAction("shake")))
+	g.Expect(gg.Apply(games.Action{Type: WordAction, Player: "Joe"}, now)).To(Equal(games.ActionError{Action: WordAction, Details: "missing payload"}))
+	g.Expect(gg.Apply(games.Action{Type: WordAction, Player: "Joe", Payload: json.RawMessage(`{"word": ""}`)}, now)).To(Equal(
+		games.ActionError{Action: WordAction, Details: "invalid payload: " + InvalidArgError{"word", "empty word"}.Error()}))
+	g.Expect(gg.Apply(games.Action{Type: WordAction, Player: "Joe", Payload: json.RawMessage(`{"word": "cats"}`)}, now)).To(Succeed())
+	g.Expect(game.Players["Joe"].Words).To(Equal([]string{"CATS"}))
+	g.Expect(gg.Apply(games.Action{Type: LetterAction, Player: "Joe", Payload: json.RawMessage(`{"row": 0, "col": 0, "face": "A"}`)}, now)).To(
+		Equal(InvalidStateError{"PlaceLetter", "game is not reverse"}))
+	g.Expect(gg.Apply(games.Action{Type: NextAction, Player: "Joe"}, now)).To(Equal(InvalidStateError{"NextRound", "round is not over"}))
+	g.Expect(gg.Apply(games.Action{Type: EndAction, Player: "Joe"}, now)).To(Succeed())
+	g.Expect(gg.Status()).To(Equal(games.Status{
+		State:  "RoundOver",
+		Scores: map[string]int{"Joe": 1, "Natasha": 0, "Maria": 0},
+	}))
+	g.Expect(gg.Apply(games.Action{Type: NextAction, Player: "Joe", Seed: 7}, now)).To(Succeed())
+	g.Expect(game.Round).To(Equal(2))
+	g.Expect(game.Board).To(Equal(Roll(mustDice("en", DefaultSize), 7)))
+
+	// Players and spectators join and leave
+	g.Expect(gg.Apply(games.Action{Type: games.JoinAction, Player: "Ivan"}, now)).To(Succeed())
+	g.Expect(gg.Status().Scores).To(HaveKeyWithValue("Ivan", 0))
+	g.Expect(gg.Apply(games.Action{Type: games.LeaveAction, Player: "Ivan"}, now)).To(Succeed())
+	g.Expect(gg.Status().Scores).NotTo(HaveKey("Ivan"))
+	g.Expect(gg.Apply(games.Action{Type: games.WatchAction, Player: "Ivan"}, now)).To(Succeed())
+	g.Expect(game.Spectators).To(HaveKey("Ivan"))
+	g.Expect(gg.Apply(games.Action{Type: games.UnwatchAction, Player: "Ivan"}, now)).To(Succeed())
+	g.Expect(game.Spectators).To(BeEmpty())
+}
+
+func TestGenericRedact(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	gg := Generic{Game: game}
+	word := func(username, w string) games.Action {
+		return games.Action{Type: WordAction, Player: username, Payload: json.RawMessage(fmt.Sprintf(`{"word": %q}`, w))}
+	}
+	results := []*games.Result{
+		{Seq: 1, Action: word("Joe", "cats"), Version: 1},
+		{Seq: 2, Action: games.Action{Type: EndAction, Player: "Joe"}, Version: 2},
+		{Seq: 3, Action: games.Action{Type: NextAction, Player: "Joe"}, Version: 3},
+		{Seq: 4, Action: word("Joe", "quit"), Version: 4},
+		{Seq: 5, Action: word("Maria", "sing"), Version: 5},
+		{Seq: 6, Action: word("Maria", "xyzzy"), Version: 5, Error: "Invalid arg: word detail: XYZZY is not a word"},
+	}
+
+	// Only the words of other players in the round being played are hidden
+	redacted := gg.Redact(results, "Maria")
+	g.Expect(redacted).To(HaveLen(len(results)))
+	g.Expect(redacted[:3]).To(Equal(results[:3]))
+	g.Expect(redacted[3].Action.Payload).To(BeNil())
+	g.Expect(redacted[3].Succeeded()).To(BeTrue())
+	g.Expect(redacted[4:]).To(Equal(results[4:]))
+	redacted = gg.Redact(results, "")
+	g.Expect(redacted[4].Action.Payload).To(BeNil())
+	g.Expect(redacted[5].Action.Payload).To(BeNil())
+	g.Expect(redacted[5].Error).To(Equal(hiddenError))
+	g.Expect(results[3].Action.Payload).NotTo(BeNil())
+
+	// Nothing is hidden once the round is over
+	game.State = RoundOver
+	g.Expect(gg.Redact(results, "")).To(Equal(results))
+}
@@ -0,0 +1,144 @@
+package boggle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bbawn/boredgames/internal/dictionary"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+const (
+	// MinClues is the fewest clues dealt for a round of a Reverse game.
+	// Boards with fewer words are re-rolled.
+	MinClues = 3
+	// MaxClues is the most clues dealt for a round of a Reverse game
+	MaxClues = 12
+	// maxRolls is the most boards rolled for a round of a Reverse game
+	// before giving up on finding MinClues words
+	maxRolls = 100
+)
+
+// Clue is a word to spell on the board of a Reverse game
+type Clue struct {
+	Word string `json:"word"`
+	// Solver is the username of the player whose letter completed the
+	// word on the board, "" while it is unsolved
+	Solver string `json:"solver,omitempty"`
+	// Path are the cells the word was completed on, which may no longer
+	// change
+	Path []Cell `json:"path,omitempty"`
+}
+
+// dealClues returns the clues of a Reverse round: the longest words of at
+// least minLen letters on the given board, at most MaxClues of them, sorted
+func dealClues(b Board, lex Lexicon, minLen int) []*Clue {
+	words := b.Solve(lex, minLen)
+	sort.SliceStable(words, func(i, j int) bool {
+		return utf8.RuneCountInString(words[i]) > utf8.RuneCountInString(words[j])
+	})
+	if len(words) > MaxClues {
+		words = words[:MaxClues]
+	}
+	sort.Strings(words)
+	clues := make([]*Clue, len(words))
+	for i, w := range words {
+		clues[i] = &Clue{Word: w}
+	}
+	return clues
+}
+
+// blankBoard returns a board of the given size with every face blank
+func blankBoard(size int) Board {
+	b := make(Board, size)
+	for row := range b {
+		b[row] = make([]string, size)
+	}
+	return b
+}
+
+// PlaceLetter places a face on a cell of the shared board of a Reverse
+// game, replacing any face there, or blanks the cell if the face is "".
+// The player completing a clue, so that it can be traced on the board (see
+// Board.Path), solves it, adding it to their words, which score as in a
+// Classic game. Once all the clues are solved the round ends.
+//
+// If the round is over or the game is not Reverse, an InvalidStateError is
+// returned.
+//
+// If the given username is not a player in the Game, an
+// InvalidArgError(Arg="username") is returned.
+//
+// If the cell is off the board or on the path of a solved clue, an
+// InvalidArgError(Arg="cell") is returned.
+//
+// If the face, normalized per the game's Language, is not on any of its
+// dice, an InvalidArgError(Arg="face") is returned.
+func (g *Game) PlaceLetter(username string, c Cell, face string) error {
+	username = validate.Normal(username)
+	if g.State != Playing {
+		return InvalidStateError{"PlaceLetter", "round is over"}
+	}
+	if g.Mode != Reverse {
+		return InvalidStateError{"PlaceLetter", "game is not reverse"}
+	}
+	p, err := g.player(username)
+	if err != nil {
+		return err
+	}
+	if c.Row < 0 || c.Row >= len(g.Board) || c.Col < 0 || c.Col >= len(g.Board[c.Row]) {
+		return InvalidArgError{"cell", fmt.Sprintf("%d,%d is off the board", c.Row, c.Col)}
+	}
+	for _, clue := range g.Clues {
+		for _, pc := range clue.Path {
+			if pc == c {
+				return InvalidArgError{"cell", fmt.Sprintf("%d,%d is in solved word %s", c.Row, c.Col, clue.Word)}
+			}
+		}
+	}
+	if face != "" {
+		if face, err = g.dieFace(face); err != nil {
+			return err
+		}
+	}
+	g.Board[c.Row][c.Col] = face
+	solved := 0
+	for _, clue := range g.Clues {
+		if clue.Solver == "" {
+			if path := g.Board.Path(clue.Word); path != nil {
+				clue.Solver = username
+				clue.Path = path
+				p.Words = append(p.Words, clue.Word)
+			}
+		}
+		if clue.Solver != "" {
+			solved++
+		}
+	}
+	if solved == len(g.Clues) {
+		return g.EndRound(nil)
+	}
+	return nil
+}
+
+// dieFace returns the face of the game's dice that the given face is
+// normalized to, or an InvalidArgError if there is none
+func (g *Game) dieFace(face string) (string, error) {
+	lang, err := dictionary.GetLanguage(g.Language)
+	if err != nil {
+		return "", InvalidStateError{"PlaceLetter", err.Error()}
+	}
+	normalized, ok := lang.Normalize(face)
+	if ok {
+		for _, d := range mustDice(g.Language, g.Size) {
+			for _, f := range d {
+				if f != "" && strings.ToUpper(f) == normalized {
+					return f, nil
+				}
+			}
+		}
+	}
+	return "", InvalidArgError{"face", face + " is not on any " + lang.Name + " die"}
+}
@@ -0,0 +1,129 @@
+package boggle
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dictionary"
+)
+
+func TestDealClues(t *testing.T) {
+	g := NewGomegaWithT(t)
+	clues := dealClues(testBoard(), testLexicon(), 3)
+	words := make([]string, len(clues))
+	for i, c := range clues {
+		words[i] = c.Word
+	}
+	g.Expect(words).To(Equal([]string{"CAT", "CATS", "QUIT", "SING", "TINGE"}))
+
+	// Only the longest words of a board with many are dealt
+	reg, err := dictionary.NewRegistry()
+	g.Expect(err).To(BeNil())
+	en, err := reg.Get("en")
+	g.Expect(err).To(BeNil())
+	b := Roll(mustDice("en", SuperBigSize), 42)
+	clues = dealClues(b, en, 4)
+	g.Expect(len(clues)).To(BeNumerically("<=", MaxClues))
+	shortest := 0
+	for _, c := range clues {
+		if n := utf8.RuneCountInString(c.Word); shortest == 0 || n < shortest {
+			shortest = n
+		}
+	}
+	dealt := make(map[string]bool)
+	for _, c := range clues {
+		dealt[c.Word] = true
+	}
+	for _, w := range b.Solve(en, 4) {
+		if !dealt[w] {
+			g.Expect(utf8.RuneCountInString(w)).To(BeNumerically("<=", shortest), w)
+		}
+	}
+}
+
+func TestReverse(t *testing.T) {
+	g := NewGomegaWithT(t)
+	_, err := NewGameOptions(Options{Mode: Reverse}, getUsernames()...)
+	g.Expect(err).To(Equal(InvalidArgError{"lexicon", "none to deal reverse game clues"}))
+	_, err = NewGameOptions(Options{Mode: 7}, getUsernames()...)
+	g.Expect(err).To(Equal(InvalidArgError{"mode", "7"}))
+
+	reg, err := dictionary.NewRegistry()
+	g.Expect(err).To(BeNil())
+	lex, err := reg.Get("en")
+	g.Expect(err).To(BeNil())
+	game, err := NewGameOptions(Options{Mode: Reverse, Seed: 42, Lexicon: lex}, getUsernames()...)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Target).To(Equal(Roll(ClassicDice, 42)))
+	g.Expect(game.Board).To(Equal(blankBoard(DefaultSize)))
+	g.Expect(game.View("Joe").Target).To(BeNil())
+	g.Expect(game.SubmitWord("Joe", "cat", nil)).To(Equal(InvalidStateError{"SubmitWord", "game is not classic"}))
+
+	// Play on the clues of the test board
+	game.Target = testBoard()
+	game.Clues = dealClues(game.Target, testLexicon(), 3)
+	for i, f := range []string{"c", "A", "T"} {
+		g.Expect(game.PlaceLetter("Joe", Cell{0, i}, f)).To(Succeed())
+	}
+	g.Expect(game.Clues[0]).To(Equal(&Clue{"CAT", "Joe", []Cell{{0, 0}, {0, 1}, {0, 2}}}))
+	g.Expect(game.PlaceLetter("Natasha", Cell{0, 3}, "s")).To(Succeed())
+	g.Expect(game.Clues[1].Solver).To(Equal("Natasha"))
+	g.Expect(game.Players["Natasha"].Words).To(Equal([]string{"CATS"}))
+
+	g.Expect(game.PlaceLetter("Joe", Cell{0, 0}, "x")).To(Equal(InvalidArgError{"cell", "0,0 is in solved word CAT"}))
+	g.Expect(game.PlaceLetter("Joe", Cell{4, 0}, "x")).To(Equal(InvalidArgError{"cell", "4,0 is off the board"}))
+	g.Expect(game.PlaceLetter("Joe", Cell{1, 1}, "Q")).To(Equal(InvalidArgError{"face", "Q is not on any English die"}))
+	g.Expect(game.PlaceLetter("Joe", Cell{1, 1}, "é")).To(Equal(InvalidArgError{"face", "é is not on any English die"}))
+	g.Expect(game.PlaceLetter("Ivan", Cell{1, 1}, "x")).To(Equal(InvalidArgError{"username", "Ivan"}))
+	g.Expect(game.PlaceLetter("Joe", Cell{3, 3}, "x")).To(Succeed())
+	g.Expect(game.Board[3][3]).To(Equal("X"))
+	g.Expect(game.PlaceLetter("Joe", Cell{3, 3}, "")).To(Succeed())
+	g.Expect(game.Board[3][3]).To(Equal(""))
+	g.Expect(game.PlaceLetter("Joe", Cell{1, 1}, "qu")).To(Succeed())
+	g.Expect(game.Board[1][1]).To(Equal("Qu"))
+
+	// Solving the last clue ends the round
+	for row := 1; row < DefaultSize; row++ {
+		for col := 0; col < DefaultSize; col++ {
+			if game.State == Playing {
+				g.Expect(game.PlaceLetter("Maria", Cell{row, col}, game.Target[row][col])).To(Succeed())
+			}
+		}
+	}
+	g.Expect(game.State).To(Equal(RoundOver))
+	for _, c := range game.Clues {
+		g.Expect(c.Solver).NotTo(BeEmpty(), c.Word)
+	}
+	g.Expect(game.Scores()).To(Equal(map[string]int{"Joe": 1, "Natasha": 1, "Maria": 4}))
+	g.Expect(game.View("Joe").Target).To(Equal(game.Target))
+	g.Expect(game.PlaceLetter("Joe", Cell{1, 1}, "x")).To(Equal(InvalidStateError{"PlaceLetter", "round is over"}))
+
+	// The next round deals new clues on a blank board
+	g.Expect(game.NextRound(1, nil)).To(Equal(InvalidArgError{"lexicon", "none to deal reverse game clues"}))
+	g.Expect(game.NextRound(1, lex)).To(Succeed())
+	g.Expect(game.Round).To(Equal(2))
+	g.Expect(game.Board).To(Equal(blankBoard(DefaultSize)))
+	g.Expect(game.Target).To(Equal(Roll(ClassicDice, game.Seed)))
+	g.Expect(game.Players["Maria"].Words).To(BeEmpty())
+
+	g.Expect(len(game.Clues)).To(BeNumerically(">=", MinClues))
+
+	// Boards with too few clues are re-rolled
+	de, err := reg.Get("de")
+	g.Expect(err).To(BeNil())
+	g.Expect(dealClues(Roll(mustDice("de", DefaultSize), 25), de, MinWordLen(DefaultSize))).To(BeEmpty())
+	game, err = NewGameOptions(Options{Language: "de", Mode: Reverse, Seed: 25, Lexicon: de}, getUsernames()...)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Seed).NotTo(Equal(int64(25)))
+	g.Expect(game.Target).To(Equal(Roll(mustDice("de", DefaultSize), game.Seed)))
+	g.Expect(len(game.Clues)).To(BeNumerically(">=", MinClues))
+	_, err = NewGameOptions(Options{Mode: Reverse, Seed: 42, Lexicon: testLexicon()}, getUsernames()...)
+	g.Expect(err).To(Equal(InvalidArgError{"lexicon", "too few words to deal 3 clues"}))
+
+	// Classic games have no letters to place
+	game, err = NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.PlaceLetter("Joe", Cell{0, 0}, "x")).To(Equal(InvalidStateError{"PlaceLetter", "game is not reverse"}))
+}
@@ -0,0 +1,62 @@
+package boggle
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Lexicon is a Dictionary that also knows whether any of its words start
+// with a prefix, which lets a solver prune its search of the board
+type Lexicon interface {
+	Dictionary
+	// HasPrefix returns whether any valid word starts with the prefix
+	HasPrefix(prefix string) bool
+}
+
+// Solve returns all the words of the lexicon of at least minLen letters
+// that can be traced on the board (see Path), in upper case and sorted
+func (b Board) Solve(lex Lexicon, minLen int) []string {
+	found := make(map[string]bool)
+	used := make([][]bool, len(b))
+	for row := range b {
+		used[row] = make([]bool, len(b[row]))
+	}
+	for row := range b {
+		for col := range b[row] {
+			b.solveFrom(Cell{row, col}, "", lex, minLen, used, found)
+		}
+	}
+	words := make([]string, 0, len(found))
+	for w := range found {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// solveFrom adds to found the words of at least minLen letters starting
+// with prefix and continuing from the given unused cell
+func (b Board) solveFrom(c Cell, prefix string, lex Lexicon, minLen int, used [][]bool, found map[string]bool) {
+	face := strings.ToUpper(b[c.Row][c.Col])
+	if face == "" {
+		return
+	}
+	prefix += face
+	if !lex.HasPrefix(prefix) {
+		return
+	}
+	if utf8.RuneCountInString(prefix) >= minLen && lex.Contains(prefix) {
+		found[prefix] = true
+	}
+	used[c.Row][c.Col] = true
+	defer func() { used[c.Row][c.Col] = false }()
+	for row := c.Row - 1; row <= c.Row+1; row++ {
+		for col := c.Col - 1; col <= c.Col+1; col++ {
+			if row < 0 || row >= len(b) || col < 0 || col >= len(b[row]) || used[row][col] {
+				continue
+			}
+			b.solveFrom(Cell{row, col}, prefix, lex, minLen, used, found)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package boggle
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dictionary"
+)
+
+// testLexicon returns a dictionary of a few English words, some on the
+// testBoard
+func testLexicon() *dictionary.Dictionary {
+	d := dictionary.New(dictionary.Languages["en"])
+	d.Load(strings.NewReader("at\ncat\ncats\nquit\nquite\nsend\nsing\ntinge\n"))
+	return d
+}
+
+func TestSolve(t *testing.T) {
+	g := NewGomegaWithT(t)
+	b := testBoard()
+	lex := testLexicon()
+
+	words := b.Solve(lex, 3)
+	g.Expect(words).To(Equal([]string{"CAT", "CATS", "QUIT", "SING", "TINGE"}))
+	for _, w := range words {
+		g.Expect(b.Path(w)).NotTo(BeNil(), w)
+	}
+	g.Expect(b.Solve(lex, 4)).To(Equal([]string{"CATS", "QUIT", "SING", "TINGE"}))
+	g.Expect(b.Solve(dictionary.New(dictionary.Languages["en"]), 3)).To(BeEmpty())
+
+	// Blank faces are not part of any word
+	b = Board{
+		{"C", "", "T"},
+		{"", "A", "S"},
+		{"", "", ""},
+	}
+	g.Expect(b.Solve(lex, 3)).To(Equal([]string{"CAT", "CATS"}))
+	g.Expect(b.Path("CAT")).To(Equal([]Cell{{0, 0}, {1, 1}, {0, 2}}))
+	g.Expect(b.Path("CT")).To(BeNil())
+}
+
+func BenchmarkSolveSuperBig(b *testing.B) {
+	reg, err := dictionary.NewRegistry()
+	if err != nil {
+		b.Fatal(err)
+	}
+	en, err := reg.Get("en")
+	if err != nil {
+		b.Fatal(err)
+	}
+	board := Roll(mustDice("en", SuperBigSize), 42)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board.Solve(en, MinWordLen(SuperBigSize))
+	}
+}
@@ -0,0 +1,77 @@
+package boggle
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// View is the projection of a Game presented to a player or spectator.
+// While a round is being played, it omits the words of players other than
+// the viewer, who could otherwise copy them, giving their number instead,
+// and the Target board of a Reverse game.
+type View struct {
+	ID           uuid.UUID              `json:"id"`
+	Players      map[string]*PlayerView `json:"players"`
+	Spectators   map[string]bool        `json:"spectators"`
+	Language     string                 `json:"language"`
+	Size         int                    `json:"size"`
+	Mode         Mode                   `json:"mode"`
+	Round        int                    `json:"round"`
+	State        State                  `json:"state"`
+	Board        Board                  `json:"board"`
+	Target       Board                  `json:"target,omitempty"`
+	Clues        []*Clue                `json:"clues,omitempty"`
+	Cancelled    []string               `json:"cancelled,omitempty"`
+	Solution     []string               `json:"solution,omitempty"`
+	LastActivity time.Time              `json:"lastActivity"`
+}
+
+// PlayerView is the projection of a Player in a View
+type PlayerView struct {
+	Username string `json:"username"`
+	// Words are nil if hidden from the viewer
+	Words      []string `json:"words,omitempty"`
+	WordCount  int      `json:"wordCount"`
+	RoundScore int      `json:"roundScore"`
+	Score      int      `json:"score"`
+}
+
+// View returns the projection of the game presented to the given user, ""
+// for anyone not playing
+func (g *Game) View(username string) *View {
+	username = validate.Normal(username)
+	v := &View{
+		ID:           g.ID,
+		Players:      make(map[string]*PlayerView, len(g.Players)),
+		Spectators:   g.Spectators,
+		Language:     g.Language,
+		Size:         g.Size,
+		Mode:         g.Mode,
+		Round:        g.Round,
+		State:        g.State,
+		Board:        g.Board,
+		Clues:        g.Clues,
+		Cancelled:    g.Cancelled,
+		Solution:     g.Solution,
+		LastActivity: g.LastActivity,
+	}
+	if g.State != Playing {
+		v.Target = g.Target
+	}
+	for u, p := range g.Players {
+		pv := &PlayerView{
+			Username:   p.Username,
+			WordCount:  len(p.Words),
+			RoundScore: p.RoundScore,
+			Score:      p.Score,
+		}
+		if g.State != Playing || u == username {
+			pv.Words = p.Words
+		}
+		v.Players[u] = pv
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package games
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/rooms"
+)
+
+// Game is a game of any registered type, as rooms play it through the
+// generic game API
+type Game interface {
+	// GameID returns the unique identifier of the game
+	GameID() uuid.UUID
+	// Apply applies the action, made at the given time, which may be one
+	// of the game type's own or a membership action. An ActionError is
+	// returned if the game has no action of its Type or its payload is
+	// invalid for it.
+	Apply(a Action, now time.Time) error
+	// View returns the projection of the game presented to the given user,
+	// or to everyone if username is ""
+	View(username string) interface{}
+	// Status returns the state of the game and the scores of its players
+	Status() Status
+}
+
+// Status is the game-independent summary of a Game
+type Status struct {
+	// State is the name of the game's state, specific to its type
+	State string `json:"state"`
+	// Over is true if no more actions but ending the game are useful
+	Over bool `json:"over"`
+	// Scores are the scores of each player, keyed on username
+	Scores map[string]int `json:"scores"`
+}
+
+// Type creates and persists the games of one type
+type Type interface {
+	// Create creates and saves a game with the given players, configured by
+	// the type's JSON options, which may be empty for the defaults
+	Create(usernames []string, options json.RawMessage) (Game, error)
+	// Get returns the game with the given ID
+	Get(id uuid.UUID) (Game, error)
+	// Apply applies the action, as of the current time, to the game with
+	// the given ID, records its Result in the game's log of actions, and
+	// saves and publishes the game. An action that fails is recorded too,
+	// and its error returned with its Result. An action whose
+	// ClientVersion is not the game's version is neither applied nor
+	// recorded.
+	Apply(id uuid.UUID, a Action) (*Result, Game, error)
+	// Delete deletes the game with the given ID
+	Delete(id uuid.UUID) error
+}
+
+// TypeError indicates a game type is not registered
+type TypeError struct {
+	Type rooms.GameType
+}
+
+func (e TypeError) Error() string {
+	return fmt.Sprintf("unsupported game type: %d", e.Type)
+}
+
+// Registry holds the Type of each game type rooms can host
+type Registry struct {
+	m     sync.RWMutex
+	types map[rooms.GameType]Type
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[rooms.GameType]Type)}
+}
+
+// Register registers t as the Type of the given game type, replacing any
+// registered before
+func (reg *Registry) Register(typ rooms.GameType, t Type) {
+	reg.m.Lock()
+	defer reg.m.Unlock()
+	reg.types[typ] = t
+}
+
+// Get returns the Type of the given game type, or a TypeError if it is not
+// registered
+func (reg *Registry) Get(typ rooms.GameType) (Type, error) {
+	reg.m.RLock()
+	defer reg.m.RUnlock()
+	t, ok := reg.types[typ]
+	if !ok {
+		return nil, TypeError{typ}
+	}
+	return t, nil
+}
+
+// GameTypes returns the registered game types, in order
+func (reg *Registry) GameTypes() []rooms.GameType {
+	reg.m.RLock()
+	defer reg.m.RUnlock()
+	typs := make([]rooms.GameType, 0, len(reg.types))
+	for typ := range reg.types {
+		typs = append(typs, typ)
+	}
+	sort.Slice(typs, func(i, j int) bool { return typs[i] < typs[j] })
+	return typs
+}
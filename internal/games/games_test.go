@@ -0,0 +1,114 @@
+package games
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/rooms"
+)
+
+func TestRegistry(t *testing.T) {
+	g := NewGomegaWithT(t)
+	reg := NewRegistry()
+	_, err := reg.Get(rooms.Set)
+	g.Expect(err).To(Equal(TypeError{rooms.Set}))
+	g.Expect(err).To(MatchError("unsupported game type: 1"))
+
+	var typ Type
+	reg.Register(rooms.Set, typ)
+	_, err = reg.Get(rooms.Set)
+	g.Expect(err).To(BeNil())
+	reg.Register(rooms.RRobots, typ)
+	reg.Register(rooms.Boggle, typ)
+	g.Expect(reg.GameTypes()).To(Equal([]rooms.GameType{rooms.Set, rooms.Boggle, rooms.RRobots}))
+}
+
+// counter is a Game that counts the increments of each player
+type counter struct {
+	counts map[string]int
+}
+
+type incPayload struct {
+	By int `json:"by"`
+}
+
+func (p incPayload) Validate() error {
+	if p.By < 1 {
+		return fmt.Errorf("%d is less than 1", p.By)
+	}
+	return nil
+}
+
+func (c *counter) GameID() uuid.UUID {
+	return uuid.Nil
+}
+
+func (c *counter) Apply(a Action, now time.Time) error {
+	if a.Type != "inc" {
+		return UnknownAction(a.Type)
+	}
+	var p incPayload
+	if err := DecodePayload(a, &p); err != nil {
+		return err
+	}
+	c.counts[a.Player] += p.By
+	return nil
+}
+
+func (c *counter) View(username string) interface{} {
+	return c.counts
+}
+
+func (c *counter) Status() Status {
+	return Status{State: "Counting", Scores: c.counts}
+}
+
+func TestDecodePayload(t *testing.T) {
+	g := NewGomegaWithT(t)
+	a := Action{Type: "inc", Player: "Joe"}
+	var p incPayload
+	g.Expect(DecodePayload(a, &p)).To(Equal(ActionError{"inc", "missing payload"}))
+	a.Payload = json.RawMessage("null")
+	g.Expect(DecodePayload(a, &p)).To(Equal(ActionError{"inc", "missing payload"}))
+	a.Payload = json.RawMessage(`{"by": "one"}`)
+	err := DecodePayload(a, &p)
+	g.Expect(err).To(BeAssignableToTypeOf(ActionError{}))
+	g.Expect(err.Error()).To(HavePrefix("Invalid action: inc detail: invalid payload:"))
+	a.Payload = json.RawMessage(`{"by": 0}`)
+	g.Expect(DecodePayload(a, &p)).To(Equal(ActionError{"inc", "invalid payload: 0 is less than 1"}))
+	a.Payload = json.RawMessage(`{"by": 2}`)
+	g.Expect(DecodePayload(a, &p)).To(Succeed())
+	g.Expect(p.By).To(Equal(2))
+}
+
+func TestMembershipAction(t *testing.T) {
+	g := NewGomegaWithT(t)
+	for _, typ := range []string{JoinAction, LeaveAction, WatchAction, UnwatchAction} {
+		g.Expect(MembershipAction(typ)).To(BeTrue(), typ)
+	}
+	g.Expect(MembershipAction("inc")).To(BeFalse())
+}
+
+func TestReplay(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Now()
+	results := []*Result{
+		{Seq: 1, Action: Action{Type: "inc", Player: "Joe", Payload: json.RawMessage(`{"by": 2}`)}, Time: now, Version: 1},
+		{Seq: 2, Action: Action{Type: "dec", Player: "Joe"}, Time: now, Version: 1, Error: "Invalid action: dec detail: no such action"},
+		{Seq: 3, Action: Action{Type: "inc", Player: "Maria", Payload: json.RawMessage(`{"by": 1}`)}, Time: now, Version: 2},
+	}
+	c := &counter{map[string]int{"Joe": 0, "Maria": 0}}
+	g.Expect(Replay(c, results)).To(Succeed())
+	g.Expect(c.counts).To(Equal(map[string]int{"Joe": 2, "Maria": 1}))
+
+	// A result that no longer applies stops the replay
+	results[1].Error = ""
+	c = &counter{map[string]int{"Joe": 0, "Maria": 0}}
+	g.Expect(Replay(c, results)).To(MatchError("replaying result 2: Invalid action: dec detail: no such action"))
+	g.Expect(c.counts).To(Equal(map[string]int{"Joe": 2, "Maria": 0}))
+}
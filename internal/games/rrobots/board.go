@@ -0,0 +1,219 @@
+package rrobots
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Size is the number of rows, and of columns, of the board
+const Size = 16
+
+type Direction byte
+
+const (
+	North Direction = iota
+	East
+	South
+	West
+)
+
+//go:generate stringer -type=Direction
+
+// Valid returns true if d is one of the defined Directions
+func (d Direction) Valid() bool {
+	return d <= West
+}
+
+// rotate returns the direction turned clockwise by n quarter turns
+func (d Direction) rotate(n int) Direction {
+	return (d + Direction(n)) % 4
+}
+
+// opposite returns the direction facing d
+func (d Direction) opposite() Direction {
+	return d.rotate(2)
+}
+
+// delta returns the change in row and column of a step in the direction
+func (d Direction) delta() (int, int) {
+	switch d {
+	case North:
+		return -1, 0
+	case East:
+		return 0, 1
+	case South:
+		return 1, 0
+	default:
+		return 0, -1
+	}
+}
+
+// Wall is the set of sides of a cell with a wall, bit 1<<d for the side
+// facing Direction d
+type Wall byte
+
+// Has returns whether the wall on the side facing d is in the set
+func (w Wall) Has(d Direction) bool {
+	return w&(1<<d) != 0
+}
+
+type Color byte
+
+const (
+	Red Color = iota
+	Green
+	Blue
+	Yellow
+	// Any is the color of the vortex target, which any robot may reach
+	Any
+)
+
+//go:generate stringer -type=Color
+
+// NumRobots is the number of robots, one of each Color but Any
+const NumRobots = int(Any)
+
+// Valid returns true if c is one of the defined Colors
+func (c Color) Valid() bool {
+	return c <= Any
+}
+
+type Shape byte
+
+const (
+	Circle Shape = iota
+	Triangle
+	Square
+	Hexagon
+	Vortex
+)
+
+//go:generate stringer -type=Shape
+
+// Valid returns true if s is one of the defined Shapes
+func (s Shape) Valid() bool {
+	return s <= Vortex
+}
+
+// Cell is the position of a square of the Board
+type Cell struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// onBoard returns whether the cell is on the board
+func (c Cell) onBoard() bool {
+	return c.Row >= 0 && c.Row < Size && c.Col >= 0 && c.Col < Size
+}
+
+// step returns the neighbor of the cell in the given direction
+func (c Cell) step(d Direction) Cell {
+	dr, dc := d.delta()
+	return Cell{c.Row + dr, c.Col + dc}
+}
+
+// center returns whether the cell is one of the four in the middle of the
+// board, which are walled off from robots
+func (c Cell) center() bool {
+	return (c.Row == Size/2-1 || c.Row == Size/2) && (c.Col == Size/2-1 || c.Col == Size/2)
+}
+
+// Target is a chip players race to move the robot of its Color to
+type Target struct {
+	Color Color `json:"color"`
+	Shape Shape `json:"shape"`
+	Cell  Cell  `json:"cell"`
+}
+
+func (t Target) String() string {
+	if t.Color == Any {
+		return t.Shape.String()
+	}
+	return fmt.Sprintf("%s %s", t.Color, t.Shape)
+}
+
+// Robots are the cells of the robots, indexed by Color
+type Robots [NumRobots]Cell
+
+// Move is a robot's slide in a Direction until it is blocked by a wall or
+// another robot
+type Move struct {
+	Color     Color     `json:"color"`
+	Direction Direction `json:"direction"`
+}
+
+func (m Move) String() string {
+	return fmt.Sprintf("%s %s", m.Color, m.Direction)
+}
+
+// Board is the layout of walls and targets robots move on
+type Board struct {
+	// Walls are the walls of each cell, indexed by row then column
+	Walls   [Size][Size]Wall `json:"walls"`
+	Targets []Target         `json:"targets"`
+}
+
+// addWall adds a wall on the side of the cell facing d, which is also a
+// wall of the neighbor on that side
+func (b *Board) addWall(c Cell, d Direction) {
+	b.Walls[c.Row][c.Col] |= 1 << d
+	if n := c.step(d); n.onBoard() {
+		b.Walls[n.Row][n.Col] |= 1 << d.opposite()
+	}
+}
+
+// Slide returns the cell a robot of the given color stops at moving in
+// the given direction: the last before a wall or another robot
+func (b *Board) Slide(robots Robots, color Color, d Direction) Cell {
+	c := robots[color]
+	for !b.Walls[c.Row][c.Col].Has(d) {
+		n := c.step(d)
+		for i, r := range robots {
+			if Color(i) != color && r == n {
+				return c
+			}
+		}
+		c = n
+	}
+	return c
+}
+
+// Apply returns the robots after the given moves, or an InvalidArgError
+// if a move is not of a robot in a valid Direction
+func (b *Board) Apply(robots Robots, moves []Move) (Robots, error) {
+	for i, m := range moves {
+		if int(m.Color) >= NumRobots || !m.Direction.Valid() {
+			return robots, InvalidArgError{"moves", fmt.Sprintf("move %d is of color %d direction %d", i+1, m.Color, m.Direction)}
+		}
+		robots[m.Color] = b.Slide(robots, m.Color, m.Direction)
+	}
+	return robots, nil
+}
+
+// Reached returns whether the robot of the given Color, having made the
+// last move, reached the target: it is on it and of its Color, or the
+// target is a vortex. A robot left on a target has not reached it.
+func Reached(robots Robots, moved Color, t Target) bool {
+	return (t.Color == Any || t.Color == moved) && robots[moved] == t.Cell
+}
+
+// PlaceRobots returns the robots placed at random, by the given source, on
+// distinct cells off the center and the targets
+func (b *Board) PlaceRobots(rng *rand.Rand) Robots {
+	taken := make(map[Cell]bool)
+	for _, t := range b.Targets {
+		taken[t.Cell] = true
+	}
+	var robots Robots
+	for i := range robots {
+		for {
+			c := Cell{rng.Intn(Size), rng.Intn(Size)}
+			if !taken[c] && !c.center() {
+				robots[i] = c
+				taken[c] = true
+				break
+			}
+		}
+	}
+	return robots
+}
@@ -0,0 +1,135 @@
+package rrobots
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// emptyBoard returns a board with walls only around its edges
+func emptyBoard() *Board {
+	b := new(Board)
+	for i := 0; i < Size; i++ {
+		b.addWall(Cell{0, i}, North)
+		b.addWall(Cell{Size - 1, i}, South)
+		b.addWall(Cell{i, 0}, West)
+		b.addWall(Cell{i, Size - 1}, East)
+	}
+	return b
+}
+
+func TestPlace(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(place(Cell{0, 0}, 0)).To(Equal(Cell{0, 0}))
+	g.Expect(place(Cell{0, 0}, 1)).To(Equal(Cell{0, 15}))
+	g.Expect(place(Cell{0, 0}, 2)).To(Equal(Cell{15, 15}))
+	g.Expect(place(Cell{0, 0}, 3)).To(Equal(Cell{15, 0}))
+	g.Expect(place(Cell{1, 4}, 1)).To(Equal(Cell{4, 14}))
+	for corner := 0; corner < NumQuadrants; corner++ {
+		g.Expect(place(Cell{7, 7}, corner).center()).To(BeTrue())
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	g := NewGomegaWithT(t)
+	_, err := Assemble([NumQuadrants]int{0, 1, 2, 2})
+	g.Expect(err).To(Equal(InvalidArgError{"quadrants", "[0 1 2 2]"}))
+	_, err = Assemble([NumQuadrants]int{0, 1, 2, 4})
+	g.Expect(err).To(HaveOccurred())
+
+	b, err := Assemble([NumQuadrants]int{2, 0, 3, 1})
+	g.Expect(err).To(BeNil())
+	g.Expect(b.Targets).To(HaveLen(17))
+	seen := make(map[Target]bool)
+	cells := make(map[Cell]bool)
+	for _, tg := range b.Targets {
+		key := Target{tg.Color, tg.Shape, Cell{}}
+		g.Expect(seen[key]).To(BeFalse(), tg.String())
+		seen[key] = true
+		g.Expect(cells[tg.Cell]).To(BeFalse(), tg.String())
+		cells[tg.Cell] = true
+		g.Expect(tg.Cell.onBoard() && !tg.Cell.center()).To(BeTrue(), tg.String())
+		// Targets are in a corner of walls
+		w := b.Walls[tg.Cell.Row][tg.Cell.Col]
+		g.Expect(w.Has(North) != w.Has(South)).To(BeTrue(), tg.String())
+		g.Expect(w.Has(East) != w.Has(West)).To(BeTrue(), tg.String())
+	}
+
+	for row := 0; row < Size; row++ {
+		for col := 0; col < Size; col++ {
+			c := Cell{row, col}
+			for d := North; d <= West; d++ {
+				n := c.step(d)
+				has := b.Walls[row][col].Has(d)
+				if !n.onBoard() {
+					g.Expect(has).To(BeTrue(), "edge %v %s", c, d)
+					continue
+				}
+				// Walls are on both cells they separate
+				g.Expect(b.Walls[n.Row][n.Col].Has(d.opposite())).To(Equal(has), "%v %s", c, d)
+				// The center is walled off
+				if c.center() != n.center() {
+					g.Expect(has).To(BeTrue(), "center %v %s", c, d)
+				}
+			}
+		}
+	}
+}
+
+func TestSlide(t *testing.T) {
+	g := NewGomegaWithT(t)
+	b := emptyBoard()
+	robots := Robots{{0, 0}, {0, 5}, {9, 9}, {15, 15}}
+
+	g.Expect(b.Slide(robots, Red, North)).To(Equal(Cell{0, 0}))
+	g.Expect(b.Slide(robots, Red, East)).To(Equal(Cell{0, 4}))
+	g.Expect(b.Slide(robots, Red, South)).To(Equal(Cell{15, 0}))
+	g.Expect(b.Slide(robots, Green, East)).To(Equal(Cell{0, 15}))
+	g.Expect(b.Slide(robots, Yellow, West)).To(Equal(Cell{15, 0}))
+	b.addWall(Cell{0, 2}, East)
+	g.Expect(b.Slide(robots, Red, East)).To(Equal(Cell{0, 2}))
+	g.Expect(b.Slide(robots, Green, West)).To(Equal(Cell{0, 3}))
+}
+
+func TestApply(t *testing.T) {
+	g := NewGomegaWithT(t)
+	b := emptyBoard()
+	robots := Robots{{0, 0}, {0, 5}, {9, 9}, {15, 15}}
+
+	after, err := b.Apply(robots, []Move{{Red, South}, {Red, East}, {Yellow, North}})
+	g.Expect(err).To(BeNil())
+	g.Expect(after).To(Equal(Robots{{15, 14}, {0, 5}, {9, 9}, {0, 15}}))
+	g.Expect(robots[Red]).To(Equal(Cell{0, 0}))
+	_, err = b.Apply(robots, []Move{{Red, South}, {Any, East}})
+	g.Expect(err).To(Equal(InvalidArgError{"moves", "move 2 is of color 4 direction 1"}))
+	_, err = b.Apply(robots, []Move{{Red, 4}})
+	g.Expect(err).To(HaveOccurred())
+
+	g.Expect(Reached(after, Red, Target{Red, Circle, Cell{15, 14}})).To(BeTrue())
+	g.Expect(Reached(after, Green, Target{Red, Circle, Cell{15, 14}})).To(BeFalse())
+	g.Expect(Reached(after, Red, Target{Green, Circle, Cell{15, 14}})).To(BeFalse())
+	g.Expect(Reached(after, Yellow, Target{Any, Vortex, Cell{0, 15}})).To(BeTrue())
+	g.Expect(Reached(after, Red, Target{Any, Vortex, Cell{0, 15}})).To(BeFalse())
+}
+
+func TestPlaceRobots(t *testing.T) {
+	g := NewGomegaWithT(t)
+	b := RandomBoard(rand.New(rand.NewSource(42)))
+	g.Expect(RandomBoard(rand.New(rand.NewSource(42)))).To(Equal(b))
+	targets := make(map[Cell]bool)
+	for _, tg := range b.Targets {
+		targets[tg.Cell] = true
+	}
+	for seed := int64(1); seed < 100; seed++ {
+		robots := b.PlaceRobots(rand.New(rand.NewSource(seed)))
+		cells := make(map[Cell]bool)
+		for _, r := range robots {
+			g.Expect(r.onBoard()).To(BeTrue())
+			g.Expect(r.center()).To(BeFalse())
+			g.Expect(targets[r]).To(BeFalse())
+			cells[r] = true
+		}
+		g.Expect(cells).To(HaveLen(NumRobots))
+	}
+}
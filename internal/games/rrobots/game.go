@@ -0,0 +1,417 @@
+package rrobots
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+type State byte
+
+const (
+	// Bidding rounds await the first bid on the Target
+	Bidding State = iota
+	// Countdown rounds accept bids until the Deadline
+	Countdown
+	// Demonstrating rounds await the moves of the Demonstrator
+	Demonstrating
+	// RoundOver rounds await NextRound
+	RoundOver
+	// GameOver games have no Targets left
+	GameOver
+)
+
+//go:generate stringer -type=State
+
+// CountdownDuration is how long bids are accepted after the first bid of a
+// round
+const CountdownDuration = time.Minute
+
+// Player is a participant in a ricochet robots game
+type Player struct {
+	Username string `json:"username"`
+	// Chips are the targets the player has won
+	Chips []Target `json:"chips"`
+}
+
+// Bid is a player's claim to be able to reach the round's target in the
+// given number of Moves
+type Bid struct {
+	Username string    `json:"username"`
+	Moves    int       `json:"moves"`
+	Time     time.Time `json:"time"`
+}
+
+// Game is an instance of a ricochet robots game
+type Game struct {
+	ID      uuid.UUID          `json:"id"`
+	Players map[string]*Player `json:"players"`
+	// Spectators are users watching the game who may not bid
+	Spectators map[string]bool `json:"spectators"`
+	// Seed is the seed of the random source that laid out the Board,
+	// placed the Robots and shuffled the Targets
+	Seed   int64  `json:"seed"`
+	Board  *Board `json:"board"`
+	Robots Robots `json:"robots"`
+	// Target is the target of the current round
+	Target Target `json:"target"`
+	// Targets are the targets of the rounds to come, in order
+	Targets []Target `json:"targets"`
+	// Round is the number of the current round, from 1
+	Round int   `json:"round"`
+	State State `json:"state"`
+	// Bids are the round's bids yet to be demonstrated, the lowest first
+	// and, of equal bids, the earliest
+	Bids []*Bid `json:"bids"`
+	// Deadline is the time the Countdown ends
+	Deadline time.Time `json:"deadline"`
+	// Demonstrator is the username of the player whose bid is being
+	// demonstrated
+	Demonstrator string `json:"demonstrator,omitempty"`
+	// Failed are the usernames of the players who failed to demonstrate
+	// their bids this round
+	Failed []string `json:"failed,omitempty"`
+	// Winner is the username of the player who won the round's Target,
+	// Solution the moves they demonstrated
+	Winner   string `json:"winner,omitempty"`
+	Solution []Move `json:"solution,omitempty"`
+	// LastActivity is the time the game was last saved
+	LastActivity time.Time `json:"lastActivity"`
+}
+
+// InvalidArgError indicates an argument is invalid
+type InvalidArgError struct {
+	Arg   string
+	Value string
+}
+
+func (e InvalidArgError) Error() string {
+	return fmt.Sprintf("Invalid value: %s for arg: %s", e.Value, e.Arg)
+}
+
+// InvalidStateError indicates the Method was called for an object that is not in
+// the right state for it
+type InvalidStateError struct {
+	Method  string
+	Details string
+}
+
+func (e InvalidStateError) Error() string {
+	return fmt.Sprintf("Invalid method: %s detail: %s", e.Method, e.Details)
+}
+
+// Options are the settings of a new game
+type Options struct {
+	// Seed is the seed of the random source that lays out the board,
+	// places the robots and shuffles the targets, random if 0
+	Seed int64 `json:"seed"`
+}
+
+// NewGame returns a game with the given players bidding on the first
+// target of a randomly laid out board
+func NewGame(usernames ...string) (*Game, error) {
+	return NewGameOptions(Options{}, usernames...)
+}
+
+// NewGameOptions returns a game with the given options and players bidding
+// on the first target
+func NewGameOptions(opts Options, usernames ...string) (*Game, error) {
+	g := new(Game)
+	g.ID = uuid.New()
+	g.Players = make(map[string]*Player)
+	g.Spectators = make(map[string]bool)
+	for _, u := range usernames {
+		if err := g.AddPlayer(u); err != nil {
+			return nil, err
+		}
+	}
+	g.Seed = opts.Seed
+	if g.Seed == 0 {
+		g.Seed = rand.Int63()
+	}
+	rng := rand.New(rand.NewSource(g.Seed))
+	g.Board = RandomBoard(rng)
+	g.Robots = g.Board.PlaceRobots(rng)
+	g.Targets = make([]Target, len(g.Board.Targets))
+	for i, j := range rng.Perm(len(g.Targets)) {
+		g.Targets[i] = g.Board.Targets[j]
+	}
+	g.next()
+	return g, nil
+}
+
+// next starts bidding on the next of the Targets
+func (g *Game) next() {
+	g.Round++
+	g.State = Bidding
+	g.Target, g.Targets = g.Targets[0], g.Targets[1:]
+	g.Bids = []*Bid{}
+	g.Deadline = time.Time{}
+	g.Demonstrator = ""
+	g.Failed = nil
+	g.Winner = ""
+	g.Solution = nil
+}
+
+// AddPlayer adds a player with no chips to the game, which may be in
+// progress. The username must be valid per validate.Username, in whose
+// normal form it is stored, and no player or other spectator may have the
+// same username but for case. A spectator who joins becomes a player and is
+// no longer a spectator.
+func (g *Game) AddPlayer(username string) error {
+	username, err := validate.Username(username)
+	if err != nil {
+		return err
+	}
+	if _, present := g.Players[username]; present {
+		return InvalidArgError{"username", username + " already present"}
+	}
+	if other := g.user(username); other != "" && !g.Spectators[username] {
+		return InvalidArgError{"username", username + " already present as " + other}
+	}
+	delete(g.Spectators, username)
+	g.Players[username] = &Player{Username: username, Chips: []Target{}}
+	return nil
+}
+
+// RemovePlayer removes a player, and any bid of theirs, from the game. If
+// they were demonstrating, the next lowest bidder demonstrates.
+func (g *Game) RemovePlayer(username string) error {
+	username = validate.Normal(username)
+	if _, present := g.Players[username]; !present {
+		return InvalidArgError{"username", username}
+	}
+	delete(g.Players, username)
+	for i, b := range g.Bids {
+		if b.Username == username {
+			g.Bids = append(g.Bids[:i], g.Bids[i+1:]...)
+			break
+		}
+	}
+	if g.State == Demonstrating && g.Demonstrator == username {
+		g.nextDemonstrator()
+	}
+	return nil
+}
+
+// AddSpectator adds a user who receives game updates but may not bid. The
+// username must be valid per validate.Username and, regardless of case,
+// not already a player or spectator.
+func (g *Game) AddSpectator(username string) error {
+	username, err := validate.Username(username)
+	if err != nil {
+		return err
+	}
+	if _, present := g.Players[username]; present {
+		return InvalidArgError{"username", username + " is a player"}
+	}
+	if g.Spectators[username] {
+		return InvalidArgError{"username", username + " already present"}
+	}
+	if other := g.user(username); other != "" {
+		return InvalidArgError{"username", username + " already present as " + other}
+	}
+	if g.Spectators == nil {
+		g.Spectators = make(map[string]bool)
+	}
+	g.Spectators[username] = true
+	return nil
+}
+
+// RemoveSpectator removes the given spectator from the game
+func (g *Game) RemoveSpectator(username string) error {
+	username = validate.Normal(username)
+	if !g.Spectators[username] {
+		return InvalidArgError{"username", username + " is not a spectator"}
+	}
+	delete(g.Spectators, username)
+	return nil
+}
+
+// player returns the player with the given username, or an
+// InvalidArgError if they are not playing
+func (g *Game) player(username string) (*Player, error) {
+	if g.Spectators[username] {
+		return nil, InvalidArgError{"username", username + " is a spectator"}
+	}
+	p, present := g.Players[username]
+	if !present {
+		return nil, InvalidArgError{"username", username}
+	}
+	return p, nil
+}
+
+// user returns the player or spectator with the same username as the
+// given one but for case, or "" if there is none
+func (g *Game) user(username string) string {
+	key := validate.Key(username)
+	for u := range g.Players {
+		if validate.Key(u) == key {
+			return u
+		}
+	}
+	for u := range g.Spectators {
+		if validate.Key(u) == key {
+			return u
+		}
+	}
+	return ""
+}
+
+// Bid records a player's bid, made at the given time, to reach the Target
+// in the given number of moves. The first bid of the round starts the
+// Countdown. A player may bid again to lower their bid.
+//
+// If the round is not Bidding or in its Countdown, an InvalidStateError is
+// returned.
+//
+// If the given username is not a player in the Game, an
+// InvalidArgError(Arg="username") is returned.
+//
+// If the number of moves is less than 1 or not lower than the player's
+// previous bid, an InvalidArgError(Arg="moves") is returned.
+func (g *Game) Bid(username string, moves int, now time.Time) error {
+	username = validate.Normal(username)
+	if g.State != Bidding && g.State != Countdown {
+		return InvalidStateError{"Bid", "bidding is over"}
+	}
+	if g.State == Countdown && !now.Before(g.Deadline) {
+		return InvalidStateError{"Bid", "countdown is over"}
+	}
+	if _, err := g.player(username); err != nil {
+		return err
+	}
+	if moves < 1 {
+		return InvalidArgError{"moves", fmt.Sprintf("%d is less than 1", moves)}
+	}
+	for i, b := range g.Bids {
+		if b.Username == username {
+			if moves >= b.Moves {
+				return InvalidArgError{"moves", fmt.Sprintf("%d is not lower than the bid of %d", moves, b.Moves)}
+			}
+			g.Bids = append(g.Bids[:i], g.Bids[i+1:]...)
+			break
+		}
+	}
+	g.Bids = append(g.Bids, &Bid{username, moves, now})
+	sort.SliceStable(g.Bids, func(i, j int) bool {
+		return g.Bids[i].Moves < g.Bids[j].Moves
+	})
+	if g.State == Bidding {
+		g.State = Countdown
+		g.Deadline = now.Add(CountdownDuration)
+	}
+	return nil
+}
+
+// EndCountdown ends the Countdown, as of the given time, which must not be
+// before the Deadline, and has the lowest bidder demonstrate
+func (g *Game) EndCountdown(now time.Time) error {
+	if g.State != Countdown {
+		return InvalidStateError{"EndCountdown", "round is not in countdown"}
+	}
+	if now.Before(g.Deadline) {
+		return InvalidStateError{"EndCountdown", "countdown ends at " + g.Deadline.Format(time.RFC3339)}
+	}
+	g.State = Demonstrating
+	g.nextDemonstrator()
+	return nil
+}
+
+// nextDemonstrator has the lowest remaining bidder demonstrate or, if
+// there are none, ends the round with no winner, the Target returning to
+// be played last
+func (g *Game) nextDemonstrator() {
+	if len(g.Bids) > 0 {
+		g.Demonstrator = g.Bids[0].Username
+		return
+	}
+	g.Demonstrator = ""
+	g.Targets = append(g.Targets, g.Target)
+	g.endRound()
+}
+
+// endRound ends the round, and the game if no Targets are left
+func (g *Game) endRound() {
+	g.State = RoundOver
+	if len(g.Targets) == 0 {
+		g.State = GameOver
+	}
+}
+
+// Demonstrate checks the moves the Demonstrator claims reach the Target in
+// no more than they bid, the last moving a robot onto it (see Reached). If
+// they do, the Demonstrator wins the Target and the robots stay where the
+// moves left them. Otherwise the next lowest bidder demonstrates from the
+// same positions.
+//
+// If the round is not Demonstrating, an InvalidStateError is returned.
+//
+// If the given username is not the Demonstrator, an
+// InvalidArgError(Arg="username") is returned.
+//
+// If a move is not of a robot in a valid Direction, an
+// InvalidArgError(Arg="moves") is returned.
+func (g *Game) Demonstrate(username string, moves []Move) error {
+	username = validate.Normal(username)
+	if g.State != Demonstrating {
+		return InvalidStateError{"Demonstrate", "round is not demonstrating"}
+	}
+	if username != g.Demonstrator {
+		return InvalidArgError{"username", username + " is not the demonstrator"}
+	}
+	robots, err := g.Board.Apply(g.Robots, moves)
+	if err != nil {
+		return err
+	}
+	bid := g.Bids[0]
+	g.Bids = g.Bids[1:]
+	if len(moves) == 0 || len(moves) > bid.Moves || !Reached(robots, moves[len(moves)-1].Color, g.Target) {
+		g.Failed = append(g.Failed, username)
+		g.nextDemonstrator()
+		return nil
+	}
+	p := g.Players[username]
+	p.Chips = append(p.Chips, g.Target)
+	g.Robots = robots
+	g.Winner = username
+	g.Solution = moves
+	g.Demonstrator = ""
+	g.endRound()
+	return nil
+}
+
+// Skip ends a round no one has bid on, when the players give up on its
+// Target, which returns to be played last
+func (g *Game) Skip() error {
+	if g.State != Bidding {
+		return InvalidStateError{"Skip", "round has bids"}
+	}
+	g.Targets = append(g.Targets, g.Target)
+	g.endRound()
+	return nil
+}
+
+// NextRound starts bidding on the next target
+func (g *Game) NextRound() error {
+	if g.State != RoundOver {
+		return InvalidStateError{"NextRound", "round is not over"}
+	}
+	g.next()
+	return nil
+}
+
+// Scores returns each player's score, the number of chips they have won,
+// keyed on username
+func (g *Game) Scores() map[string]int {
+	scores := make(map[string]int)
+	for u, p := range g.Players {
+		scores[u] = len(p.Chips)
+	}
+	return scores
+}
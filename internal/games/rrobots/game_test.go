@@ -0,0 +1,214 @@
+package rrobots
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func getUsernames() []string {
+	return []string{"Joe", "Natasha", "Maria"}
+}
+
+// testGame returns a game on an emptyBoard whose target the red robot
+// reaches moving east
+func testGame(t *testing.T) *Game {
+	game, err := NewGameOptions(Options{Seed: 42}, getUsernames()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.Board = emptyBoard()
+	game.Robots = Robots{{0, 0}, {5, 5}, {9, 9}, {15, 15}}
+	game.Target = Target{Red, Circle, Cell{0, 15}}
+	return game
+}
+
+func TestNewGame(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGameOptions(Options{Seed: 42}, getUsernames()...)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).To(HaveLen(3))
+	g.Expect(game.Round).To(Equal(1))
+	g.Expect(game.State).To(Equal(Bidding))
+	g.Expect(game.Bids).To(BeEmpty())
+	g.Expect(game.Targets).To(HaveLen(16))
+	g.Expect(game.Board.Targets).To(ContainElement(game.Target))
+	g.Expect(game.Targets).NotTo(ContainElement(game.Target))
+
+	// The seed determines the board, robots and targets
+	other, err := NewGameOptions(Options{Seed: 42})
+	g.Expect(err).To(BeNil())
+	g.Expect(other.Board).To(Equal(game.Board))
+	g.Expect(other.Robots).To(Equal(game.Robots))
+	g.Expect(other.Target).To(Equal(game.Target))
+	g.Expect(other.Targets).To(Equal(game.Targets))
+
+	_, err = NewGame("Joe", "joe")
+	g.Expect(err).To(Equal(InvalidArgError{"username", "joe already present as Joe"}))
+}
+
+func TestMembership(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+
+	g.Expect(game.AddSpectator("Ivan")).To(Succeed())
+	g.Expect(game.AddSpectator("joe")).To(Equal(InvalidArgError{"username", "joe already present as Joe"}))
+	g.Expect(game.Bid("Ivan", 3, time.Now())).To(Equal(InvalidArgError{"username", "Ivan is a spectator"}))
+	g.Expect(game.AddPlayer("Ivan")).To(Succeed())
+	g.Expect(game.Spectators).NotTo(HaveKey("Ivan"))
+	g.Expect(game.Players["Ivan"].Chips).To(BeEmpty())
+	g.Expect(game.RemovePlayer("Ivan")).To(Succeed())
+	g.Expect(game.RemovePlayer("Ivan")).To(Equal(InvalidArgError{"username", "Ivan"}))
+	g.Expect(game.RemoveSpectator("Joe")).To(Equal(InvalidArgError{"username", "Joe is not a spectator"}))
+
+	// Usernames are found when given in another normal form
+	now := time.Now()
+	g.Expect(game.AddPlayer("Jos\u00e9")).To(Succeed())
+	g.Expect(game.Bid("Jose\u0301", 5, now)).To(Succeed())
+	g.Expect(game.Bid("Jose\u0301", 4, now)).To(Succeed())
+	g.Expect(game.Bids).To(Equal([]*Bid{{"Jos\u00e9", 4, now}}))
+	g.Expect(game.RemovePlayer("Jose\u0301")).To(Succeed())
+	g.Expect(game.Players).NotTo(HaveKey("Jos\u00e9"))
+	g.Expect(game.Bids).To(BeEmpty())
+	g.Expect(game.AddSpectator("Rene\u0301")).To(Succeed())
+	g.Expect(game.RemoveSpectator("Ren\u00e9")).To(Succeed())
+	g.Expect(game.Spectators).To(BeEmpty())
+}
+
+func TestBid(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game := testGame(t)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	g.Expect(game.EndCountdown(now)).To(Equal(InvalidStateError{"EndCountdown", "round is not in countdown"}))
+	g.Expect(game.Bid("Joe", 0, now)).To(Equal(InvalidArgError{"moves", "0 is less than 1"}))
+	g.Expect(game.Bid("Ivan", 3, now)).To(Equal(InvalidArgError{"username", "Ivan"}))
+
+	// The first bid starts the countdown
+	g.Expect(game.Bid("Joe", 5, now)).To(Succeed())
+	g.Expect(game.State).To(Equal(Countdown))
+	g.Expect(game.Deadline).To(Equal(now.Add(CountdownDuration)))
+	g.Expect(game.Bid("Natasha", 5, now.Add(time.Second))).To(Succeed())
+	g.Expect(game.Bid("Maria", 7, now.Add(2*time.Second))).To(Succeed())
+	g.Expect(game.Bid("Maria", 7, now.Add(3*time.Second))).To(Equal(InvalidArgError{"moves", "7 is not lower than the bid of 7"}))
+	g.Expect(game.Bid("Maria", 6, now.Add(4*time.Second))).To(Succeed())
+	g.Expect(game.Bids).To(Equal([]*Bid{
+		{"Joe", 5, now},
+		{"Natasha", 5, now.Add(time.Second)},
+		{"Maria", 6, now.Add(4 * time.Second)},
+	}))
+	g.Expect(game.Deadline).To(Equal(now.Add(CountdownDuration)))
+
+	g.Expect(game.EndCountdown(now.Add(time.Second))).To(Equal(InvalidStateError{"EndCountdown", "countdown ends at 2020-01-01T12:01:00Z"}))
+	g.Expect(game.Bid("Joe", 4, game.Deadline)).To(Equal(InvalidStateError{"Bid", "countdown is over"}))
+	g.Expect(game.EndCountdown(game.Deadline)).To(Succeed())
+	g.Expect(game.State).To(Equal(Demonstrating))
+	g.Expect(game.Demonstrator).To(Equal("Joe"))
+	g.Expect(game.Bid("Joe", 4, game.Deadline)).To(Equal(InvalidStateError{"Bid", "bidding is over"}))
+}
+
+func TestDemonstrate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game := testGame(t)
+	now := time.Now()
+	g.Expect(game.Demonstrate("Joe", nil)).To(Equal(InvalidStateError{"Demonstrate", "round is not demonstrating"}))
+	g.Expect(game.Bid("Joe", 1, now)).To(Succeed())
+	g.Expect(game.Bid("Natasha", 2, now)).To(Succeed())
+	g.Expect(game.Bid("Maria", 3, now)).To(Succeed())
+	g.Expect(game.EndCountdown(game.Deadline)).To(Succeed())
+
+	g.Expect(game.Demonstrate("Natasha", nil)).To(Equal(InvalidArgError{"username", "Natasha is not the demonstrator"}))
+	g.Expect(game.Demonstrate("Joe", []Move{{Any, North}})).To(Equal(InvalidArgError{"moves", "move 1 is of color 4 direction 0"}))
+
+	// Moves that miss the target fail
+	g.Expect(game.Demonstrate("Joe", []Move{{Red, South}})).To(Succeed())
+	g.Expect(game.Failed).To(Equal([]string{"Joe"}))
+	g.Expect(game.Demonstrator).To(Equal("Natasha"))
+	g.Expect(game.Robots[Red]).To(Equal(Cell{0, 0}))
+
+	// As do more moves than bid
+	moves := []Move{{Red, South}, {Red, North}, {Red, East}}
+	g.Expect(game.Demonstrate("Natasha", moves)).To(Succeed())
+	g.Expect(game.Failed).To(Equal([]string{"Joe", "Natasha"}))
+	g.Expect(game.Demonstrator).To(Equal("Maria"))
+
+	g.Expect(game.Demonstrate("Maria", moves)).To(Succeed())
+	g.Expect(game.State).To(Equal(RoundOver))
+	g.Expect(game.Winner).To(Equal("Maria"))
+	g.Expect(game.Solution).To(Equal(moves))
+	g.Expect(game.Robots[Red]).To(Equal(Cell{0, 15}))
+	g.Expect(game.Players["Maria"].Chips).To(Equal([]Target{game.Target}))
+	g.Expect(game.Scores()).To(Equal(map[string]int{"Joe": 0, "Natasha": 0, "Maria": 1}))
+
+	// The next round starts from where the robots are
+	left := len(game.Targets)
+	next := game.Targets[0]
+	g.Expect(game.NextRound()).To(Succeed())
+	g.Expect(game.Round).To(Equal(2))
+	g.Expect(game.State).To(Equal(Bidding))
+	g.Expect(game.Target).To(Equal(next))
+	g.Expect(game.Targets).To(HaveLen(left - 1))
+	g.Expect(game.Robots[Red]).To(Equal(Cell{0, 15}))
+	g.Expect(game.Bids).To(BeEmpty())
+	g.Expect(game.Failed).To(BeNil())
+	g.Expect(game.Winner).To(BeEmpty())
+}
+
+func TestNoWinner(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game := testGame(t)
+	target := game.Target
+	g.Expect(game.NextRound()).To(Equal(InvalidStateError{"NextRound", "round is not over"}))
+
+	// A skipped target is played last
+	g.Expect(game.Skip()).To(Succeed())
+	g.Expect(game.State).To(Equal(RoundOver))
+	g.Expect(game.Targets[len(game.Targets)-1]).To(Equal(target))
+	g.Expect(game.NextRound()).To(Succeed())
+
+	// As is one no bidder demonstrates, including one who leaves
+	target = game.Target
+	now := time.Now()
+	g.Expect(game.Bid("Joe", 1, now)).To(Succeed())
+	g.Expect(game.Skip()).To(Equal(InvalidStateError{"Skip", "round has bids"}))
+	g.Expect(game.Bid("Natasha", 2, now)).To(Succeed())
+	g.Expect(game.EndCountdown(game.Deadline)).To(Succeed())
+	g.Expect(game.RemovePlayer("Joe")).To(Succeed())
+	g.Expect(game.Demonstrator).To(Equal("Natasha"))
+	g.Expect(game.Demonstrate("Natasha", []Move{{Green, North}})).To(Succeed())
+	g.Expect(game.State).To(Equal(RoundOver))
+	g.Expect(game.Winner).To(BeEmpty())
+	g.Expect(game.Targets[len(game.Targets)-1]).To(Equal(target))
+}
+
+func TestGameOver(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game := testGame(t)
+	game.Targets = nil
+	g.Expect(game.Bid("Joe", 1, time.Now())).To(Succeed())
+	g.Expect(game.EndCountdown(game.Deadline)).To(Succeed())
+	g.Expect(game.Demonstrate("Joe", []Move{{Red, East}})).To(Succeed())
+	g.Expect(game.State).To(Equal(GameOver))
+	g.Expect(game.NextRound()).To(Equal(InvalidStateError{"NextRound", "round is not over"}))
+}
+
+func TestGameJSON(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Bid("Joe", 3, time.Now().UTC())).To(Succeed())
+	data, err := json.Marshal(game)
+	g.Expect(err).To(BeNil())
+	var decoded *Game
+	g.Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+	g.Expect(decoded).To(Equal(game))
+
+	v := game.View()
+	g.Expect(v.TargetsLeft).To(Equal(len(game.Targets)))
+	data, err = json.Marshal(v)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(data)).NotTo(ContainSubstring(`"seed"`))
+}
@@ -0,0 +1,115 @@
+package rrobots
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/games"
+)
+
+// Actions of the game in the generic game API
+const (
+	// BidAction bids the moves to reach the target, with a BidPayload
+	BidAction = "bid"
+	// DemonstrateAction demonstrates the moves bid, with a
+	// DemonstratePayload
+	DemonstrateAction = "demonstrate"
+	// SkipAction ends a round no one has bid on
+	SkipAction = "skip"
+	// NextAction starts bidding on the next target
+	NextAction = "next"
+	// EndCountdownAction ends a countdown that is over, calling on the
+	// lowest bidder. The server applies it as the countdown's timer ends,
+	// with no player.
+	EndCountdownAction = "endCountdown"
+)
+
+// BidPayload is the payload of BidAction
+type BidPayload struct {
+	Moves int `json:"moves"`
+}
+
+// Validate returns an error if fewer than 1 move is bid
+func (p BidPayload) Validate() error {
+	if p.Moves < 1 {
+		return InvalidArgError{"moves", fmt.Sprintf("%d is less than 1", p.Moves)}
+	}
+	return nil
+}
+
+// DemonstratePayload is the payload of DemonstrateAction
+type DemonstratePayload struct {
+	Moves []Move `json:"moves"`
+}
+
+// Validate returns an error if a move is not of a robot in a valid
+// Direction. No moves concede the demonstration.
+func (p DemonstratePayload) Validate() error {
+	for i, m := range p.Moves {
+		if int(m.Color) >= NumRobots || !m.Direction.Valid() {
+			return InvalidArgError{"moves", fmt.Sprintf("move %d is of color %d direction %d", i+1, m.Color, m.Direction)}
+		}
+	}
+	return nil
+}
+
+// Generic adapts a Game to the games.Game interface
+type Generic struct {
+	*Game
+}
+
+func (g Generic) GameID() uuid.UUID {
+	return g.ID
+}
+
+// Apply first ends the countdown if it is over as of now but no
+// EndCountdownAction ended it yet, as its timer would have
+func (g Generic) Apply(a games.Action, now time.Time) error {
+	if a.Type == EndCountdownAction {
+		return g.EndCountdown(now)
+	}
+	if g.State == Countdown && !now.Before(g.Deadline) {
+		if err := g.EndCountdown(now); err != nil {
+			return err
+		}
+	}
+	switch a.Type {
+	case BidAction:
+		var p BidPayload
+		if err := games.DecodePayload(a, &p); err != nil {
+			return err
+		}
+		return g.Bid(a.Player, p.Moves, now)
+	case DemonstrateAction:
+		var p DemonstratePayload
+		if err := games.DecodePayload(a, &p); err != nil {
+			return err
+		}
+		return g.Demonstrate(a.Player, p.Moves)
+	case SkipAction:
+		return g.Skip()
+	case NextAction:
+		return g.NextRound()
+	case games.JoinAction:
+		return g.AddPlayer(a.Player)
+	case games.LeaveAction:
+		return g.RemovePlayer(a.Player)
+	case games.WatchAction:
+		return g.AddSpectator(a.Player)
+	case games.UnwatchAction:
+		return g.RemoveSpectator(a.Player)
+	default:
+		return games.UnknownAction(a.Type)
+	}
+}
+
+// View returns the View, which is the same for every user
+func (g Generic) View(username string) interface{} {
+	return g.Game.View()
+}
+
+func (g Generic) Status() games.Status {
+	return games.Status{State: g.State.String(), Over: g.State == GameOver, Scores: g.Scores()}
+}
@@ -0,0 +1,67 @@
+package rrobots
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/games"
+)
+
+func TestGeneric(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game := testGame(t)
+	var gg games.Game = Generic{game}
+	g.Expect(gg.GameID()).To(Equal(game.ID))
+	g.Expect(gg.Status()).To(Equal(games.Status{
+		State:  "Bidding",
+		Scores: map[string]int{"Joe": 0, "Natasha": 0, "Maria": 0},
+	}))
+	g.Expect(gg.View("Joe")).To(Equal(game.View()))
+
+	now := time.Now()
+	g.Expect(gg.Apply(games.Action{Type: "tilt", Player: "Joe"}, now)).To(Equal(games.UnknownAction("tilt")))
+	g.Expect(gg.Apply(games.Action{Type: SkipAction, Player: "Joe"}, now)).To(Succeed())
+	g.Expect(gg.Status().State).To(Equal("RoundOver"))
+	g.Expect(gg.Apply(games.Action{Type: NextAction, Player: "Joe"}, now)).To(Succeed())
+	game.Robots = Robots{{0, 0}, {5, 5}, {9, 9}, {15, 15}}
+	game.Target = Target{Red, Circle, Cell{0, 15}}
+
+	g.Expect(gg.Apply(games.Action{Type: BidAction, Player: "Joe"}, now)).To(Equal(games.ActionError{Action: BidAction, Details: "missing payload"}))
+	g.Expect(gg.Apply(games.Action{Type: BidAction, Player: "Joe", Payload: json.RawMessage(`{"moves": 0}`)}, now)).To(Equal(
+		games.ActionError{Action: BidAction, Details: "invalid payload: " + InvalidArgError{"moves", "0 is less than 1"}.Error()}))
+	g.Expect(gg.Apply(games.Action{Type: BidAction, Player: "Joe", Payload: json.RawMessage(`{"moves": 1}`)}, now)).To(Succeed())
+	g.Expect(gg.Status().State).To(Equal("Countdown"))
+	g.Expect(gg.Apply(games.Action{Type: games.JoinAction, Player: "Ivan"}, now)).To(Succeed())
+	g.Expect(gg.Status().Scores).To(HaveKeyWithValue("Ivan", 0))
+	g.Expect(gg.Apply(games.Action{Type: games.LeaveAction, Player: "Ivan"}, now)).To(Succeed())
+	g.Expect(gg.Status().Scores).NotTo(HaveKey("Ivan"))
+	g.Expect(gg.Apply(games.Action{Type: games.WatchAction, Player: "Ivan"}, now)).To(Succeed())
+	g.Expect(game.Spectators).To(HaveKey("Ivan"))
+	g.Expect(gg.Apply(games.Action{Type: games.UnwatchAction, Player: "Ivan"}, now)).To(Succeed())
+	g.Expect(game.Spectators).To(BeEmpty())
+	g.Expect(gg.Apply(games.Action{Type: SkipAction, Player: "Joe"}, now)).To(Equal(InvalidStateError{"Skip", "round has bids"}))
+
+	// The countdown ends only once over
+	g.Expect(gg.Apply(games.Action{Type: EndCountdownAction}, now)).To(MatchError(InvalidStateError{"EndCountdown", "countdown ends at " + game.Deadline.Format(time.RFC3339)}))
+	g.Expect(gg.Status().State).To(Equal("Countdown"))
+
+	// Actions once the countdown is over end it first
+	now = game.Deadline
+	g.Expect(gg.Apply(games.Action{Type: DemonstrateAction, Player: "Joe", Payload: json.RawMessage(`{"moves": [{"color": 9, "direction": 0}]}`)}, now)).To(Equal(
+		games.ActionError{Action: DemonstrateAction, Details: "invalid payload: " + InvalidArgError{"moves", "move 1 is of color 9 direction 0"}.Error()}))
+	g.Expect(gg.Status().State).To(Equal("Demonstrating"))
+
+	// Winning the last target ends the game
+	game.Targets = nil
+	payload, err := json.Marshal(DemonstratePayload{[]Move{{Red, East}}})
+	g.Expect(err).To(BeNil())
+	g.Expect(gg.Apply(games.Action{Type: DemonstrateAction, Player: "Joe", Payload: payload}, now)).To(Succeed())
+	g.Expect(gg.Status()).To(Equal(games.Status{
+		State:  "GameOver",
+		Over:   true,
+		Scores: map[string]int{"Joe": 1, "Natasha": 0, "Maria": 0},
+	}))
+}
@@ -0,0 +1,146 @@
+package rrobots
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// NumQuadrants is the number of board quadrants, each of which fills a
+// quarter of the board
+const NumQuadrants = 4
+
+// quadrantTarget is a target of a quadrant, in the corner of walls on two
+// sides of its cell
+type quadrantTarget struct {
+	color  Color
+	shape  Shape
+	cell   Cell
+	corner [2]Direction
+}
+
+// quadrant is a quarter of the board as laid out in the north west corner:
+// its outer edges are north and west and the cell at row and column
+// Size/2-1 is part of the center. Laid out in the other corners it is
+// rotated clockwise, a quarter turn per corner.
+type quadrant struct {
+	targets []quadrantTarget
+	// northEdge is the column of the cell on the north edge with a wall
+	// on its east side, westEdge the row of the cell on the west edge with
+	// a wall on its south side
+	northEdge, westEdge int
+}
+
+// quadrants are the board quadrants. Each has a target of every robot
+// color, of a different shape in each, and the last has the vortex.
+var quadrants = [NumQuadrants]quadrant{
+	{
+		targets: []quadrantTarget{
+			{Red, Circle, Cell{1, 4}, [2]Direction{South, East}},
+			{Green, Triangle, Cell{3, 1}, [2]Direction{North, West}},
+			{Blue, Square, Cell{4, 6}, [2]Direction{South, West}},
+			{Yellow, Hexagon, Cell{6, 3}, [2]Direction{North, East}},
+		},
+		northEdge: 4,
+		westEdge:  3,
+	},
+	{
+		targets: []quadrantTarget{
+			{Red, Triangle, Cell{1, 2}, [2]Direction{South, West}},
+			{Green, Square, Cell{2, 5}, [2]Direction{North, East}},
+			{Blue, Hexagon, Cell{5, 1}, [2]Direction{South, East}},
+			{Yellow, Circle, Cell{6, 6}, [2]Direction{North, West}},
+		},
+		northEdge: 2,
+		westEdge:  5,
+	},
+	{
+		targets: []quadrantTarget{
+			{Red, Square, Cell{1, 5}, [2]Direction{North, West}},
+			{Green, Hexagon, Cell{3, 2}, [2]Direction{South, East}},
+			{Blue, Circle, Cell{4, 4}, [2]Direction{North, East}},
+			{Yellow, Triangle, Cell{6, 1}, [2]Direction{South, West}},
+		},
+		northEdge: 5,
+		westEdge:  2,
+	},
+	{
+		targets: []quadrantTarget{
+			{Red, Hexagon, Cell{2, 3}, [2]Direction{North, West}},
+			{Green, Circle, Cell{6, 2}, [2]Direction{North, East}},
+			{Blue, Triangle, Cell{3, 6}, [2]Direction{South, East}},
+			{Yellow, Square, Cell{5, 4}, [2]Direction{South, West}},
+			{Any, Vortex, Cell{1, 6}, [2]Direction{South, West}},
+		},
+		northEdge: 3,
+		westEdge:  4,
+	},
+}
+
+// place returns the cell of the board a quadrant cell is at when the
+// quadrant is in the given corner: 0 north west, 1 north east, 2 south east
+// and 3 south west
+func place(c Cell, corner int) Cell {
+	const n = Size / 2
+	for i := 0; i < corner; i++ {
+		c = Cell{c.Col, n - 1 - c.Row}
+	}
+	switch corner {
+	case 1:
+		c.Col += n
+	case 2:
+		c.Row += n
+		c.Col += n
+	case 3:
+		c.Row += n
+	}
+	return c
+}
+
+// Assemble returns the board of the quadrants with the given indexes laid
+// out in the north west, north east, south east and south west corners,
+// which must each be used once
+func Assemble(order [NumQuadrants]int) (*Board, error) {
+	used := make(map[int]bool)
+	for _, q := range order {
+		if q < 0 || q >= NumQuadrants || used[q] {
+			return nil, InvalidArgError{"quadrants", fmt.Sprint(order)}
+		}
+		used[q] = true
+	}
+	b := new(Board)
+	for i := 0; i < Size; i++ {
+		b.addWall(Cell{0, i}, North)
+		b.addWall(Cell{Size - 1, i}, South)
+		b.addWall(Cell{i, 0}, West)
+		b.addWall(Cell{i, Size - 1}, East)
+	}
+	for corner, q := range order {
+		quad := quadrants[q]
+		// The center cell is walled off on its sides facing the quadrant
+		center := place(Cell{Size/2 - 1, Size/2 - 1}, corner)
+		b.addWall(center, North.rotate(corner))
+		b.addWall(center, West.rotate(corner))
+		b.addWall(place(Cell{0, quad.northEdge}, corner), East.rotate(corner))
+		b.addWall(place(Cell{quad.westEdge, 0}, corner), South.rotate(corner))
+		for _, qt := range quad.targets {
+			c := place(qt.cell, corner)
+			for _, d := range qt.corner {
+				b.addWall(c, d.rotate(corner))
+			}
+			b.Targets = append(b.Targets, Target{qt.color, qt.shape, c})
+		}
+	}
+	return b, nil
+}
+
+// RandomBoard returns the board of the quadrants laid out in an order
+// chosen by the given random source
+func RandomBoard(rng *rand.Rand) *Board {
+	var order [NumQuadrants]int
+	copy(order[:], rng.Perm(NumQuadrants))
+	b, err := Assemble(order)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
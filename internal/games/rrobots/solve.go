@@ -0,0 +1,277 @@
+package rrobots
+
+// MaxSolveMoves is the most moves solvers search for a solution with by
+// default, beyond the longest solutions of positions in play
+const MaxSolveMoves = 20
+
+// unreachable is the heuristic of a cell robots cannot reach the target
+// from
+const unreachable = 255
+
+// Solver finds the fewest moves to reach a target of a board. It searches
+// states of the robots packed, a byte per robot, into a uint32 and, when
+// robots other than the target's are interchangeable, hashes each state to
+// the same key as those differing only by which of them is where.
+type Solver struct {
+	target Target
+	// stops are the cells robots moving from each cell in each direction
+	// stop at before a wall, ignoring other robots, indexed by cell index
+	stops [Size * Size][4]uint8
+	// h are lower bounds of the moves a robot on each cell needs to
+	// reach the target, since it could at best stop on any cell it passes
+	h [Size * Size]uint8
+}
+
+// index returns the index of the cell in a row-major array of cells
+func index(c Cell) uint8 {
+	return uint8(c.Row*Size + c.Col)
+}
+
+// cell returns the cell with the given index
+func cell(i uint8) Cell {
+	return Cell{int(i) / Size, int(i) % Size}
+}
+
+// NewSolver returns a solver for the given target of the board
+func NewSolver(b *Board, t Target) *Solver {
+	s := &Solver{target: t}
+	for i := range s.stops {
+		for d := North; d <= West; d++ {
+			c := cell(uint8(i))
+			for !b.Walls[c.Row][c.Col].Has(d) {
+				c = c.step(d)
+			}
+			s.stops[i][d] = index(c)
+		}
+	}
+	for i := range s.h {
+		s.h[i] = unreachable
+	}
+	s.h[index(t.Cell)] = 0
+	frontier := []Cell{t.Cell}
+	for k := uint8(1); len(frontier) > 0; k++ {
+		var next []Cell
+		for _, c := range frontier {
+			// Robots moving toward c along any line it is reached by
+			// unobstructed could stop there, given a blocker
+			for d := North; d <= West; d++ {
+				for x := c; !b.Walls[x.Row][x.Col].Has(d); {
+					x = x.step(d)
+					if s.h[index(x)] == unreachable {
+						s.h[index(x)] = k
+						next = append(next, x)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return s
+}
+
+// state is the cell indexes of the robots, the robot of Color i in byte i
+type state uint32
+
+func pack(robots Robots) state {
+	var s state
+	for i, r := range robots {
+		s |= state(index(r)) << (8 * i)
+	}
+	return s
+}
+
+func (s state) pos(i int) uint8 {
+	return uint8(s >> (8 * i))
+}
+
+func (s state) with(i int, p uint8) state {
+	return s&^(0xff<<(8*i)) | state(p)<<(8*i)
+}
+
+// key returns the hash of the state, shared with the states differing only
+// by which robots, of those that are interchangeable, are where
+func (s *Solver) key(st state) state {
+	var (
+		cells [NumRobots]uint8
+		n     int
+	)
+	for i := 0; i < NumRobots; i++ {
+		if Color(i) != s.target.Color {
+			cells[n] = st.pos(i)
+			n++
+		}
+	}
+	// Insertion sort of the few interchangeable robots
+	for i := 1; i < n; i++ {
+		for j := i; j > 0 && cells[j] < cells[j-1]; j-- {
+			cells[j], cells[j-1] = cells[j-1], cells[j]
+		}
+	}
+	var k state
+	for i := 0; i < n; i++ {
+		k = k<<8 | state(cells[i])
+	}
+	if s.target.Color != Any {
+		k = k<<8 | state(st.pos(int(s.target.Color)))
+	}
+	return k
+}
+
+// heuristic returns a lower bound of the moves to reach the target from
+// the state
+func (s *Solver) heuristic(st state) int {
+	if s.target.Color != Any {
+		return int(s.h[st.pos(int(s.target.Color))])
+	}
+	min := unreachable
+	for i := 0; i < NumRobots; i++ {
+		if h := int(s.h[st.pos(i)]); h < min {
+			min = h
+		}
+	}
+	return min
+}
+
+// reached returns whether the robot of Color i, having moved last,
+// reached the target in the state
+func (s *Solver) reached(st state, i int) bool {
+	return (s.target.Color == Any || s.target.Color == Color(i)) && st.pos(i) == index(s.target.Cell)
+}
+
+// slide returns the cell index the robot of Color i stops at moving in the
+// given direction: its stop before a wall, or before the nearest robot on
+// the way to it
+func (s *Solver) slide(st state, i int, d Direction) uint8 {
+	p := st.pos(i)
+	stop := s.stops[p][d]
+	if stop == p {
+		return p
+	}
+	pr, pc := int(p)/Size, int(p)%Size
+	dr, dc := d.delta()
+	n := (int(stop)/Size-pr)*dr + (int(stop)%Size-pc)*dc
+	for j := 0; j < NumRobots; j++ {
+		if j == i {
+			continue
+		}
+		q := st.pos(j)
+		qr, qc := int(q)/Size, int(q)%Size
+		k := 0
+		if dr == 0 && qr == pr {
+			k = (qc - pc) * dc
+		} else if dc == 0 && qc == pc {
+			k = (qr - pr) * dr
+		}
+		if k > 0 && k <= n {
+			n = k - 1
+		}
+	}
+	return uint8((pr+dr*n)*Size + pc + dc*n)
+}
+
+// order returns the robots in the order to try moving them: the target's
+// first, as it is the likeliest to reach it
+func (s *Solver) order() [NumRobots]int {
+	var o [NumRobots]int
+	n := 0
+	if s.target.Color != Any {
+		o[0] = int(s.target.Color)
+		n++
+	}
+	for i := 0; i < NumRobots; i++ {
+		if Color(i) != s.target.Color {
+			o[n] = i
+			n++
+		}
+	}
+	return o
+}
+
+// Solve returns the fewest moves from the given robots that reach the
+// target (see Reached), or nil if it takes more than maxMoves. It searches
+// by iterative deepening A*, remembering the most moves left each state was
+// searched with to prune repeated searches of it.
+func (s *Solver) Solve(robots Robots, maxMoves int) []Move {
+	order := s.order()
+	seen := make(map[state]int)
+	path := make([]Move, 0, maxMoves)
+	var search func(st state, left int) bool
+	search = func(st state, left int) bool {
+		k := s.key(st)
+		if l, ok := seen[k]; ok && l >= left {
+			return false
+		}
+		seen[k] = left
+		for _, i := range order {
+			for d := North; d <= West; d++ {
+				p := s.slide(st, i, d)
+				if p == st.pos(i) {
+					continue
+				}
+				next := st.with(i, p)
+				path = append(path, Move{Color(i), d})
+				if s.reached(next, i) || left > 1 && s.heuristic(next) < left && search(next, left-1) {
+					return true
+				}
+				path = path[:len(path)-1]
+			}
+		}
+		return false
+	}
+	start := pack(robots)
+	for limit := 1; limit <= maxMoves; limit++ {
+		if s.heuristic(start) <= limit && search(start, limit) {
+			return path
+		}
+	}
+	return nil
+}
+
+// SolveBFS returns the same number of moves as Solve, searching breadth
+// first. It uses more memory than Solve, but searches each state once.
+func (s *Solver) SolveBFS(robots Robots, maxMoves int) []Move {
+	type node struct {
+		st     state
+		parent int
+		move   Move
+	}
+	start := pack(robots)
+	order := s.order()
+	nodes := []node{{start, -1, Move{}}}
+	seen := map[state]bool{s.key(start): true}
+	// Each pass expands the nodes of a depth, nodes[lo:hi]
+	for depth, lo := 0, 0; depth < maxMoves && lo < len(nodes); depth++ {
+		hi := len(nodes)
+		for at := lo; at < hi; at++ {
+			st := nodes[at].st
+			for _, i := range order {
+				for d := North; d <= West; d++ {
+					p := s.slide(st, i, d)
+					if p == st.pos(i) {
+						continue
+					}
+					next := st.with(i, p)
+					m := Move{Color(i), d}
+					if s.reached(next, i) {
+						moves := []Move{m}
+						for j := at; nodes[j].parent >= 0; j = nodes[j].parent {
+							moves = append(moves, nodes[j].move)
+						}
+						for l, r := 0, len(moves)-1; l < r; l, r = l+1, r-1 {
+							moves[l], moves[r] = moves[r], moves[l]
+						}
+						return moves
+					}
+					k := s.key(next)
+					if seen[k] || depth+1+s.heuristic(next) > maxMoves {
+						continue
+					}
+					seen[k] = true
+					nodes = append(nodes, node{next, at, m})
+				}
+			}
+		}
+		lo = hi
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package rrobots
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// solvers are the search methods of a Solver, keyed on name
+var solvers = map[string]func(*Solver, Robots, int) []Move{
+	"IDA*": (*Solver).Solve,
+	"BFS":  (*Solver).SolveBFS,
+}
+
+// solves returns whether the moves from the robots reach the target
+func solves(b *Board, robots Robots, t Target, moves []Move) bool {
+	after, err := b.Apply(robots, moves)
+	return err == nil && len(moves) > 0 && Reached(after, moves[len(moves)-1].Color, t)
+}
+
+func TestSolve(t *testing.T) {
+	g := NewGomegaWithT(t)
+	b := emptyBoard()
+	robots := Robots{{0, 0}, {5, 8}, {9, 9}, {15, 15}}
+	for name, solve := range solvers {
+		s := NewSolver(b, Target{Red, Circle, Cell{0, 15}})
+		g.Expect(solve(s, robots, MaxSolveMoves)).To(Equal([]Move{{Red, East}}), name)
+
+		// Stopping mid row takes a blocker
+		s = NewSolver(b, Target{Red, Circle, Cell{0, 7}})
+		g.Expect(solve(s, robots, MaxSolveMoves)).To(Equal([]Move{{Green, North}, {Red, East}}), name)
+		g.Expect(solve(s, robots, 1)).To(BeNil(), name)
+
+		// Any robot may reach the vortex
+		s = NewSolver(b, Target{Any, Vortex, Cell{15, 9}})
+		g.Expect(solve(s, robots, MaxSolveMoves)).To(Equal([]Move{{Blue, South}}), name)
+
+		// A robot already on the target must leave and come back
+		s = NewSolver(b, Target{Red, Circle, Cell{0, 0}})
+		moves := solve(s, robots, MaxSolveMoves)
+		g.Expect(moves).To(HaveLen(2), name)
+		g.Expect(solves(b, robots, s.target, moves)).To(BeTrue(), name)
+	}
+}
+
+func TestSolveGames(t *testing.T) {
+	g := NewGomegaWithT(t)
+	for seed := int64(1); seed <= 20; seed++ {
+		game, err := NewGameOptions(Options{Seed: seed})
+		g.Expect(err).To(BeNil())
+		s := NewSolver(game.Board, game.Target)
+		moves := s.Solve(game.Robots, MaxSolveMoves)
+		g.Expect(moves).NotTo(BeNil(), "seed %d", seed)
+		g.Expect(solves(game.Board, game.Robots, game.Target, moves)).To(BeTrue(), "seed %d", seed)
+
+		// Both searches find the fewest moves
+		bfs := s.SolveBFS(game.Robots, MaxSolveMoves)
+		g.Expect(bfs).To(HaveLen(len(moves)), "seed %d", seed)
+		g.Expect(solves(game.Board, game.Robots, game.Target, bfs)).To(BeTrue(), "seed %d", seed)
+		g.Expect(s.SolveBFS(game.Robots, len(moves)-1)).To(BeNil(), "seed %d", seed)
+	}
+}
+
+// benchmarkSolve solves the first target of games with a range of seeds
+func benchmarkSolve(b *testing.B, solve func(*Solver, Robots, int) []Move) {
+	var games []*Game
+	for seed := int64(1); seed <= 10; seed++ {
+		game, err := NewGameOptions(Options{Seed: seed})
+		if err != nil {
+			b.Fatal(err)
+		}
+		games = append(games, game)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		game := games[i%len(games)]
+		if solve(NewSolver(game.Board, game.Target), game.Robots, MaxSolveMoves) == nil {
+			b.Fatalf("no solution for seed %d", game.Seed)
+		}
+	}
+}
+
+func BenchmarkSolve(b *testing.B) {
+	benchmarkSolve(b, (*Solver).Solve)
+}
+
+func BenchmarkSolveBFS(b *testing.B) {
+	benchmarkSolve(b, (*Solver).SolveBFS)
+}
+
+func BenchmarkNewSolver(b *testing.B) {
+	game, err := NewGameOptions(Options{Seed: 1})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		NewSolver(game.Board, game.Target)
+	}
+}
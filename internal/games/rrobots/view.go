@@ -0,0 +1,51 @@
+package rrobots
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// View is the projection of a Game presented to players and spectators. It
+// omits the Targets to come, and the Seed they were shuffled with, giving
+// their number instead.
+type View struct {
+	ID           uuid.UUID          `json:"id"`
+	Players      map[string]*Player `json:"players"`
+	Spectators   map[string]bool    `json:"spectators"`
+	Board        *Board             `json:"board"`
+	Robots       Robots             `json:"robots"`
+	Target       Target             `json:"target"`
+	TargetsLeft  int                `json:"targetsLeft"`
+	Round        int                `json:"round"`
+	State        State              `json:"state"`
+	Bids         []*Bid             `json:"bids"`
+	Deadline     time.Time          `json:"deadline"`
+	Demonstrator string             `json:"demonstrator,omitempty"`
+	Failed       []string           `json:"failed,omitempty"`
+	Winner       string             `json:"winner,omitempty"`
+	Solution     []Move             `json:"solution,omitempty"`
+	LastActivity time.Time          `json:"lastActivity"`
+}
+
+// View returns the public projection of the game
+func (g *Game) View() *View {
+	return &View{
+		ID:           g.ID,
+		Players:      g.Players,
+		Spectators:   g.Spectators,
+		Board:        g.Board,
+		Robots:       g.Robots,
+		Target:       g.Target,
+		TargetsLeft:  len(g.Targets),
+		Round:        g.Round,
+		State:        g.State,
+		Bids:         g.Bids,
+		Deadline:     g.Deadline,
+		Demonstrator: g.Demonstrator,
+		Failed:       g.Failed,
+		Winner:       g.Winner,
+		Solution:     g.Solution,
+		LastActivity: g.LastActivity,
+	}
+}
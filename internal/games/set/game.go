@@ -7,8 +7,11 @@ import (
 	"math/rand"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/validate"
 )
 
 const (
@@ -16,6 +19,7 @@ const (
 	NAxes        = 4
 	FullDeckLen  = 81
 	InitBoardLen = 12
+	MaxCount     = 3
 )
 
 type Shading byte
@@ -42,6 +46,11 @@ func abbrToShading(a string) Shading {
 
 //go:generate stringer -type=Shading
 
+// Valid returns true if s is one of the defined Shadings
+func (s Shading) Valid() bool {
+	return s <= Stripe
+}
+
 type Shape byte
 
 const (
@@ -66,6 +75,11 @@ func abbrToShape(a string) Shape {
 
 //go:generate stringer -type=Shape
 
+// Valid returns true if s is one of the defined Shapes
+func (s Shape) Valid() bool {
+	return s <= Squiggle
+}
+
 type Color byte
 
 const (
@@ -90,6 +104,11 @@ func abbrToColor(a string) Color {
 
 //go:generate stringer -type=Color
 
+// Valid returns true if c is one of the defined Colors
+func (c Color) Valid() bool {
+	return c <= Red
+}
+
 type State byte
 
 const (
@@ -107,35 +126,46 @@ type Card struct {
 	Shape   Shape   `json:"shape"`
 }
 
+// UnmarshalJSON decodes a Card from its JSON string form, accepting either
+// the abbreviated ("G2SO") or long ("green 2 striped ovals") syntax. See
+// ParseCard.
 func (c *Card) UnmarshalJSON(b []byte) error {
 	var s string
 	if err := json.Unmarshal(b, &s); err != nil {
 		return err
 	}
-	if len(s) != 4 {
-		return fmt.Errorf("card string must have len 4: %s", s)
+	card, err := ParseCard(s)
+	if err != nil {
+		return err
 	}
-	color := abbrToColor(s[0:1])
-	if color == math.MaxUint8 {
-		return fmt.Errorf("invalid color abbreviation: %c", s[0])
+	*c = card
+	return nil
+}
+
+// Validate returns a CardAttrError for the first attribute of c that is
+// out of range, or nil if c is a card in the deck
+func (c Card) Validate() error {
+	if !c.Color.Valid() {
+		return CardAttrError{"color", c.Color.String()}
 	}
-	count, err := strconv.Atoi(s[1:2])
-	if err != nil {
-		return fmt.Errorf("invalid count: %c", s[1])
+	if c.Count < 1 || c.Count > MaxCount {
+		return CardAttrError{"count", strconv.Itoa(int(c.Count))}
 	}
-	shading := abbrToShading(s[2:3])
-	if shading == math.MaxUint8 {
-		return fmt.Errorf("invalid shading abbreviation: %c", s[2])
+	if !c.Shading.Valid() {
+		return CardAttrError{"shading", c.Shading.String()}
 	}
-	shape := abbrToShape(s[3:])
-	if color == math.MaxUint8 {
-		return fmt.Errorf("invalid color abbreviation: %c", s[3])
+	if !c.Shape.Valid() {
+		return CardAttrError{"shape", c.Shape.String()}
 	}
-	*c = Card{color, byte(count), shading, shape}
 	return nil
 }
 
+// MarshalJSON encodes a Card as its abbreviated string form. Cards that
+// fail Validate are rejected so they are never persisted.
 func (c Card) MarshalJSON() ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
 	s := c.Color.String()[0:1] + strconv.Itoa(int(c.Count)) + c.Shading.String()[0:1] + c.Shape.String()[0:1]
 	return json.Marshal(s)
 }
@@ -168,6 +198,56 @@ func CardToCardBase3(c *Card) CardBase3 {
 // CardTriple set of three cards that are a Potential Set
 type CardTriple [SetLen]Card
 
+// MarshalJSON encodes a CardTriple as a JSON array of its cards, or an
+// empty array for the zero CardTriple, which is the ClaimedSet of a round
+// not yet claimed
+func (cs CardTriple) MarshalJSON() ([]byte, error) {
+	if cs == (CardTriple{}) {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([SetLen]Card(cs))
+}
+
+// UnmarshalJSON decodes a CardTriple from a JSON array of exactly SetLen
+// cards, or the zero CardTriple from an empty array. The default array
+// decoding silently drops extra elements and zero-fills missing ones.
+func (cs *CardTriple) UnmarshalJSON(b []byte) error {
+	var cards []Card
+	if err := json.Unmarshal(b, &cards); err != nil {
+		return err
+	}
+	if len(cards) == 0 {
+		*cs = CardTriple{}
+		return nil
+	}
+	if len(cards) != SetLen {
+		return CardSyntaxError{string(b), "card triple must have 3 cards"}
+	}
+	copy(cs[:], cards)
+	return nil
+}
+
+// Validate returns an error if the given cards could not have come from a
+// single deck: any card with an out-of-range attribute or a card repeated
+// within the triple.
+func (cs CardTriple) Validate() error {
+	for _, c := range cs {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	if cs[0] == cs[1] || cs[1] == cs[2] || cs[0] == cs[2] {
+		return CardSyntaxError{cs.String(), "card triple has duplicate cards"}
+	}
+	return nil
+}
+
+// String returns the abbreviated forms of the cards separated by spaces,
+// the syntax accepted by ParseCardTriple
+func (cs CardTriple) String() string {
+	return cs[0].String() + " " + cs[1].String() + " " + cs[2].String()
+}
+
 // IsSet returns true if the given cards are a set, false otherwise
 func IsSet(cs CardTriple) bool {
 	if cs[0] == cs[1] || cs[1] == cs[2] || cs[0] == cs[2] {
@@ -264,6 +344,13 @@ type Game struct {
 	Board           Board              `json:"board"`
 	ClaimedSet      CardTriple         `json:"claimedSet"`
 	ClaimedUsername string             `json:"claimedUsername"`
+	// Spectators are users watching the game who may not claim sets
+	Spectators map[string]bool `json:"spectators"`
+	// Seed is the seed of the random source that shuffled the Deck as the
+	// game was created, so the game replays from its log of actions
+	Seed int64 `json:"seed"`
+	// LastActivity is the time the game was last saved
+	LastActivity time.Time `json:"lastActivity"`
 	// TODO(bbawn): do we need a logical timestamp field to detect stale operations?
 }
 
@@ -288,24 +375,38 @@ func (e InvalidStateError) Error() string {
 	return fmt.Sprintf("Invalid method: %s detail: %s", e.Method, e.Details)
 }
 
+// Options are the settings of a new game
+type Options struct {
+	// Seed is the seed of the random source that shuffles the Deck, random
+	// if 0
+	Seed int64 `json:"seed"`
+}
+
+// NewGame returns a game with the given players and a shuffled Deck
 func NewGame(usernames ...string) (*Game, error) {
+	return NewGameOptions(Options{}, usernames...)
+}
+
+// NewGameOptions returns a game with the given options and players
+func NewGameOptions(opts Options, usernames ...string) (*Game, error) {
 	g := new(Game)
 	g.ID = uuid.New()
 	g.Players = make(map[string]*Player)
+	g.Spectators = make(map[string]bool)
 	for _, u := range usernames {
-		if u == "" {
-			return nil, InvalidArgError{"username", "empty"}
-		}
-		if _, present := g.Players[u]; present {
-			return nil, InvalidArgError{"username", u + " already present"}
+		if err := g.AddPlayer(u); err != nil {
+			return nil, err
 		}
-		g.Players[u] = &Player{Username: u, Sets: []CardTriple{}}
 	}
 	g.Deck = make([]*Card, FullDeckLen)
 	for i := range g.Deck {
 		g.Deck[i] = CardBase3ToCard(CardBase3(i))
 	}
-	rand.Shuffle(len(g.Deck), func(i, j int) {
+	g.Seed = opts.Seed
+	if g.Seed == 0 {
+		g.Seed = rand.Int63()
+	}
+	rand.New(rand.NewSource(g.Seed)).Shuffle(len(g.Deck), func(i, j int) {
 		g.Deck[i], g.Deck[j] = g.Deck[j], g.Deck[i]
 	})
 	// Deal cards from deck to board
@@ -316,6 +417,115 @@ func NewGame(usernames ...string) (*Game, error) {
 	return g, nil
 }
 
+// LeavePolicy determines what happens to the claimed sets of a player
+// removed from a game
+type LeavePolicy string
+
+const (
+	// DiscardSets removes the player's sets from play
+	DiscardSets LeavePolicy = "discard"
+	// ReturnSets shuffles the player's sets back into the Deck
+	ReturnSets LeavePolicy = "return"
+)
+
+// AddPlayer adds a player with no sets to the game, which may be in
+// progress. The username must be valid per validate.Username, in whose
+// normal form it is stored, and no player or other spectator may have the
+// same username but for case. A spectator who joins becomes a player and is
+// no longer a spectator.
+func (g *Game) AddPlayer(username string) error {
+	username, err := validate.Username(username)
+	if err != nil {
+		return err
+	}
+	if _, present := g.Players[username]; present {
+		return InvalidArgError{"username", username + " already present"}
+	}
+	if other := g.user(username); other != "" && !g.Spectators[username] {
+		return InvalidArgError{"username", username + " already present as " + other}
+	}
+	delete(g.Spectators, username)
+	g.Players[username] = &Player{Username: username, Sets: []CardTriple{}}
+	return nil
+}
+
+// RemovePlayer removes a player from the game, disposing of their claimed
+// sets per the given policy (DiscardSets if empty). Sets returned to the
+// Deck are shuffled into it with the given seed. If the player claimed the
+// current round, the claim stands until NextRound.
+func (g *Game) RemovePlayer(username string, policy LeavePolicy, seed int64) error {
+	username = validate.Normal(username)
+	p, present := g.Players[username]
+	if !present {
+		return InvalidArgError{"username", username}
+	}
+	switch policy {
+	case "", DiscardSets:
+	case ReturnSets:
+		for i := range p.Sets {
+			g.Deck = append(g.Deck, &p.Sets[i][0], &p.Sets[i][1], &p.Sets[i][2])
+		}
+		rand.New(rand.NewSource(seed)).Shuffle(len(g.Deck), func(i, j int) {
+			g.Deck[i], g.Deck[j] = g.Deck[j], g.Deck[i]
+		})
+	default:
+		return InvalidArgError{"policy", string(policy)}
+	}
+	delete(g.Players, username)
+	return nil
+}
+
+// AddSpectator adds a user who receives game updates but may not claim
+// sets. The username must be valid per validate.Username and, regardless
+// of case, not already a player or spectator.
+func (g *Game) AddSpectator(username string) error {
+	username, err := validate.Username(username)
+	if err != nil {
+		return err
+	}
+	if _, present := g.Players[username]; present {
+		return InvalidArgError{"username", username + " is a player"}
+	}
+	if g.Spectators[username] {
+		return InvalidArgError{"username", username + " already present"}
+	}
+	if other := g.user(username); other != "" {
+		return InvalidArgError{"username", username + " already present as " + other}
+	}
+	if g.Spectators == nil {
+		g.Spectators = make(map[string]bool)
+	}
+	g.Spectators[username] = true
+	return nil
+}
+
+// user returns the player or spectator with the same username as the
+// given one but for case, or "" if there is none
+func (g *Game) user(username string) string {
+	key := validate.Key(username)
+	for u := range g.Players {
+		if validate.Key(u) == key {
+			return u
+		}
+	}
+	for u := range g.Spectators {
+		if validate.Key(u) == key {
+			return u
+		}
+	}
+	return ""
+}
+
+// RemoveSpectator removes the given spectator from the game
+func (g *Game) RemoveSpectator(username string) error {
+	username = validate.Normal(username)
+	if !g.Spectators[username] {
+		return InvalidArgError{"username", username + " is not a spectator"}
+	}
+	delete(g.Spectators, username)
+	return nil
+}
+
 // ExpandBoard adds a new set-length column to the Game's board
 // from the Game's deck. Returns true if there were enough cards
 // in the deck, otherwise false.
@@ -337,7 +547,11 @@ func (g *Game) expandBoard() bool {
 // If the given username is not a player in the Game, an
 // InvalidArgError(Arg="username") is returned.
 //
-// If the given cards are not a set or not present in the deck, nil is
+// If the given cards are malformed (see CardTriple.Validate), an
+// InvalidArgError(Arg="cards") is returned and the player is not penalized:
+// such a claim can only come from a buggy client.
+//
+// If the given cards are not a set or not present on the board, nil is
 // returned and (per game rules) the most recent set in the player's collection
 // is returned to the Deck.
 // NOTE: we need to add Game.logicalTime to avoid race where set was valid for
@@ -348,13 +562,20 @@ func (g *Game) expandBoard() bool {
 // prior to the next round) and is added to the given player's collection and
 // nil is returned.
 func (g *Game) ClaimSet(username string, cs CardTriple) error {
+	username = validate.Normal(username)
 	if g.GetState() != Playing {
 		return InvalidStateError{"ClaimSet", "round already claimed by " + g.ClaimedUsername}
 	}
+	if g.Spectators[username] {
+		return InvalidArgError{"username", username + " is a spectator"}
+	}
 	p, present := g.Players[username]
 	if !present {
 		return InvalidArgError{"username", username}
 	}
+	if err := cs.Validate(); err != nil {
+		return InvalidArgError{"cards", err.Error()}
+	}
 	if !IsSet(cs) {
 		g.penalty(p)
 		// Illegal move, but not an error (we must update datastore)
@@ -415,6 +636,16 @@ func (g *Game) NextRound() error {
 	return nil
 }
 
+// Scores returns the number of sets claimed by each player, keyed on
+// username
+func (g *Game) Scores() map[string]int {
+	scores := make(map[string]int)
+	for u, p := range g.Players {
+		scores[u] = len(p.Sets)
+	}
+	return scores
+}
+
 func (g *Game) GetState() State {
 	if g.ClaimedUsername == "" {
 		return Playing
@@ -1,9 +1,12 @@
 package set
 
 import (
+	"encoding/json"
 	. "github.com/onsi/gomega"
 	"math/rand"
 	"testing"
+
+	"github.com/bbawn/boredgames/internal/validate"
 )
 
 const (
@@ -184,6 +187,137 @@ func TestGameSequence(t *testing.T) {
 	g.Expect(len(game.Board)).To(Equal(oldLen - SetLen))
 }
 
+func TestClaimSetMalformed(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(Succeed())
+
+	// Give Joe a set to lose if he were (wrongly) penalized
+	s := game.FindExpandSet()
+	g.Expect(game.ClaimSet("Joe", *s)).To(Succeed())
+	g.Expect(game.NextRound()).To(Succeed())
+	g.Expect(game.Players["Joe"].Sets).To(HaveLen(1))
+	deckLen := len(game.Deck)
+
+	// Duplicate cards
+	c := *game.Board[0]
+	dup := CardTriple{c, c, *game.Board[1]}
+	err = game.ClaimSet("Joe", dup)
+	g.Expect(err).To(MatchError(InvalidArgError{"cards", "card triple has duplicate cards: " + dup.String()}))
+
+	// Card with invalid attributes
+	bad := CardTriple{*game.Board[0], *game.Board[1], {Red, 7, Filled, Diamond}}
+	err = game.ClaimSet("Joe", bad)
+	g.Expect(err).To(MatchError(InvalidArgError{"cards", "invalid card count: 7"}))
+
+	// Zero card, as left by a short JSON array
+	err = game.ClaimSet("Joe", CardTriple{*game.Board[0], *game.Board[1]})
+	g.Expect(err).To(MatchError(InvalidArgError{"cards", "invalid card count: 0"}))
+
+	g.Expect(game.GetState()).To(Equal(Playing))
+	g.Expect(game.Players["Joe"].Sets).To(HaveLen(1))
+	g.Expect(game.Deck).To(HaveLen(deckLen))
+
+	// A genuine wrong guess is still penalized
+	s = game.Board.FindSet(false)
+	g.Expect(game.ClaimSet("Joe", *s)).To(Succeed())
+	g.Expect(game.Players["Joe"].Sets).To(BeEmpty())
+	g.Expect(game.Deck).To(HaveLen(deckLen + SetLen))
+}
+
+func TestSpectators(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(Succeed())
+	g.Expect(game.Spectators).To(BeEmpty())
+
+	g.Expect(game.AddSpectator("Jane")).To(Succeed())
+	g.Expect(game.Spectators).To(HaveKey("Jane"))
+	g.Expect(game.AddSpectator("Jane")).To(MatchError(InvalidArgError{"username", "Jane already present"}))
+	g.Expect(game.AddSpectator("Joe")).To(MatchError(InvalidArgError{"username", "Joe is a player"}))
+	g.Expect(game.AddSpectator("")).To(MatchError(validate.Error{Field: "username", Value: "", Reason: "empty"}))
+	g.Expect(game.AddSpectator("joe")).To(MatchError(InvalidArgError{"username", "joe already present as Joe"}))
+
+	// Spectators may not claim sets
+	s := game.FindExpandSet()
+	err = game.ClaimSet("Jane", *s)
+	g.Expect(err).To(MatchError(InvalidArgError{"username", "Jane is a spectator"}))
+	g.Expect(game.GetState()).To(Equal(Playing))
+
+	g.Expect(game.RemoveSpectator("Jane")).To(Succeed())
+	g.Expect(game.Spectators).To(BeEmpty())
+	g.Expect(game.RemoveSpectator("Jane")).To(MatchError(InvalidArgError{"username", "Jane is not a spectator"}))
+}
+
+func TestJoinLeave(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame("Joe", "Maria")
+	g.Expect(err).To(Succeed())
+
+	// Join mid-play, including a spectator becoming a player
+	g.Expect(game.AddSpectator("Frank")).To(Succeed())
+	g.Expect(game.AddPlayer("Frank")).To(Succeed())
+	g.Expect(game.Spectators).To(BeEmpty())
+	g.Expect(game.Players["Frank"].Sets).To(BeEmpty())
+	g.Expect(game.AddPlayer("Frank")).To(MatchError(InvalidArgError{"username", "Frank already present"}))
+	g.Expect(game.AddPlayer("")).To(MatchError(validate.Error{Field: "username", Value: "", Reason: "empty"}))
+	g.Expect(game.AddPlayer("FRANK")).To(MatchError(InvalidArgError{"username", "FRANK already present as Frank"}))
+	g.Expect(game.AddPlayer("a/b")).To(BeAssignableToTypeOf(validate.Error{}))
+
+	// Frank and Maria each claim a set
+	for _, u := range []string{"Frank", "Maria"} {
+		s := game.FindExpandSet()
+		g.Expect(game.ClaimSet(u, *s)).To(Succeed())
+		g.Expect(game.NextRound()).To(Succeed())
+	}
+	deckLen := len(game.Deck)
+
+	g.Expect(game.RemovePlayer("Frank", "keep", 1)).To(MatchError(InvalidArgError{"policy", "keep"}))
+	g.Expect(game.Players).To(HaveKey("Frank"))
+
+	// Returned sets go back in the deck, shuffled alike by the same seed
+	b, err := json.Marshal(game)
+	g.Expect(err).To(BeNil())
+	var twin *Game
+	g.Expect(json.Unmarshal(b, &twin)).To(Succeed())
+	g.Expect(game.RemovePlayer("Frank", ReturnSets, 42)).To(Succeed())
+	g.Expect(game.Players).NotTo(HaveKey("Frank"))
+	g.Expect(game.Deck).To(HaveLen(deckLen + SetLen))
+	g.Expect(twin.RemovePlayer("Frank", ReturnSets, 42)).To(Succeed())
+	g.Expect(twin.Deck).To(Equal(game.Deck))
+
+	// Discarded sets are out of play
+	g.Expect(game.RemovePlayer("Maria", DiscardSets, 1)).To(Succeed())
+	g.Expect(game.Players).NotTo(HaveKey("Maria"))
+	g.Expect(game.Deck).To(HaveLen(deckLen + SetLen))
+
+	g.Expect(game.RemovePlayer("Maria", "", 1)).To(MatchError(InvalidArgError{"username", "Maria"}))
+}
+
+func TestCardTripleUnmarshal(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var ct CardTriple
+	g.Expect(json.Unmarshal([]byte(`["G1FD", "R2SO", "P3OS"]`), &ct)).To(Succeed())
+	g.Expect(ct).To(Equal(CardTriple{{Green, 1, Filled, Diamond}, {Red, 2, Stripe, Oval}, {Purple, 3, Outline, Squiggle}}))
+	g.Expect(json.Unmarshal([]byte(`["G1FD", "R2SO"]`), &ct)).To(MatchError(CardSyntaxError{`["G1FD", "R2SO"]`, "card triple must have 3 cards"}))
+	g.Expect(json.Unmarshal([]byte(`["G1FD", "R2SO", "P3OS", "P3OD"]`), &ct)).To(HaveOccurred())
+}
+
+func TestCardTripleMarshal(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ct := CardTriple{{Green, 1, Filled, Diamond}, {Red, 2, Stripe, Oval}, {Purple, 3, Outline, Squiggle}}
+	b, err := json.Marshal(ct)
+	g.Expect(err).To(BeNil())
+	g.Expect(b).To(MatchJSON(`["G1FD", "R2SO", "P3OS"]`))
+
+	// The ClaimedSet of an unclaimed round is an empty array
+	b, err = json.Marshal(CardTriple{})
+	g.Expect(err).To(BeNil())
+	g.Expect(b).To(MatchJSON(`[]`))
+	g.Expect(json.Unmarshal(b, &ct)).To(Succeed())
+	g.Expect(ct).To(Equal(CardTriple{}))
+}
+
 func TestGamesLoop(t *testing.T) {
 	g := NewGomegaWithT(t)
 	for i := 0; i < nTestGames; i++ {
@@ -218,3 +352,41 @@ func TestGamesLoop(t *testing.T) {
 		g.Expect(game.Deck).To(BeEmpty())
 	}
 }
+
+func TestNewGameSeed(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Seed).NotTo(BeZero())
+
+	// The seed determines the deck and board
+	other, err := NewGameOptions(Options{Seed: game.Seed})
+	g.Expect(err).To(BeNil())
+	g.Expect(other.Seed).To(Equal(game.Seed))
+	g.Expect(other.Deck).To(Equal(game.Deck))
+	g.Expect(other.Board).To(Equal(game.Board))
+}
+
+func TestNewGameUsernames(t *testing.T) {
+	g := NewGomegaWithT(t)
+	_, err := NewGame("Joe", "joe")
+	g.Expect(err).To(MatchError(InvalidArgError{"username", "joe already present as Joe"}))
+	_, err = NewGame("Joe", "")
+	g.Expect(err).To(MatchError(validate.Error{Field: "username", Value: "", Reason: "empty"}))
+
+	// Usernames are stored normalized
+	game, err := NewGame("Jose\u0301")
+	g.Expect(err).To(Succeed())
+	g.Expect(game.Players).To(HaveKey("Jos\u00e9"))
+
+	// and found when given in another form
+	cs := game.FindExpandSet()
+	g.Expect(cs).NotTo(BeNil())
+	g.Expect(game.ClaimSet("Jose\u0301", *cs)).To(Succeed())
+	g.Expect(game.ClaimedUsername).To(Equal("Jos\u00e9"))
+	g.Expect(game.AddSpectator("Rene\u0301")).To(Succeed())
+	g.Expect(game.RemoveSpectator("Rene\u0301")).To(Succeed())
+	g.Expect(game.Spectators).To(BeEmpty())
+	g.Expect(game.RemovePlayer("Jose\u0301", DiscardSets, 1)).To(Succeed())
+	g.Expect(game.Players).To(BeEmpty())
+}
@@ -0,0 +1,92 @@
+package set
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/games"
+)
+
+// Actions of the game in the generic game API
+const (
+	// ClaimAction claims a set, with a ClaimPayload
+	ClaimAction = "claim"
+	// ExpandAction expands the board when no one can find a set
+	ExpandAction = "expand"
+	// NextAction starts the next round after a set is claimed
+	NextAction = "next"
+)
+
+// ClaimPayload is the payload of ClaimAction
+type ClaimPayload struct {
+	Cards CardTriple `json:"cards"`
+}
+
+// Validate returns an error if the cards could not have come from a deck
+func (p ClaimPayload) Validate() error {
+	return p.Cards.Validate()
+}
+
+// LeavePayload is the optional payload of games.LeaveAction
+type LeavePayload struct {
+	// Sets is the policy for the departing player's claimed sets,
+	// DiscardSets if empty
+	Sets LeavePolicy `json:"sets"`
+}
+
+// Generic adapts a Game to the games.Game interface
+type Generic struct {
+	*Game
+}
+
+func (g Generic) GameID() uuid.UUID {
+	return g.ID
+}
+
+func (g Generic) Apply(a games.Action, now time.Time) error {
+	switch a.Type {
+	case ClaimAction:
+		var p ClaimPayload
+		if err := games.DecodePayload(a, &p); err != nil {
+			return err
+		}
+		return g.ClaimSet(a.Player, p.Cards)
+	case ExpandAction:
+		return g.Expand()
+	case NextAction:
+		return g.NextRound()
+	case games.JoinAction:
+		return g.AddPlayer(a.Player)
+	case games.LeaveAction:
+		var p LeavePayload
+		if len(a.Payload) > 0 {
+			if err := games.DecodePayload(a, &p); err != nil {
+				return err
+			}
+		}
+		return g.RemovePlayer(a.Player, p.Sets, a.Seed)
+	case games.WatchAction:
+		return g.AddSpectator(a.Player)
+	case games.UnwatchAction:
+		return g.RemoveSpectator(a.Player)
+	default:
+		return games.UnknownAction(a.Type)
+	}
+}
+
+// View returns the public View, which is the same for every user
+func (g Generic) View(username string) interface{} {
+	return g.Game.View()
+}
+
+// Status reports the game over once the deck is empty and no set is left
+// on the board
+func (g Generic) Status() games.Status {
+	state := g.GetState()
+	return games.Status{
+		State:  state.String(),
+		Over:   state == Playing && len(g.Deck) == 0 && g.Board.FindSet(true) == nil,
+		Scores: g.Scores(),
+	}
+}
@@ -0,0 +1,72 @@
+package set
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/games"
+)
+
+func TestGeneric(t *testing.T) {
+	g := NewGomegaWithT(t)
+	game, err := NewGame(getUsernames()...)
+	g.Expect(err).To(BeNil())
+	var gg games.Game = Generic{game}
+	g.Expect(gg.GameID()).To(Equal(game.ID))
+	g.Expect(gg.Status()).To(Equal(games.Status{
+		State:  "Playing",
+		Scores: map[string]int{"Joe": 0, "Natasha": 0, "Maria": 0, "Frank": 0},
+	}))
+	g.Expect(gg.View("Joe")).To(Equal(game.View()))
+
+	now := time.Now()
+	g.Expect(gg.Apply(games.Action{Type: "shuffle", Player: "Joe"}, now)).To(Equal(games.UnknownAction("shuffle")))
+	g.Expect(gg.Apply(games.Action{Type: ClaimAction, Player: "Joe"}, now)).To(Equal(games.ActionError{Action: ClaimAction, Details: "missing payload"}))
+	g.Expect(gg.Apply(games.Action{Type: NextAction, Player: "Joe"}, now)).To(Equal(InvalidStateError{"NextRound", "round not yet claimed"}))
+
+	cs := game.FindExpandSet()
+	g.Expect(cs).NotTo(BeNil())
+	payload, err := json.Marshal(ClaimPayload{*cs})
+	g.Expect(err).To(BeNil())
+	dup, err := json.Marshal(ClaimPayload{CardTriple{cs[0], cs[0], cs[1]}})
+	g.Expect(err).To(BeNil())
+	err = gg.Apply(games.Action{Type: ClaimAction, Player: "Joe", Payload: dup}, now)
+	g.Expect(err).To(BeAssignableToTypeOf(games.ActionError{}))
+	g.Expect(err.Error()).To(ContainSubstring("duplicate cards"))
+	g.Expect(gg.Apply(games.Action{Type: ClaimAction, Player: "Joe", Payload: payload}, now)).To(Succeed())
+	g.Expect(gg.Status().State).To(Equal("SetClaimed"))
+	g.Expect(gg.Status().Scores["Joe"]).To(Equal(1))
+	g.Expect(gg.Apply(games.Action{Type: NextAction, Player: "Joe"}, now)).To(Succeed())
+
+	// Leaving players forfeit their sets unless they return them
+	deckLen := len(game.Deck)
+	g.Expect(gg.Apply(games.Action{Type: games.LeaveAction, Player: "Joe"}, now)).To(Succeed())
+	g.Expect(gg.Status().Scores).NotTo(HaveKey("Joe"))
+	g.Expect(game.Deck).To(HaveLen(deckLen))
+	g.Expect(gg.Apply(games.Action{Type: games.JoinAction, Player: "Ivan"}, now)).To(Succeed())
+	g.Expect(gg.Status().Scores).To(HaveKeyWithValue("Ivan", 0))
+	g.Expect(gg.Apply(games.Action{Type: games.JoinAction, Player: "Joe"}, now)).To(Succeed())
+	cs = game.FindExpandSet()
+	g.Expect(cs).NotTo(BeNil())
+	payload, err = json.Marshal(ClaimPayload{*cs})
+	g.Expect(err).To(BeNil())
+	g.Expect(gg.Apply(games.Action{Type: ClaimAction, Player: "Joe", Payload: payload}, now)).To(Succeed())
+	g.Expect(gg.Apply(games.Action{Type: NextAction, Player: "Joe"}, now)).To(Succeed())
+	deckLen = len(game.Deck)
+	g.Expect(gg.Apply(games.Action{Type: games.LeaveAction, Player: "Joe", Payload: json.RawMessage(`{"sets": "return"}`), Seed: 7}, now)).To(Succeed())
+	g.Expect(game.Deck).To(HaveLen(deckLen + SetLen))
+
+	// Spectators watch and stop watching
+	g.Expect(gg.Apply(games.Action{Type: games.WatchAction, Player: "Joe"}, now)).To(Succeed())
+	g.Expect(game.Spectators).To(HaveKey("Joe"))
+	g.Expect(gg.Apply(games.Action{Type: games.UnwatchAction, Player: "Joe"}, now)).To(Succeed())
+	g.Expect(game.Spectators).To(BeEmpty())
+
+	// The game is over when the deck is empty and the board has no set
+	game.Deck = nil
+	game.Board = Board{&Card{Red, 1, Filled, Diamond}, &Card{Red, 1, Filled, Oval}, &Card{Red, 2, Filled, Diamond}}
+	g.Expect(gg.Status().Over).To(BeTrue())
+}
@@ -0,0 +1,176 @@
+package set
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CardSyntaxError indicates a string is not in a recognized card (or card
+// triple) syntax
+type CardSyntaxError struct {
+	Input   string
+	Details string
+}
+
+func (e CardSyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Details, e.Input)
+}
+
+// CardAttrError indicates a card attribute has a value outside its range
+type CardAttrError struct {
+	Attr  string
+	Value string
+}
+
+func (e CardAttrError) Error() string {
+	return fmt.Sprintf("invalid card %s: %s", e.Attr, e.Value)
+}
+
+// longColors maps long-form color words to Colors
+var longColors = map[string]Color{
+	"green":  Green,
+	"purple": Purple,
+	"red":    Red,
+}
+
+// longCounts maps long-form count words to counts
+var longCounts = map[string]byte{
+	"1":     1,
+	"2":     2,
+	"3":     3,
+	"one":   1,
+	"two":   2,
+	"three": 3,
+}
+
+// longShadings maps long-form shading words to Shadings
+var longShadings = map[string]Shading{
+	"filled":   Filled,
+	"solid":    Filled,
+	"outline":  Outline,
+	"outlined": Outline,
+	"open":     Outline,
+	"empty":    Outline,
+	"stripe":   Stripe,
+	"striped":  Stripe,
+	"shaded":   Stripe,
+}
+
+// longShapes maps long-form shape words (singular or plural) to Shapes
+var longShapes = map[string]Shape{
+	"diamond":   Diamond,
+	"diamonds":  Diamond,
+	"oval":      Oval,
+	"ovals":     Oval,
+	"squiggle":  Squiggle,
+	"squiggles": Squiggle,
+}
+
+// ParseCard parses a Card from either its abbreviated form, one character
+// per attribute in color, count, shading, shape order (e.g. "G2SO"), or its
+// long form, four whitespace-separated words in the same order
+// (e.g. "green 2 striped ovals"). Both forms are case-insensitive. A
+// CardSyntaxError is returned if s is in neither form and a CardAttrError
+// if any attribute is out of range.
+func ParseCard(s string) (Card, error) {
+	if len(strings.Fields(s)) > 1 {
+		return parseLongCard(s)
+	}
+	return parseShortCard(s)
+}
+
+// parseShortCard parses the abbreviated card form
+func parseShortCard(s string) (Card, error) {
+	if len(s) != NAxes {
+		return Card{}, CardSyntaxError{s, "card string must have len 4"}
+	}
+	color := abbrToColor(s[0:1])
+	if !color.Valid() {
+		return Card{}, CardAttrError{"color", s[0:1]}
+	}
+	count, err := strconv.Atoi(s[1:2])
+	if err != nil || count < 1 || count > MaxCount {
+		return Card{}, CardAttrError{"count", s[1:2]}
+	}
+	shading := abbrToShading(s[2:3])
+	if !shading.Valid() {
+		return Card{}, CardAttrError{"shading", s[2:3]}
+	}
+	shape := abbrToShape(s[3:4])
+	if !shape.Valid() {
+		return Card{}, CardAttrError{"shape", s[3:4]}
+	}
+	return Card{color, byte(count), shading, shape}, nil
+}
+
+// parseLongCard parses the long card form
+func parseLongCard(s string) (Card, error) {
+	words := strings.Fields(strings.ToLower(s))
+	if len(words) != NAxes {
+		return Card{}, CardSyntaxError{s, "long form card string must have 4 words"}
+	}
+	color, ok := longColors[words[0]]
+	if !ok {
+		return Card{}, CardAttrError{"color", words[0]}
+	}
+	count, ok := longCounts[words[1]]
+	if !ok {
+		return Card{}, CardAttrError{"count", words[1]}
+	}
+	shading, ok := longShadings[words[2]]
+	if !ok {
+		return Card{}, CardAttrError{"shading", words[2]}
+	}
+	shape, ok := longShapes[words[3]]
+	if !ok {
+		return Card{}, CardAttrError{"shape", words[3]}
+	}
+	return Card{color, count, shading, shape}, nil
+}
+
+// ParseCardTriple parses a CardTriple from three cards in the syntax
+// accepted by ParseCard. Cards are separated by commas, or, if there are
+// no commas, by whitespace (abbreviated form only), e.g. "G1FD R2SO P3OS"
+// or "green 1 filled diamond, red 2 striped ovals, purple 3 outlined
+// squiggles".
+func ParseCardTriple(s string) (CardTriple, error) {
+	var parts []string
+	if strings.Contains(s, ",") {
+		parts = strings.Split(s, ",")
+	} else {
+		parts = strings.Fields(s)
+	}
+	if len(parts) != SetLen {
+		return CardTriple{}, CardSyntaxError{s, "card triple must have 3 cards"}
+	}
+	var ct CardTriple
+	for i, p := range parts {
+		c, err := ParseCard(strings.TrimSpace(p))
+		if err != nil {
+			return CardTriple{}, err
+		}
+		ct[i] = c
+	}
+	return ct, nil
+}
+
+// LongString returns the long form of the card, e.g. "green 2 striped ovals"
+func (c Card) LongString() string {
+	var shading string
+	switch c.Shading {
+	case Filled:
+		shading = "filled"
+	case Outline:
+		shading = "outlined"
+	case Stripe:
+		shading = "striped"
+	default:
+		shading = c.Shading.String()
+	}
+	shape := strings.ToLower(c.Shape.String())
+	if c.Count != 1 {
+		shape += "s"
+	}
+	return fmt.Sprintf("%s %d %s %s", strings.ToLower(c.Color.String()), c.Count, shading, shape)
+}
@@ -0,0 +1,197 @@
+package set
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	. "github.com/onsi/gomega"
+)
+
+// quickCard is a Card generator for testing/quick that produces only cards
+// in the deck
+type quickCard Card
+
+func (quickCard) Generate(r *rand.Rand, size int) reflect.Value {
+	c := CardBase3ToCard(CardBase3(r.Intn(FullDeckLen)))
+	return reflect.ValueOf(quickCard(*c))
+}
+
+func TestParseCard(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c, err := ParseCard("G2SO")
+	g.Expect(err).To(Succeed())
+	g.Expect(c).To(Equal(Card{Green, 2, Stripe, Oval}))
+
+	c, err = ParseCard("r1fd")
+	g.Expect(err).To(Succeed())
+	g.Expect(c).To(Equal(Card{Red, 1, Filled, Diamond}))
+
+	c, err = ParseCard("green 2 striped ovals")
+	g.Expect(err).To(Succeed())
+	g.Expect(c).To(Equal(Card{Green, 2, Stripe, Oval}))
+
+	c, err = ParseCard("  Purple   one SOLID squiggle ")
+	g.Expect(err).To(Succeed())
+	g.Expect(c).To(Equal(Card{Purple, 1, Filled, Squiggle}))
+
+	_, err = ParseCard("foo")
+	g.Expect(err).To(MatchError(CardSyntaxError{"foo", "card string must have len 4"}))
+	_, err = ParseCard("")
+	g.Expect(err).To(MatchError(CardSyntaxError{"", "card string must have len 4"}))
+	_, err = ParseCard("green 2 ovals")
+	g.Expect(err).To(MatchError(CardSyntaxError{"green 2 ovals", "long form card string must have 4 words"}))
+
+	_, err = ParseCard("X1FD")
+	g.Expect(err).To(MatchError(CardAttrError{"color", "X"}))
+	_, err = ParseCard("G0FD")
+	g.Expect(err).To(MatchError(CardAttrError{"count", "0"}))
+	_, err = ParseCard("G7FD")
+	g.Expect(err).To(MatchError(CardAttrError{"count", "7"}))
+	_, err = ParseCard("G1XD")
+	g.Expect(err).To(MatchError(CardAttrError{"shading", "X"}))
+	_, err = ParseCard("g9fx")
+	g.Expect(err).To(MatchError(CardAttrError{"count", "9"}))
+	_, err = ParseCard("G1Fx")
+	g.Expect(err).To(MatchError(CardAttrError{"shape", "x"}))
+	_, err = ParseCard("blue 2 striped ovals")
+	g.Expect(err).To(MatchError(CardAttrError{"color", "blue"}))
+	_, err = ParseCard("green four striped ovals")
+	g.Expect(err).To(MatchError(CardAttrError{"count", "four"}))
+	_, err = ParseCard("green 2 dotted ovals")
+	g.Expect(err).To(MatchError(CardAttrError{"shading", "dotted"}))
+	_, err = ParseCard("green 2 striped circles")
+	g.Expect(err).To(MatchError(CardAttrError{"shape", "circles"}))
+}
+
+func TestParseCardTriple(t *testing.T) {
+	g := NewGomegaWithT(t)
+	exp := CardTriple{{Green, 1, Filled, Diamond}, {Red, 2, Stripe, Oval}, {Purple, 3, Outline, Squiggle}}
+
+	ct, err := ParseCardTriple("G1FD R2SO P3OS")
+	g.Expect(err).To(Succeed())
+	g.Expect(ct).To(Equal(exp))
+
+	ct, err = ParseCardTriple("G1FD,R2SO, P3OS")
+	g.Expect(err).To(Succeed())
+	g.Expect(ct).To(Equal(exp))
+
+	ct, err = ParseCardTriple("green 1 filled diamond, red 2 striped ovals, purple 3 outlined squiggles")
+	g.Expect(err).To(Succeed())
+	g.Expect(ct).To(Equal(exp))
+
+	_, err = ParseCardTriple("G1FD R2SO")
+	g.Expect(err).To(MatchError(CardSyntaxError{"G1FD R2SO", "card triple must have 3 cards"}))
+	_, err = ParseCardTriple("G1FD R2SO P3OX")
+	g.Expect(err).To(MatchError(CardAttrError{"shape", "X"}))
+}
+
+func TestCardValidate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(Card{Red, 3, Stripe, Squiggle}.Validate()).To(Succeed())
+	g.Expect(Card{Red + 1, 1, Filled, Diamond}.Validate()).To(MatchError(CardAttrError{"color", "Color(3)"}))
+	g.Expect(Card{Red, 0, Filled, Diamond}.Validate()).To(MatchError(CardAttrError{"count", "0"}))
+	g.Expect(Card{Red, 4, Filled, Diamond}.Validate()).To(MatchError(CardAttrError{"count", "4"}))
+	g.Expect(Card{Red, 1, Stripe + 1, Diamond}.Validate()).To(MatchError(CardAttrError{"shading", "Shading(3)"}))
+	g.Expect(Card{Red, 1, Filled, Squiggle + 1}.Validate()).To(MatchError(CardAttrError{"shape", "Shape(3)"}))
+
+	_, err := json.Marshal(Card{Red, 0, Filled, Diamond})
+	g.Expect(err).To(HaveOccurred())
+}
+
+// TestCardRoundTrip checks every Card in the deck survives each of its
+// encodings
+func TestCardRoundTrip(t *testing.T) {
+	roundTrip := func(qc quickCard) bool {
+		c := Card(qc)
+		b, err := json.Marshal(c)
+		if err != nil {
+			return false
+		}
+		var jc Card
+		if err := json.Unmarshal(b, &jc); err != nil || jc != c {
+			return false
+		}
+		lc, err := ParseCard(c.LongString())
+		if err != nil || lc != c {
+			return false
+		}
+		return *CardBase3ToCard(CardToCardBase3(&c)) == c
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 10 * FullDeckLen}); err != nil {
+		t.Error(err)
+	}
+
+	// The generator is random, so also cover the whole deck exhaustively
+	for i := 0; i < FullDeckLen; i++ {
+		c := CardBase3ToCard(CardBase3(i))
+		if !roundTrip(quickCard(*c)) {
+			t.Errorf("Card %s does not round trip", c)
+		}
+		if CardToCardBase3(c) != CardBase3(i) {
+			t.Errorf("CardBase3 %d does not round trip", i)
+		}
+	}
+}
+
+// FuzzParseCard checks arbitrary input either fails to parse or yields a
+// valid Card that parses back from each of its forms
+func FuzzParseCard(f *testing.F) {
+	for _, s := range []string{"G2SO", "r1fd", "g9fx", "G1Fx", "", "foo",
+		"green 2 striped ovals", "  Purple   one SOLID squiggle ", "blue 2 striped ovals"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		c, err := ParseCard(s)
+		if err != nil {
+			if c != (Card{}) {
+				t.Errorf("ParseCard(%q) returned %s with err %s", s, &c, err)
+			}
+			return
+		}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("ParseCard(%q) returned invalid card: %s", s, err)
+		}
+		for _, form := range []string{c.String(), c.LongString()} {
+			fc, err := ParseCard(form)
+			if err != nil || fc != c {
+				t.Errorf("ParseCard(%q) of %q returned %s, %v", form, s, &fc, err)
+			}
+		}
+	})
+}
+
+// FuzzCardUnmarshalJSON checks arbitrary JSON either fails to unmarshal or
+// yields a valid Card that round trips through Marshal and CardBase3
+func FuzzCardUnmarshalJSON(f *testing.F) {
+	for _, s := range []string{`"G2SO"`, `"g9fx"`, `"green 2 striped ovals"`, `""`, `"G1F"`, `12`, `null`, `{"Color": 1}`} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var c Card
+		if err := json.Unmarshal(b, &c); err != nil {
+			return
+		}
+		if c == (Card{}) {
+			// JSON null leaves the card untouched
+			return
+		}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("Unmarshal(%q) returned invalid card: %s", b, err)
+		}
+		jb, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("Marshal(%s) of %q returned err %s", &c, b, err)
+		}
+		var jc Card
+		if err := json.Unmarshal(jb, &jc); err != nil || jc != c {
+			t.Errorf("Unmarshal(%s) of %q returned %s, %v", jb, b, &jc, err)
+		}
+		if bc := CardBase3ToCard(CardToCardBase3(&c)); *bc != c {
+			t.Errorf("CardBase3 of %s of %q returned %s", &c, b, bc)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package set
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// View is the projection of a Game presented to players and spectators. It
+// omits the Deck, which would reveal the upcoming cards and the order they
+// will be dealt, replacing it with the number of cards remaining.
+type View struct {
+	ID              uuid.UUID          `json:"id"`
+	Players         map[string]*Player `json:"players"`
+	DeckLen         int                `json:"deckLen"`
+	Board           Board              `json:"board"`
+	ClaimedSet      CardTriple         `json:"claimedSet"`
+	ClaimedUsername string             `json:"claimedUsername"`
+	Spectators      map[string]bool    `json:"spectators"`
+	LastActivity    time.Time          `json:"lastActivity"`
+}
+
+// View returns the public projection of the game
+func (g *Game) View() *View {
+	return &View{
+		ID:              g.ID,
+		Players:         g.Players,
+		DeckLen:         len(g.Deck),
+		Board:           g.Board,
+		ClaimedSet:      g.ClaimedSet,
+		ClaimedUsername: g.ClaimedUsername,
+		Spectators:      g.Spectators,
+		LastActivity:    g.LastActivity,
+	}
+}
+
+// GetState returns the state of the viewed game
+func (v *View) GetState() State {
+	if v.ClaimedUsername == "" {
+		return Playing
+	}
+	return SetClaimed
+}
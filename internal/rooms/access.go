@@ -0,0 +1,110 @@
+package rooms
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// inviteLen is the number of random bytes in an invite code
+const inviteLen = 16
+
+// HashPassword returns the salted bcrypt hash of the given room password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword returns true if password matches the given hash
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewInvite returns a new random invite code and its hash, which is what
+// a room stores
+func NewInvite() (code, hash string, err error) {
+	b := make([]byte, inviteLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	code = hex.EncodeToString(b)
+	return code, HashInvite(code), nil
+}
+
+// HashInvite returns the hash of the given invite code. Codes are random,
+// so unlike passwords they need no salt.
+func HashInvite(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// Restricted returns true if users must give a password or invite code to
+// join the room
+func (r *Room) Restricted() bool {
+	return r.Private || r.PasswordHash != ""
+}
+
+// Admits returns true if a user giving the password and invite code may
+// join the room. Either one suffices; an unrestricted room admits anyone.
+func (r *Room) Admits(password, invite string) bool {
+	if !r.Restricted() {
+		return true
+	}
+	if password != "" && r.PasswordHash != "" && checkPassword(r.PasswordHash, password) {
+		return true
+	}
+	if invite != "" {
+		_, ok := r.Invites[HashInvite(invite)]
+		return ok
+	}
+	return false
+}
+
+// SetAccess sets whether the room is private and its password hash, with
+// an empty hash meaning no password
+func (r *Room) SetAccess(private bool, passwordHash string) {
+	r.Private = private
+	r.PasswordHash = passwordHash
+}
+
+// AddInvite records the invite code with the given hash, created at the
+// given time
+func (r *Room) AddInvite(hash string, now time.Time) {
+	if r.Invites == nil {
+		r.Invites = make(map[string]time.Time)
+	}
+	r.Invites[hash] = now
+}
+
+// RevokeInvite deletes the invite code with the given hash, or all invite
+// codes if hash is empty. It returns false if there is no such code.
+func (r *Room) RevokeInvite(hash string) bool {
+	if hash == "" {
+		r.Invites = nil
+		return true
+	}
+	if _, ok := r.Invites[hash]; !ok {
+		return false
+	}
+	delete(r.Invites, hash)
+	if len(r.Invites) == 0 {
+		r.Invites = nil
+	}
+	return true
+}
+
+// Redacted returns a copy of the room without its password hash and
+// invite code hashes, for clients that have no business with them
+func (r *Room) Redacted() *Room {
+	cp := *r
+	cp.HasPassword = r.PasswordHash != ""
+	cp.PasswordHash = ""
+	cp.Invites = nil
+	return &cp
+}
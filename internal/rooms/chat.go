@@ -0,0 +1,47 @@
+package rooms
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxMessageLen is the most characters in a chat message
+const MaxMessageLen = 500
+
+// Message is a chat message posted in a room
+type Message struct {
+	// ID orders the messages in a room, later messages having greater IDs
+	ID       int64     `json:"id"`
+	Room     string    `json:"room"`
+	Username string    `json:"username"`
+	Text     string    `json:"text"`
+	Time     time.Time `json:"time"`
+}
+
+// MessageError indicates a chat message is invalid
+type MessageError struct {
+	Details string
+}
+
+func (e MessageError) Error() string {
+	return e.Details
+}
+
+// NewMessage creates a message from the given user in the given room with
+// the given text, less leading and trailing whitespace. A MessageError is
+// returned if the text is empty or longer than MaxMessageLen.
+func NewMessage(room, username, text string) (*Message, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, MessageError{"message text must be non-empty"}
+	}
+	if !utf8.ValidString(text) {
+		return nil, MessageError{"message text must be valid UTF-8"}
+	}
+	if n := utf8.RuneCountInString(text); n > MaxMessageLen {
+		return nil, MessageError{fmt.Sprintf("message text has %d characters, more than the %d allowed", n, MaxMessageLen)}
+	}
+	return &Message{Room: room, Username: username, Text: text}, nil
+}
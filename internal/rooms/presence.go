@@ -0,0 +1,86 @@
+package rooms
+
+import (
+	"sort"
+	"time"
+)
+
+// PresenceStatus is whether a user in a room is connected and active
+type PresenceStatus string
+
+const (
+	// Online users are connected and active
+	Online PresenceStatus = "online"
+	// Idle users are connected but their client reports them inactive
+	Idle PresenceStatus = "idle"
+	// Disconnected users have stopped sending heartbeats
+	Disconnected PresenceStatus = "disconnected"
+)
+
+// Presence is the connection status of a user in a room
+type Presence struct {
+	Status PresenceStatus `json:"status"`
+	// Since is when the user entered Status
+	Since time.Time `json:"since"`
+	// LastSeen is the time of the user's last heartbeat
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Heartbeat records a heartbeat at the given time from the given user,
+// reporting whether they are idle. It returns true if their status changed.
+func (r *Room) Heartbeat(username string, idle bool, now time.Time) bool {
+	status := Online
+	if idle {
+		status = Idle
+	}
+	p, ok := r.Presence[username]
+	changed := !ok || p.Status != status
+	if changed {
+		p.Status = status
+		p.Since = now
+	}
+	p.LastSeen = now
+	if r.Presence == nil {
+		r.Presence = make(map[string]Presence)
+	}
+	r.Presence[username] = p
+	return changed
+}
+
+// Disconnect marks as disconnected, as of the given time, the users whose
+// last heartbeat was before the given time, and returns their usernames
+func (r *Room) Disconnect(before, now time.Time) []string {
+	var usernames []string
+	for u, p := range r.Presence {
+		if p.Status != Disconnected && p.LastSeen.Before(before) {
+			p.Status = Disconnected
+			p.Since = now
+			r.Presence[u] = p
+			usernames = append(usernames, u)
+		}
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
+// DisconnectedSince returns the usernames of the users who have been
+// disconnected since before the given time, sorted
+func (r *Room) DisconnectedSince(before time.Time) []string {
+	var usernames []string
+	for u, p := range r.Presence {
+		if p.Status == Disconnected && p.Since.Before(before) {
+			usernames = append(usernames, u)
+		}
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
+// deletePresence forgets the presence of the given user, leaving Presence
+// nil rather than empty so Rooms compare equal after a json round trip
+func (r *Room) deletePresence(username string) {
+	delete(r.Presence, username)
+	if len(r.Presence) == 0 {
+		r.Presence = nil
+	}
+}
@@ -1,7 +1,12 @@
 package rooms
 
 import (
+	"sort"
+	"time"
+
 	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/validate"
 )
 
 // GameType is the type of a game
@@ -16,6 +21,37 @@ const (
 
 //go:generate stringer -type=GameType
 
+// GameMaxPlayers is the most players each GameType can accommodate. Set
+// gets unplayable beyond about 8 players.
+var GameMaxPlayers = map[GameType]int{
+	Set:     8,
+	Boggle:  10,
+	RRobots: 8,
+}
+
+// Role is a user's role in a room
+type Role string
+
+const (
+	// Owner may moderate the room and grant the Moderator role
+	Owner Role = "owner"
+	// Moderator may delete the room, remove players and change the game
+	Moderator Role = "moderator"
+	// Player is the default role of a user in Usernames
+	Player Role = "player"
+	// Spectator watches the room's games without playing
+	Spectator Role = "spectator"
+)
+
+// Valid returns true if r is a defined Role
+func (r Role) Valid() bool {
+	switch r {
+	case Owner, Moderator, Player, Spectator:
+		return true
+	}
+	return false
+}
+
 // Room is an instance of a game room
 type Room struct {
 	// Name is the human-readable unique identifier of the Room
@@ -26,12 +62,376 @@ type Room struct {
 	GameType GameType `json:"gameType"`
 	// GameID is the identifier of the current game being played in the room
 	GameID uuid.UUID `json:"gameID"`
+	// Games is the history of games played in the room, oldest first. The
+	// last entry is the current game, if there is one.
+	Games []*GameRecord `json:"games,omitempty"`
+	// Owner is the username of the room's owner. A room with no owner is
+	// unmoderated: anyone may moderate it.
+	Owner string `json:"owner,omitempty"`
+	// Roles are the roles of users other than the default: Owner for the
+	// Owner, Player for other users in Usernames
+	Roles map[string]Role `json:"roles,omitempty"`
+	// Private rooms are unlisted and, like rooms with a password, may be
+	// joined only with the password or an invite code
+	Private bool `json:"private,omitempty"`
+	// PasswordHash is the salted hash of the room password, if any
+	PasswordHash string `json:"passwordHash,omitempty"`
+	// HasPassword is set in place of PasswordHash in Redacted rooms
+	HasPassword bool `json:"hasPassword,omitempty"`
+	// MaxPlayers is the most players the room allows, or 0 for only the
+	// limit of the current GameType
+	MaxPlayers int `json:"maxPlayers,omitempty"`
+	// Waitlist are the usernames of users waiting for a place in a full
+	// room, first come first served
+	Waitlist []string `json:"waitlist,omitempty"`
+	// Presence is the connection status of each user who has sent a
+	// heartbeat
+	Presence map[string]Presence `json:"presence,omitempty"`
+	// Invites are the creation times of the room's invite codes, keyed on
+	// the hash of the code
+	Invites map[string]time.Time `json:"invites,omitempty"`
+	// LastActivity is the time the room was last updated
+	LastActivity time.Time `json:"lastActivity"`
+}
+
+// GameRecord is the history entry of a game played in a room
+type GameRecord struct {
+	GameType GameType  `json:"gameType"`
+	GameID   uuid.UUID `json:"gameID"`
+	Start    time.Time `json:"start"`
+	// End is nil while the game is being played
+	End *time.Time `json:"end,omitempty"`
+	// Scores are the final scores of the players, keyed on username
+	Scores map[string]int `json:"scores,omitempty"`
+	// Winners are the usernames with the highest non-zero final score
+	Winners []string `json:"winners,omitempty"`
+}
+
+// Standing is a user's cumulative results over the games in a room
+type Standing struct {
+	Username string `json:"username"`
+	// Games is the number of ended games the user played
+	Games int `json:"games"`
+	// Wins is the number of those games the user won or tied
+	Wins int `json:"wins"`
+	// Points is the user's total score over those games
+	Points int `json:"points"`
 }
 
 // NewRoom creates a room with given name and players
 func NewRoom(name string, usernames map[string]bool) *Room {
 	r := new(Room)
 	r.Name = name
+	if usernames == nil {
+		usernames = make(map[string]bool)
+	}
 	r.Usernames = usernames
 	return r
 }
+
+// Role returns the given user's role in the room, or "" if the user is
+// not in the room
+func (r *Room) Role(username string) Role {
+	if username != "" && username == r.Owner {
+		return Owner
+	}
+	if role, ok := r.Roles[username]; ok {
+		return role
+	}
+	if r.Usernames[username] {
+		return Player
+	}
+	return ""
+}
+
+// SameUser returns the username of the user in the room or its waitlist
+// who has the same username as the given one but for case, the given
+// username itself if they are in the room, or "" if there is none
+func (r *Room) SameUser(username string) string {
+	key := validate.Key(username)
+	same := func(u string) bool { return validate.Key(u) == key }
+	if same(r.Owner) {
+		return r.Owner
+	}
+	for u := range r.Usernames {
+		if same(u) {
+			return u
+		}
+	}
+	for u := range r.Roles {
+		if same(u) {
+			return u
+		}
+	}
+	for _, u := range r.Waitlist {
+		if same(u) {
+			return u
+		}
+	}
+	return ""
+}
+
+// CanModerate returns true if the given user, matched but for case, may
+// moderate the room. No user may moderate a room with no Owner, which only
+// admins moderate.
+func (r *Room) CanModerate(username string) bool {
+	if r.Owner == "" {
+		return false
+	}
+	role := r.Role(r.SameUser(username))
+	return role == Owner || role == Moderator
+}
+
+// SetRole sets the role of the given user, who must already be in the
+// room, to Moderator, Player or Spectator. Players are in Usernames and
+// spectators are not; a moderator keeps their place in Usernames, or not.
+// It returns false if the user is not in the room or is the Owner, whose
+// role changes only by SetOwner.
+func (r *Room) SetRole(username string, role Role) bool {
+	cur := r.Role(username)
+	if cur == "" || cur == Owner {
+		return false
+	}
+	switch role {
+	case Player:
+		// A user joining the players may have to wait for a place
+		if r.Usernames[username] || r.AddPlayer(username) {
+			r.deleteRole(username)
+		}
+		return true
+	case Spectator:
+		delete(r.Usernames, username)
+	}
+	if r.Roles == nil {
+		r.Roles = make(map[string]Role)
+	}
+	r.Roles[username] = role
+	if role == Spectator {
+		r.Promote()
+	}
+	return true
+}
+
+// SetOwner transfers ownership of the room to the given user, who must
+// already be in the room. The previous owner, if any, becomes a Moderator.
+// It returns false if the user is not in the room.
+func (r *Room) SetOwner(username string) bool {
+	if r.Role(username) == "" {
+		return false
+	}
+	prev := r.Owner
+	r.Owner = username
+	r.deleteRole(username)
+	if prev != "" && prev != username {
+		if r.Roles == nil {
+			r.Roles = make(map[string]Role)
+		}
+		r.Roles[prev] = Moderator
+	}
+	return true
+}
+
+// Capacity returns the most players the room allows, the lesser of its
+// MaxPlayers and the limit of its current GameType, or 0 if unlimited
+func (r *Room) Capacity() int {
+	return r.CapacityFor(r.GameType)
+}
+
+// CapacityFor returns the most players the room would allow playing a
+// game of the given type, or 0 if unlimited
+func (r *Room) CapacityFor(typ GameType) int {
+	max := r.MaxPlayers
+	if gmax := GameMaxPlayers[typ]; gmax > 0 && (max == 0 || gmax < max) {
+		max = gmax
+	}
+	return max
+}
+
+// Full returns true if the room has no place for another player
+func (r *Room) Full() bool {
+	max := r.Capacity()
+	return max > 0 && len(r.Usernames) >= max
+}
+
+// Waiting returns true if the given user is on the room's waitlist
+func (r *Room) Waiting(username string) bool {
+	for _, u := range r.Waitlist {
+		if u == username {
+			return true
+		}
+	}
+	return false
+}
+
+// AddPlayer adds the given user to the room's players or, if the room is
+// full, to the end of its waitlist. A spectator who joins becomes a player.
+// It returns false if the user was waitlisted.
+func (r *Room) AddPlayer(username string) bool {
+	if r.Full() {
+		if !r.Waiting(username) {
+			r.Waitlist = append(r.Waitlist, username)
+		}
+		return false
+	}
+	if r.Roles[username] == Spectator {
+		r.deleteRole(username)
+	}
+	r.Usernames[username] = true
+	return true
+}
+
+// RemoveUser removes the given user from the room's players, roles,
+// waitlist and presence, promoting waiting users into any place freed.
+// The Owner remains the owner.
+func (r *Room) RemoveUser(username string) {
+	delete(r.Usernames, username)
+	r.deleteRole(username)
+	r.removeWaiting(username)
+	r.deletePresence(username)
+	r.Promote()
+}
+
+// Promote moves users from the front of the waitlist into the room's
+// players while there is room for them and returns their usernames
+func (r *Room) Promote() []string {
+	var promoted []string
+	for len(r.Waitlist) > 0 && !r.Full() {
+		u := r.Waitlist[0]
+		r.removeWaiting(u)
+		r.AddPlayer(u)
+		promoted = append(promoted, u)
+	}
+	return promoted
+}
+
+// removeWaiting removes the given user from the waitlist, leaving it nil
+// rather than empty so Rooms compare equal after a json round trip
+func (r *Room) removeWaiting(username string) {
+	for i, u := range r.Waitlist {
+		if u == username {
+			r.Waitlist = append(r.Waitlist[:i], r.Waitlist[i+1:]...)
+			break
+		}
+	}
+	if len(r.Waitlist) == 0 {
+		r.Waitlist = nil
+	}
+}
+
+// SetMaxPlayers sets the room's player limit, 0 for none, promoting
+// waiting users into any places it opens. Lowering the limit below the
+// number of players removes no one.
+func (r *Room) SetMaxPlayers(max int) {
+	r.MaxPlayers = max
+	r.Promote()
+}
+
+// deleteRole reverts the given user to their default role, leaving Roles
+// nil rather than empty so Rooms compare equal after a json round trip
+func (r *Room) deleteRole(username string) {
+	delete(r.Roles, username)
+	if len(r.Roles) == 0 {
+		r.Roles = nil
+	}
+}
+
+// CurrentGame returns the history entry of the current game, or nil if
+// there is none
+func (r *Room) CurrentGame() *GameRecord {
+	if len(r.Games) == 0 || r.Games[len(r.Games)-1].End != nil {
+		return nil
+	}
+	return r.Games[len(r.Games)-1]
+}
+
+// StartGame makes the given game, started at the given time, the room's
+// current game. Any game already in progress is ended without results. A
+// typ of None just clears the current game.
+func (r *Room) StartGame(typ GameType, id uuid.UUID, now time.Time) {
+	if cur := r.CurrentGame(); cur != nil {
+		if cur.GameType == typ && cur.GameID == id {
+			return
+		}
+		r.EndGame(nil, now)
+	}
+	r.GameType = typ
+	r.GameID = id
+	if typ == None {
+		return
+	}
+	r.Games = append(r.Games, &GameRecord{GameType: typ, GameID: id, Start: now})
+}
+
+// EndGame records the end of the current game at the given time with the
+// given final scores and clears it. It returns false if there is no
+// current game.
+func (r *Room) EndGame(scores map[string]int, now time.Time) bool {
+	cur := r.CurrentGame()
+	if cur == nil {
+		return false
+	}
+	cur.End = &now
+	cur.Scores = scores
+	cur.Winners = winners(scores)
+	r.GameType = None
+	r.GameID = uuid.Nil
+	// Without the game's limit there may be places for waiting users
+	r.Promote()
+	return true
+}
+
+// winners returns the usernames with the highest non-zero score, sorted
+func winners(scores map[string]int) []string {
+	var (
+		ws  []string
+		max int
+	)
+	for u, s := range scores {
+		if s > max {
+			ws = []string{u}
+			max = s
+		} else if s == max && s > 0 {
+			ws = append(ws, u)
+		}
+	}
+	sort.Strings(ws)
+	return ws
+}
+
+// Standings returns the cumulative results of each user over the ended
+// games in the room, best first: by wins, then points, then username
+func (r *Room) Standings() []Standing {
+	m := make(map[string]*Standing)
+	for _, g := range r.Games {
+		if g.End == nil {
+			continue
+		}
+		for u, s := range g.Scores {
+			st, ok := m[u]
+			if !ok {
+				st = &Standing{Username: u}
+				m[u] = st
+			}
+			st.Games++
+			st.Points += s
+		}
+		for _, u := range g.Winners {
+			m[u].Wins++
+		}
+	}
+	standings := make([]Standing, 0, len(m))
+	for _, st := range m {
+		standings = append(standings, *st)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		si, sj := standings[i], standings[j]
+		if si.Wins != sj.Wins {
+			return si.Wins > sj.Wins
+		}
+		if si.Points != sj.Points {
+			return si.Points > sj.Points
+		}
+		return si.Username < sj.Username
+	})
+	return standings
+}
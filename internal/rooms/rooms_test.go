@@ -0,0 +1,238 @@
+package rooms
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestGameHistory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := NewRoom("r", map[string]bool{"Joe": true, "Maria": true, "Frank": true})
+	g.Expect(r.CurrentGame()).To(BeNil())
+	g.Expect(r.EndGame(nil, time.Now())).To(BeFalse())
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	id0 := uuid.New()
+	r.StartGame(Set, id0, t0)
+	g.Expect(r.GameType).To(Equal(Set))
+	g.Expect(r.GameID).To(Equal(id0))
+	g.Expect(r.CurrentGame()).To(Equal(&GameRecord{GameType: Set, GameID: id0, Start: t0}))
+
+	// Restarting the current game is a no-op
+	r.StartGame(Set, id0, t0.Add(time.Minute))
+	g.Expect(r.Games).To(HaveLen(1))
+
+	t1 := t0.Add(time.Hour)
+	g.Expect(r.EndGame(map[string]int{"Joe": 5, "Maria": 5, "Frank": 2}, t1)).To(BeTrue())
+	g.Expect(r.CurrentGame()).To(BeNil())
+	g.Expect(r.GameType).To(Equal(None))
+	g.Expect(r.GameID).To(Equal(uuid.Nil))
+	g.Expect(*r.Games[0].End).To(Equal(t1))
+	g.Expect(r.Games[0].Winners).To(Equal([]string{"Joe", "Maria"}))
+
+	// Starting a game ends the one in progress without results
+	r.StartGame(Set, uuid.New(), t1)
+	id2 := uuid.New()
+	r.StartGame(Set, id2, t1.Add(time.Hour))
+	g.Expect(r.Games).To(HaveLen(3))
+	g.Expect(r.Games[1].End).NotTo(BeNil())
+	g.Expect(r.Games[1].Winners).To(BeEmpty())
+	g.Expect(r.CurrentGame().GameID).To(Equal(id2))
+
+	g.Expect(r.EndGame(map[string]int{"Joe": 1, "Frank": 4}, t1.Add(2*time.Hour))).To(BeTrue())
+
+	// Clearing with no current game changes nothing
+	r.StartGame(None, uuid.Nil, t1.Add(3*time.Hour))
+	g.Expect(r.Games).To(HaveLen(3))
+
+	g.Expect(r.Standings()).To(Equal([]Standing{
+		{Username: "Frank", Games: 2, Wins: 1, Points: 6},
+		{Username: "Joe", Games: 2, Wins: 1, Points: 6},
+		{Username: "Maria", Games: 1, Wins: 1, Points: 5},
+	}))
+}
+
+func TestRoles(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := NewRoom("r", map[string]bool{"Joe": true, "Maria": true, "Frank": true})
+
+	// Only admins moderate unowned rooms
+	g.Expect(r.Role("Joe")).To(Equal(Player))
+	g.Expect(r.Role("Jane")).To(BeEmpty())
+	g.Expect(r.CanModerate("Joe")).To(BeFalse())
+
+	g.Expect(r.SetOwner("Jane")).To(BeFalse())
+	g.Expect(r.SetOwner("Joe")).To(BeTrue())
+	g.Expect(r.Role("Joe")).To(Equal(Owner))
+	g.Expect(r.CanModerate("Joe")).To(BeTrue())
+	g.Expect(r.CanModerate("JOE")).To(BeTrue())
+	g.Expect(r.CanModerate("Maria")).To(BeFalse())
+	g.Expect(r.CanModerate("")).To(BeFalse())
+
+	g.Expect(r.SetRole("Maria", Moderator)).To(BeTrue())
+	g.Expect(r.Role("Maria")).To(Equal(Moderator))
+	g.Expect(r.CanModerate("Maria")).To(BeTrue())
+	g.Expect(r.Usernames).To(HaveKey("Maria"))
+	g.Expect(r.SetRole("Joe", Player)).To(BeFalse())
+	g.Expect(r.SetRole("Jane", Player)).To(BeFalse())
+
+	// Spectators are not players, and become players again on joining
+	g.Expect(r.SetRole("Frank", Spectator)).To(BeTrue())
+	g.Expect(r.Role("Frank")).To(Equal(Spectator))
+	g.Expect(r.Usernames).NotTo(HaveKey("Frank"))
+	r.AddPlayer("Frank")
+	g.Expect(r.Role("Frank")).To(Equal(Player))
+	g.Expect(r.SetRole("Frank", Spectator)).To(BeTrue())
+	g.Expect(r.SetRole("Frank", Player)).To(BeTrue())
+	g.Expect(r.Usernames).To(HaveKey("Frank"))
+
+	// Previous owner becomes a moderator
+	g.Expect(r.SetOwner("Frank")).To(BeTrue())
+	g.Expect(r.Role("Frank")).To(Equal(Owner))
+	g.Expect(r.Role("Joe")).To(Equal(Moderator))
+
+	r.RemoveUser("Maria")
+	g.Expect(r.Role("Maria")).To(BeEmpty())
+	r.RemoveUser("Joe")
+	g.Expect(r.Roles).To(BeNil())
+}
+
+func TestAccess(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := NewRoom("r", map[string]bool{"Joe": true})
+	g.Expect(r.Restricted()).To(BeFalse())
+	g.Expect(r.Admits("", "")).To(BeTrue())
+
+	hash, err := HashPassword("sekrit")
+	g.Expect(err).To(Succeed())
+	g.Expect(hash).NotTo(ContainSubstring("sekrit"))
+	g.Expect(hash).To(HavePrefix("$2a$"))
+	hash2, err := HashPassword("sekrit")
+	g.Expect(err).To(Succeed())
+	g.Expect(hash2).NotTo(Equal(hash))
+
+	r.SetAccess(false, hash)
+	g.Expect(r.Restricted()).To(BeTrue())
+	g.Expect(r.Admits("", "")).To(BeFalse())
+	g.Expect(r.Admits("wrong", "")).To(BeFalse())
+	g.Expect(r.Admits("sekrit", "")).To(BeTrue())
+
+	// Private rooms with no password admit only by invite
+	r.SetAccess(true, "")
+	g.Expect(r.Admits("sekrit", "")).To(BeFalse())
+	code, inviteHash, err := NewInvite()
+	g.Expect(err).To(Succeed())
+	g.Expect(HashInvite(code)).To(Equal(inviteHash))
+	g.Expect(r.Admits("", code)).To(BeFalse())
+	r.AddInvite(inviteHash, time.Now())
+	g.Expect(r.Admits("", code)).To(BeTrue())
+	g.Expect(r.Admits("", inviteHash)).To(BeFalse())
+
+	red := r.Redacted()
+	g.Expect(red.Invites).To(BeNil())
+	g.Expect(r.Invites).To(HaveKey(inviteHash))
+
+	g.Expect(r.RevokeInvite(HashInvite("bogus"))).To(BeFalse())
+	g.Expect(r.RevokeInvite(inviteHash)).To(BeTrue())
+	g.Expect(r.Admits("", code)).To(BeFalse())
+	g.Expect(r.Invites).To(BeNil())
+
+	r.SetAccess(true, hash)
+	red = r.Redacted()
+	g.Expect(red.PasswordHash).To(BeEmpty())
+	g.Expect(red.HasPassword).To(BeTrue())
+	g.Expect(r.PasswordHash).To(Equal(hash))
+}
+
+func TestCapacity(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := NewRoom("r", map[string]bool{"Joe": true})
+	g.Expect(r.Capacity()).To(Equal(0))
+	g.Expect(r.CapacityFor(Set)).To(Equal(GameMaxPlayers[Set]))
+
+	r.SetMaxPlayers(2)
+	g.Expect(r.CapacityFor(Set)).To(Equal(2))
+	g.Expect(r.AddPlayer("Maria")).To(BeTrue())
+	g.Expect(r.Full()).To(BeTrue())
+
+	// Full rooms waitlist players in order
+	g.Expect(r.AddPlayer("Frank")).To(BeFalse())
+	g.Expect(r.AddPlayer("Jane")).To(BeFalse())
+	g.Expect(r.AddPlayer("Frank")).To(BeFalse())
+	g.Expect(r.Waitlist).To(Equal([]string{"Frank", "Jane"}))
+	g.Expect(r.Waiting("Jane")).To(BeTrue())
+	g.Expect(r.Usernames).NotTo(HaveKey("Frank"))
+
+	// Leaving promotes the first waiting user
+	r.RemoveUser("Joe")
+	g.Expect(r.Usernames).To(HaveKey("Frank"))
+	g.Expect(r.Waitlist).To(Equal([]string{"Jane"}))
+
+	// Waiting users may leave the waitlist
+	r.RemoveUser("Jane")
+	g.Expect(r.Waitlist).To(BeNil())
+
+	// Spectating frees a place, and rejoining may have to wait
+	r.AddPlayer("Jane")
+	g.Expect(r.SetRole("Maria", Spectator)).To(BeTrue())
+	g.Expect(r.Usernames).To(HaveKey("Jane"))
+	g.Expect(r.SetRole("Maria", Player)).To(BeTrue())
+	g.Expect(r.Role("Maria")).To(Equal(Spectator))
+	g.Expect(r.Waitlist).To(Equal([]string{"Maria"}))
+
+	// Raising the limit promotes waiting users, lowering it removes no one
+	r.SetMaxPlayers(0)
+	g.Expect(r.Role("Maria")).To(Equal(Player))
+	g.Expect(r.Waitlist).To(BeNil())
+	r.SetMaxPlayers(1)
+	g.Expect(r.Usernames).To(HaveLen(3))
+	g.Expect(r.Full()).To(BeTrue())
+
+	// The game type's limit applies while a game is played
+	r.SetMaxPlayers(0)
+	r.StartGame(Set, uuid.New(), time.Now())
+	g.Expect(r.Capacity()).To(Equal(GameMaxPlayers[Set]))
+	for i := 0; i < GameMaxPlayers[Set]; i++ {
+		r.AddPlayer(fmt.Sprintf("p%d", i))
+	}
+	g.Expect(r.Usernames).To(HaveLen(GameMaxPlayers[Set]))
+	g.Expect(r.Waitlist).To(HaveLen(3))
+	r.EndGame(nil, time.Now())
+	g.Expect(r.Waitlist).To(BeNil())
+}
+
+func TestPresence(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := NewRoom("r", map[string]bool{"Joe": true, "Maria": true})
+	t0 := time.Now()
+
+	g.Expect(r.Heartbeat("Joe", false, t0)).To(BeTrue())
+	g.Expect(r.Heartbeat("Maria", false, t0)).To(BeTrue())
+	g.Expect(r.Heartbeat("Joe", false, t0.Add(10*time.Second))).To(BeFalse())
+	g.Expect(r.Presence["Joe"]).To(Equal(Presence{Online, t0, t0.Add(10 * time.Second)}))
+	g.Expect(r.Heartbeat("Maria", true, t0.Add(10*time.Second))).To(BeTrue())
+	g.Expect(r.Presence["Maria"].Status).To(Equal(Idle))
+
+	// Users without recent heartbeats are disconnected
+	t1 := t0.Add(time.Minute)
+	g.Expect(r.Disconnect(t0.Add(5*time.Second), t1)).To(BeEmpty())
+	g.Expect(r.Heartbeat("Joe", false, t0.Add(20*time.Second))).To(BeFalse())
+	g.Expect(r.Disconnect(t0.Add(15*time.Second), t1)).To(Equal([]string{"Maria"}))
+	g.Expect(r.Presence["Maria"]).To(Equal(Presence{Disconnected, t1, t0.Add(10 * time.Second)}))
+	g.Expect(r.Disconnect(t0.Add(15*time.Second), t1)).To(BeEmpty())
+
+	g.Expect(r.DisconnectedSince(t1)).To(BeEmpty())
+	g.Expect(r.DisconnectedSince(t1.Add(time.Second))).To(Equal([]string{"Maria"}))
+
+	// Reconnecting
+	g.Expect(r.Heartbeat("Maria", false, t1.Add(time.Second))).To(BeTrue())
+	g.Expect(r.Presence["Maria"].Status).To(Equal(Online))
+
+	r.RemoveUser("Maria")
+	r.RemoveUser("Joe")
+	g.Expect(r.Presence).To(BeNil())
+}
@@ -0,0 +1,44 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// tokenLen is the number of random bytes in a session token
+const tokenLen = 32
+
+// Session identifies a user to the API. The server issues each session a
+// random token, which the client presents with its requests, and stores
+// only the token's hash, as the session's ID.
+type Session struct {
+	// ID is the hash of the session's token
+	ID string `json:"id"`
+	// Username is the user the session identifies, claimed by no other
+	// session while this one lasts
+	Username string `json:"username"`
+	// Created is the time the session was issued
+	Created time.Time `json:"created"`
+	// LastActivity is the time the session was last used
+	LastActivity time.Time `json:"lastActivity"`
+}
+
+// NewSession returns a new session for the given user and its random
+// token, which is to be given to the user alone
+func NewSession(username string) (session *Session, token string, err error) {
+	b := make([]byte, tokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token = hex.EncodeToString(b)
+	return &Session{ID: ID(token), Username: username}, token, nil
+}
+
+// ID returns the ID of the session with the given token. Tokens are
+// random, so unlike passwords they need no salt.
+func ID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package sessions
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestNewSession(t *testing.T) {
+	g := NewGomegaWithT(t)
+	s, token, err := NewSession("Joe")
+	g.Expect(err).To(BeNil())
+	g.Expect(s.Username).To(Equal("Joe"))
+	g.Expect(token).To(HaveLen(2 * tokenLen))
+	g.Expect(s.ID).To(Equal(ID(token)))
+	g.Expect(s.ID).NotTo(Equal(token))
+
+	// Each session has its own token
+	s2, token2, err := NewSession("Joe")
+	g.Expect(err).To(BeNil())
+	g.Expect(token2).NotTo(Equal(token))
+	g.Expect(s2.ID).NotTo(Equal(s.ID))
+}
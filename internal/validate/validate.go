@@ -0,0 +1,115 @@
+// Package validate checks and normalizes the user-chosen names used in
+// URLs and shown in the UI: room names and usernames.
+package validate
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Name length limits, in characters
+const (
+	MaxRoomNameLen = 64
+	MaxUsernameLen = 32
+)
+
+// maxValueLen is the most characters of an invalid value quoted in an Error
+const maxValueLen = 64
+
+// Error indicates an invalid name. It marshals to json for clients to
+// show against the offending field.
+type Error struct {
+	// Field is the name of the invalid request field
+	Field string `json:"field"`
+	// Value is the invalid value, truncated if long
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Reason)
+}
+
+// newError returns an Error, truncating value if long
+func newError(field, value, reason string) Error {
+	if utf8.RuneCountInString(value) > maxValueLen {
+		value = string([]rune(value)[:maxValueLen]) + "…"
+	}
+	return Error{field, value, reason}
+}
+
+// RoomName returns the normalized form of the given room name, or an
+// Error if it is not valid
+func RoomName(name string) (string, error) {
+	return check("name", name, MaxRoomNameLen)
+}
+
+// Username returns the normalized form of the given username, or an Error
+// if it is not valid
+func Username(username string) (string, error) {
+	return check("username", username, MaxUsernameLen)
+}
+
+// Usernames returns the normalized forms of the given usernames, or an
+// Error if any is not valid or two are the same but for case
+func Usernames(usernames []string) ([]string, error) {
+	normalized := make([]string, len(usernames))
+	seen := make(map[string]string)
+	for i, u := range usernames {
+		n, err := check("usernames", u, MaxUsernameLen)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[Key(n)]; ok {
+			return nil, newError("usernames", u, fmt.Sprintf("same as %q", prev))
+		}
+		seen[Key(n)] = u
+		normalized[i] = n
+	}
+	return normalized, nil
+}
+
+// Normal returns the name in Unicode normalization form C, the form in
+// which valid names are stored, so a name given in another form is found
+func Normal(name string) string {
+	return norm.NFC.String(name)
+}
+
+// Key returns the form of the given name to compare for uniqueness: names
+// are the same if their keys are equal, regardless of case
+func Key(name string) string {
+	return cases.Fold().String(norm.NFC.String(name))
+}
+
+// check returns name in Unicode normalization form C if it is at most max
+// characters long, starts with a letter or digit and otherwise contains
+// only letters, digits, combining marks, '-', '_' and '.'. Otherwise it
+// returns an Error for the given field.
+func check(field, name string, max int) (string, error) {
+	if !utf8.ValidString(name) {
+		return "", newError(field, name, "not valid UTF-8")
+	}
+	name = norm.NFC.String(name)
+	n := utf8.RuneCountInString(name)
+	if n == 0 {
+		return "", newError(field, name, "empty")
+	}
+	if n > max {
+		return "", newError(field, name, fmt.Sprintf("longer than %d characters", max))
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+		case i == 0:
+			return "", newError(field, name, "must start with a letter or digit")
+		case unicode.Is(unicode.M, r) || r == '-' || r == '_' || r == '.':
+		default:
+			return "", newError(field, name, fmt.Sprintf("contains %q, only letters, digits, '-', '_' and '.' are allowed", r))
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,103 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoomName(t *testing.T) {
+	long := strings.Repeat("a", MaxRoomNameLen+1)
+	tests := []struct {
+		name   string
+		exp    string
+		reason string
+	}{
+		{"r1", "r1", ""},
+		{"My_Room-2.0", "My_Room-2.0", ""},
+		{"café", "café", ""},
+		{"cafe\u0301", "café", ""},
+		{"ゲーム", "ゲーム", ""},
+		{strings.Repeat("a", MaxRoomNameLen), strings.Repeat("a", MaxRoomNameLen), ""},
+		{"", "", "empty"},
+		{long, "", "longer than 64 characters"},
+		{"-r1", "", "must start with a letter or digit"},
+		{"r 1", "", `contains ' ', only letters, digits, '-', '_' and '.' are allowed`},
+		{"r/1", "", `contains '/', only letters, digits, '-', '_' and '.' are allowed`},
+		{"r\xff", "", "not valid UTF-8"},
+	}
+	for _, test := range tests {
+		n, err := RoomName(test.name)
+		if test.reason == "" {
+			if err != nil {
+				t.Errorf("RoomName(%q) returned unexpected err %s", test.name, err)
+			}
+			if n != test.exp {
+				t.Errorf("RoomName(%q) returned %q, expected %q", test.name, n, test.exp)
+			}
+			continue
+		}
+		verr, ok := err.(Error)
+		if !ok {
+			t.Errorf("RoomName(%q) returned err %v, expected an Error", test.name, err)
+			continue
+		}
+		if verr.Field != "name" || verr.Reason != test.reason {
+			t.Errorf("RoomName(%q) returned err %#v, expected field name and reason %q", test.name, verr, test.reason)
+		}
+	}
+}
+
+func TestUsername(t *testing.T) {
+	_, err := Username(strings.Repeat("u", MaxUsernameLen))
+	if err != nil {
+		t.Errorf("Username returned unexpected err %s", err)
+	}
+	long := strings.Repeat("u", 100)
+	_, err = Username(long)
+	exp := Error{"username", strings.Repeat("u", maxValueLen) + "…", "longer than 32 characters"}
+	if err != exp {
+		t.Errorf("Username returned err %#v, expected %#v", err, exp)
+	}
+}
+
+func TestUsernames(t *testing.T) {
+	us, err := Usernames([]string{"ann", "café"})
+	if err != nil {
+		t.Errorf("Usernames returned unexpected err %s", err)
+	}
+	if len(us) != 2 || us[0] != "ann" || us[1] != "café" {
+		t.Errorf("Usernames returned %q, expected [ann café]", us)
+	}
+	_, err = Usernames([]string{"ann", "bob", "ANN"})
+	exp := Error{"usernames", "ANN", `same as "ann"`}
+	if err != exp {
+		t.Errorf("Usernames returned err %#v, expected %#v", err, exp)
+	}
+	_, err = Usernames([]string{"ann", ""})
+	exp = Error{"usernames", "", "empty"}
+	if err != exp {
+		t.Errorf("Usernames returned err %#v, expected %#v", err, exp)
+	}
+}
+
+func TestNormal(t *testing.T) {
+	if Normal("cafe\u0301") != "caf\u00e9" {
+		t.Errorf("Normal(%q) = %q, expected %q", "cafe\u0301", Normal("cafe\u0301"), "caf\u00e9")
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := [][2]string{
+		{"Room", "room"},
+		{"CAFÉ", "café"},
+		{"Straße", "STRASSE"},
+	}
+	for _, test := range tests {
+		if Key(test[0]) != Key(test[1]) {
+			t.Errorf("Key(%q) = %q differs from Key(%q) = %q", test[0], Key(test[0]), test[1], Key(test[1]))
+		}
+	}
+	if Key("r1") == Key("r2") {
+		t.Errorf("Key(%q) equals Key(%q)", "r1", "r2")
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao"
+	"github.com/bbawn/boredgames/internal/games"
+)
+
+// actionLog applies actions to the games of a service and records their
+// results, so each game's log replays it. Every change to a game but its
+// creation goes through the log, including the ends of rrobots countdowns;
+// each game records the seed it was created with instead.
+type actionLog struct {
+	dao dao.Actions
+	// locks serialize the actions on each game, so its log is in the order
+	// they were applied and client versions are checked against the
+	// latest, keyed on the game ID
+	locks keyLocks
+}
+
+// maxFailedResults is the most failed results recorded in a row for a
+// game. A failed action changes nothing, so later failures are returned
+// but not recorded, lest a client retrying one grow the log without bound.
+const maxFailedResults = 20
+
+// updateFunc applies the given update, as of the current time, to the game
+// with the given ID, then saves and publishes the updated game
+type updateFunc func(id uuid.UUID, update func(g games.Game, now time.Time) error) (games.Game, error)
+
+// apply seeds the action, applies it with the given update function to the
+// game with the given ID and records its result, as games.Type Apply
+// does. The game's version is the Version of its latest result, or 0 if it
+// has none.
+func (l *actionLog) apply(id uuid.UUID, a games.Action, update updateFunc) (*games.Result, games.Game, error) {
+	defer l.locks.lock(id.String())()
+	return l.record(id, a, update)
+}
+
+// record is apply for a caller already holding the lock on the game's log
+func (l *actionLog) record(id uuid.UUID, a games.Action, update updateFunc) (*games.Result, games.Game, error) {
+	results, err := l.dao.List(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	version := 0
+	if len(results) > 0 {
+		version = results[len(results)-1].Version
+	}
+	if a.ClientVersion != nil && *a.ClientVersion != version {
+		return nil, nil, conflictError{fmt.Sprintf("client version %d is not the game version %d", *a.ClientVersion, version)}
+	}
+
+	a.Seed = rand.Int63()
+	result := &games.Result{GameID: id, Action: a, Version: version}
+	var applyErr error
+	game, err := update(id, func(g games.Game, now time.Time) error {
+		result.Time = now
+		applyErr = g.Apply(a, now)
+		return applyErr
+	})
+	if err != nil && applyErr == nil {
+		return nil, nil, err
+	}
+	if applyErr != nil {
+		result.Error = applyErr.Error()
+		if failedResults(results) >= maxFailedResults {
+			return result, game, applyErr
+		}
+	} else {
+		result.Version++
+	}
+	err = l.dao.Insert(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, game, applyErr
+}
+
+// failedResults returns the number of failed results at the end of the
+// given log
+func failedResults(results []*games.Result) int {
+	n := 0
+	for i := len(results) - 1; i >= 0 && !results[i].Succeeded(); i-- {
+		n++
+	}
+	return n
+}
+
+// newAction returns the action of the given type and player with the
+// given payload, unless it is nil
+func newAction(actionType, player string, payload interface{}) (games.Action, error) {
+	a := games.Action{Type: actionType, Player: player}
+	if payload == nil {
+		return a, nil
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return a, err
+	}
+	a.Payload = b
+	return a, nil
+}
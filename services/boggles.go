@@ -0,0 +1,453 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao"
+	"github.com/bbawn/boredgames/internal/dictionary"
+	"github.com/bbawn/boredgames/internal/events"
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/games/boggle"
+	"github.com/bbawn/boredgames/internal/router"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// Boggles provides the REST API for the Boggle word game
+type Boggles struct {
+	dao dao.Boggles
+	// dicts are the dictionaries words are checked against
+	dicts *dictionary.Registry
+	// events publishes updated games to their event stream subscribers,
+	// keyed on game ID and viewer role
+	events *events.Broker
+	// actions apply and record every change to the games
+	actions *actionLog
+	// rooms are where the games may be played, whose access rules apply
+	// to them
+	rooms dao.Rooms
+}
+
+// BogglesAddRoutes adds the routes for this service to the given router and
+// returns the service
+func BogglesAddRoutes(dao dao.Boggles, actions dao.Actions, rms dao.Rooms, dicts *dictionary.Registry, router *router.TableRouter) *Boggles {
+	b := &Boggles{dao: dao, dicts: dicts, events: events.NewBroker(), actions: &actionLog{dao: actions}, rooms: rms}
+	router.AddRoute("GET", "/boggles", http.HandlerFunc(b.List))
+	router.AddRoute("POST", "/boggles", http.HandlerFunc(b.Create))
+	router.AddRoute("GET", "/boggles/([^/]+)", http.HandlerFunc(b.Get))
+	router.AddRoute("DEL", "/boggles/([^/]+)", http.HandlerFunc(b.Delete))
+	router.AddRoute("POST", "/boggles/([^/]+)/words", http.HandlerFunc(b.SubmitWord))
+	router.AddRoute("POST", "/boggles/([^/]+)/letters", http.HandlerFunc(b.PlaceLetter))
+	router.AddRoute("POST", "/boggles/([^/]+)/end", http.HandlerFunc(b.EndRound))
+	router.AddRoute("POST", "/boggles/([^/]+)/next", http.HandlerFunc(b.Next))
+	router.AddRoute("GET", "/boggles/([^/]+)/events", http.HandlerFunc(b.Events))
+	router.AddRoute("POST", "/boggles/([^/]+)/players", http.HandlerFunc(b.AddPlayer))
+	router.AddRoute("DEL", "/boggles/([^/]+)/players", http.HandlerFunc(b.DeletePlayer))
+	router.AddRoute("POST", "/boggles/([^/]+)/spectators", http.HandlerFunc(b.AddSpectator))
+	router.AddRoute("DEL", "/boggles/([^/]+)/spectators", http.HandlerFunc(b.DeleteSpectator))
+	return b
+}
+
+// boggleView returns the projection of the game presented to the client
+// making the request: the full game for admins, otherwise the View for the
+// requesting user, which hides the other players' words during a round
+func boggleView(game *boggle.Game, r *http.Request) interface{} {
+	if requestRole(r) == AdminRole {
+		return game
+	}
+	return game.View(requestUsername(r))
+}
+
+// publish sends the given updated game to its event stream subscribers:
+// the full game to admins and the View for no player to everyone else
+func (b *Boggles) publish(game *boggle.Game) {
+	views := map[Role]interface{}{PublicRole: game.View(""), AdminRole: game}
+	for role, view := range views {
+		data, err := json.Marshal(view)
+		if err != nil {
+			log.Printf("WARN: failed to encode boggle game %s for publish: %s", game.ID, err)
+			return
+		}
+		b.events.Publish(eventTopic(game.ID, role), data)
+	}
+}
+
+func (b *Boggles) List(w http.ResponseWriter, r *http.Request) {
+	games, err := b.dao.List()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load games from datastore: %s", err), httpStatus(err))
+		return
+	}
+	// Games in rooms the client may not view are hidden from it
+	hidden, err := hiddenGames(r, b.rooms)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load rooms from datastore: %s", err), httpStatus(err))
+		return
+	}
+	visible := games[:0]
+	for _, game := range games {
+		if !hidden[game.ID] {
+			visible = append(visible, game)
+		}
+	}
+	games = visible
+	enc := json.NewEncoder(w)
+	views := make([]interface{}, len(games))
+	for i, game := range games {
+		views[i] = boggleView(game, r)
+	}
+	err = enc.Encode(views)
+	if err != nil {
+		m := fmt.Sprintf("Failed to encode games from datastore: %s", err)
+		http.Error(w, m, http.StatusInternalServerError)
+		return
+	}
+}
+
+// boggleOptions are the settings of a new boggle game
+type boggleOptions struct {
+	// Language is the code of the language to play in, English if empty
+	Language string `json:"language"`
+	// Size is the number of rows, and of columns, of the board, 4 if 0
+	Size int         `json:"size"`
+	Mode boggle.Mode `json:"mode"`
+}
+
+// boggleCreateData is the payload of the create boggle game request
+type boggleCreateData struct {
+	Usernames []string `json:"usernames"`
+	boggleOptions
+}
+
+// newGame returns a new game with the given options and players, dealing
+// reverse game clues from the dictionary of its language
+func (b *Boggles) newGame(bo boggleOptions, usernames []string) (*boggle.Game, error) {
+	opts := boggle.Options{Language: bo.Language, Size: bo.Size, Mode: bo.Mode}
+	if opts.Language == "" {
+		opts.Language = dictionary.DefaultLanguage
+	}
+	// An unsupported language is reported by NewGameOptions
+	if dict, err := b.dicts.Get(opts.Language); err == nil {
+		opts.Lexicon = dict
+	}
+	return boggle.NewGameOptions(opts, usernames...)
+}
+
+func (b *Boggles) Create(w http.ResponseWriter, r *http.Request) {
+	var cd boggleCreateData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&cd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal create data: %s", err), http.StatusBadRequest)
+		return
+	}
+	game, err := b.newGame(cd.boggleOptions, cd.Usernames)
+	if err != nil {
+		if _, ok := err.(validate.Error); !ok {
+			err = badRequestError{err.Error()}
+		}
+		httpError(w, fmt.Sprintf("Failed to create new game: %s", err), err)
+		return
+	}
+	err = b.dao.Insert(game)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to insert game into datastore: %s", err), httpStatus(err))
+		return
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(boggleView(game, r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode new game: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (b *Boggles) Get(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid boggle uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	_, err = viewRoom(r, b.rooms, uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game: %s", err), httpStatus(err))
+		return
+	}
+	game, err := b.dao.Get(uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
+		return
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(boggleView(game, r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode game from datastore: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (b *Boggles) Delete(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid boggle uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	err = deletable(b.rooms, uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete game: %s", err), httpStatus(err))
+		return
+	}
+	err = b.dao.Delete(uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete game from datastore: %s", err), httpStatus(err))
+		return
+	}
+}
+
+// wordData is the payload of the submit word request
+type wordData struct {
+	Username string `json:"username"`
+	Word     string `json:"word"`
+}
+
+// SubmitWord submits a word found by a player in the current round, which
+// must be in the dictionary of the game's language
+func (b *Boggles) SubmitWord(w http.ResponseWriter, r *http.Request) {
+	var wd wordData
+	b.update(w, r, &wd, func() (games.Action, error) {
+		return newAction(boggle.WordAction, wd.Username, boggle.WordPayload{Word: wd.Word})
+	})
+}
+
+// letterData is the payload of the place letter request
+type letterData struct {
+	Username string `json:"username"`
+	Row      int    `json:"row"`
+	Col      int    `json:"col"`
+	// Face is the die face to place, "" to blank the cell
+	Face string `json:"face"`
+}
+
+// PlaceLetter places a die face on the board of a reverse game
+func (b *Boggles) PlaceLetter(w http.ResponseWriter, r *http.Request) {
+	var ld letterData
+	b.update(w, r, &ld, func() (games.Action, error) {
+		return newAction(boggle.LetterAction, ld.Username, boggle.LetterPayload{Row: ld.Row, Col: ld.Col, Face: ld.Face})
+	})
+}
+
+// EndRound ends and scores the current round and reveals all the words on
+// its board
+func (b *Boggles) EndRound(w http.ResponseWriter, r *http.Request) {
+	b.update(w, r, nil, func() (games.Action, error) {
+		return newAction(boggle.EndAction, requestUsername(r), nil)
+	})
+}
+
+// Next starts the next round on a newly rolled board, or a blank one with
+// new clues for a reverse game
+func (b *Boggles) Next(w http.ResponseWriter, r *http.Request) {
+	b.update(w, r, nil, func() (games.Action, error) {
+		return newAction(boggle.NextAction, requestUsername(r), nil)
+	})
+}
+
+// Events streams the game to the client as server-sent events: its current
+// state, then its new state after each change
+func (b *Boggles) Events(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid boggle uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	_, err = viewRoom(r, b.rooms, uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to follow game: %s", err), httpStatus(err))
+		return
+	}
+	// Subscribe before Get so no update between them is missed
+	ch, cancel := b.events.Subscribe(eventTopic(uuid, requestRole(r)))
+	defer cancel()
+	game, err := b.dao.Get(uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
+		return
+	}
+	var view interface{} = game.View("")
+	if requestRole(r) == AdminRole {
+		view = game
+	}
+	initial, err := json.Marshal(view)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode game from datastore: %s", err), http.StatusInternalServerError)
+		return
+	}
+	serveEvents(w, r, ch, initial)
+}
+
+// AddPlayer adds a player to the game
+func (b *Boggles) AddPlayer(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	b.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.JoinAction, md.Username, nil)
+	})
+}
+
+// DeletePlayer removes a player from the game
+func (b *Boggles) DeletePlayer(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	b.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.LeaveAction, md.Username, nil)
+	})
+}
+
+// AddSpectator adds a spectator to the game
+func (b *Boggles) AddSpectator(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	b.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.WatchAction, md.Username, nil)
+	})
+}
+
+// DeleteSpectator removes a spectator from the game
+func (b *Boggles) DeleteSpectator(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	b.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.UnwatchAction, md.Username, nil)
+	})
+}
+
+// update decodes the request payload into data, unless it is nil, then
+// applies the action made from it to the requested game through the
+// game's log of actions, and replies with the updated game. The action is
+// played by the user making the request unless it names its player, and
+// is applied only once authorize allows it, as through Games.
+func (b *Boggles) update(w http.ResponseWriter, r *http.Request, data interface{}, action func() (games.Action, error)) {
+	uuid, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid boggle uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	if data != nil {
+		dec := json.NewDecoder(r.Body)
+		err = dec.Decode(data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to unmarshal request data: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+	a, err := action()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode action: %s", err), http.StatusInternalServerError)
+		return
+	}
+	if a.Player == "" {
+		a.Player = requestUsername(r)
+	}
+	a.Player = validate.Normal(a.Player)
+	game, err := boggleType{b}.Get(uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = authorize(r, b.rooms, uuid, game, a)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update game: %s", err), httpStatus(err))
+		return
+	}
+	_, game, err = b.actions.apply(uuid, a, boggleType{b}.update)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to update game: %s", err), err)
+		return
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(boggleView(game.(boggle.Generic).Game, r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode updated game: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// boggleType is the games.Type of the boggle games of the service
+type boggleType struct {
+	b *Boggles
+}
+
+// GameType returns the games.Type rooms host the service's games through
+func (b *Boggles) GameType() games.Type {
+	return boggleType{b}
+}
+
+// Create creates a boggle game with the options of the create request
+func (t boggleType) Create(usernames []string, options json.RawMessage) (games.Game, error) {
+	var bo boggleOptions
+	if len(options) > 0 {
+		err := json.Unmarshal(options, &bo)
+		if err != nil {
+			return nil, badRequestError{fmt.Sprintf("invalid options: %s", err)}
+		}
+	}
+	game, err := t.b.newGame(bo, usernames)
+	if err != nil {
+		return nil, err
+	}
+	err = t.b.dao.Insert(game)
+	if err != nil {
+		return nil, err
+	}
+	return t.generic(game)
+}
+
+func (t boggleType) Get(id uuid.UUID) (games.Game, error) {
+	game, err := t.b.dao.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return t.generic(game)
+}
+
+// Apply applies the action through the service's log of actions
+func (t boggleType) Apply(id uuid.UUID, a games.Action) (*games.Result, games.Game, error) {
+	return t.b.actions.apply(id, a, t.update)
+}
+
+// update is the updateFunc of the service's games
+func (t boggleType) update(id uuid.UUID, update func(games.Game, time.Time) error) (games.Game, error) {
+	game, err := t.b.dao.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	g, err := t.generic(game)
+	if err != nil {
+		return nil, err
+	}
+	err = update(g, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	err = t.b.dao.Update(game)
+	if err != nil {
+		return nil, err
+	}
+	t.b.publish(game)
+	return g, nil
+}
+
+func (t boggleType) Delete(id uuid.UUID) error {
+	return t.b.dao.Delete(id)
+}
+
+// generic returns the game adapted to games.Game with the dictionary of
+// its language
+func (t boggleType) generic(game *boggle.Game) (games.Game, error) {
+	dict, err := t.b.dicts.Get(game.Language)
+	if err != nil {
+		return nil, err
+	}
+	return boggle.Generic{Game: game, Lexicon: dict}, nil
+}
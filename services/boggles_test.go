@@ -0,0 +1,211 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/dictionary"
+	"github.com/bbawn/boredgames/internal/games/boggle"
+	"github.com/bbawn/boredgames/internal/router"
+)
+
+func TestBoggles(t *testing.T) {
+	g := NewGomegaWithT(t)
+	dicts, err := dictionary.NewRegistry()
+	g.Expect(err).To(BeNil())
+	tr := new(router.TableRouter)
+	BogglesAddRoutes(ram.NewBoggles(), ram.NewActions(), ram.NewRooms(), dicts, tr)
+	h := AdminAuth("secret", tr)
+
+	t.Log("List with no games")
+	resp := doRequest(h, "GET", "http://example.com/boggles", nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(strings.TrimSpace(string(body))).To(Equal("[]"))
+
+	t.Log("Create a game with invalid payloads")
+	resp = doRequest(h, "POST", "http://example.com/boggles", nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(HavePrefix("Failed to unmarshal create data:"))
+	resp = doRequest(h, "POST", "http://example.com/boggles", bytes.NewReader([]byte(`{ "usernames": ["p1", "P1"] }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to create new game: Invalid value: P1 already present as p1 for arg: username\n"))
+	resp = doRequest(h, "POST", "http://example.com/boggles", bytes.NewReader([]byte(`{ "usernames": ["p1"], "language": "xx" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to create new game: Invalid value: xx for arg: language\n"))
+	resp = doRequest(h, "POST", "http://example.com/boggles", bytes.NewReader([]byte(`{ "usernames": ["p1"], "size": 3 }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to create new game: Invalid value: 3 has no English dice for arg: size\n"))
+
+	t.Log("Create a Big Boggle game")
+	resp = doRequest(h, "POST", "http://example.com/boggles", bytes.NewReader([]byte(`{ "usernames": ["p1"], "size": 5 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var big *boggle.View
+	g.Expect(json.NewDecoder(resp.Body).Decode(&big)).To(Succeed())
+	g.Expect(big.Size).To(Equal(5))
+	g.Expect(big.Board.Size()).To(Equal(5))
+	resp = doRequest(h, "DEL", "http://example.com/boggles/"+big.ID.String(), nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Play a reverse game")
+	resp = doRequest(h, "POST", "http://example.com/boggles", bytes.NewReader([]byte(`{ "usernames": ["p1"], "mode": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var rev *boggle.View
+	g.Expect(json.NewDecoder(resp.Body).Decode(&rev)).To(Succeed())
+	g.Expect(rev.Mode).To(Equal(boggle.Reverse))
+	g.Expect(rev.Target).To(BeNil())
+	g.Expect(rev.Board[0][0]).To(Equal(""))
+	revTarget := "http://example.com/boggles/" + rev.ID.String()
+	resp = doUserRequest(h, "POST", revTarget+"/letters", "p1", bytes.NewReader([]byte(`{ "username": "p1", "row": 0, "col": 0, "face": "a" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&rev)).To(Succeed())
+	g.Expect(rev.Board[0][0]).To(Equal("A"))
+	resp = doUserRequest(h, "POST", revTarget+"/letters", "p1", bytes.NewReader([]byte(`{ "username": "p1", "row": 0, "col": 9, "face": "a" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid value: 0,9 is off the board for arg: cell\n"))
+	resp = doUserRequest(h, "POST", revTarget+"/words", "p1", bytes.NewReader([]byte(`{ "username": "p1", "word": "cat" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	resp = doUserRequest(h, "POST", revTarget+"/end", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&rev)).To(Succeed())
+	g.Expect(rev.Target.Size()).To(Equal(4))
+	resp = doRequest(h, "DEL", revTarget, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Create a game")
+	resp = doRequest(h, "POST", "http://example.com/boggles", bytes.NewReader([]byte(`{ "usernames": ["p1", "p2"] }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var v *boggle.View
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Players).To(HaveLen(2))
+	g.Expect(v.Round).To(Equal(1))
+	g.Expect(v.State).To(Equal(boggle.Playing))
+	g.Expect(v.Board.Size()).To(Equal(4))
+	g.Expect(v.Language).To(Equal("en"))
+	target := "http://example.com/boggles/" + v.ID.String()
+
+	t.Log("Get non-existent and invalid games")
+	resp = doRequest(h, "GET", "http://example.com/boggles/"+"6ba7b810-9dad-11d1-80b4-00c04fd430c8", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	resp = doRequest(h, "GET", "http://example.com/boggles/foo", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	t.Log("Submit words")
+	// The first three faces of the top row are always a path on the board:
+	// make them a word
+	word := strings.Join(v.Board[0][:3], "")
+	dir, err := ioutil.TempDir("", "boggles")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "en.txt"), []byte(word+"\n"), 0644)).To(Succeed())
+	g.Expect(dicts.LoadDir(dir)).To(Succeed())
+	submit := func(username, word string) *http.Response {
+		d := fmt.Sprintf(`{ "username": %q, "word": %q }`, username, word)
+		return doUserRequest(h, "POST", target+"/words", username, bytes.NewReader([]byte(d)))
+	}
+	resp = submit("p1", word)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Players["p1"].Words).To(Equal([]string{strings.ToUpper(word)}))
+	resp = submit("p1", word)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Failed to update game: Invalid value: %s already submitted for arg: word\n", strings.ToUpper(word))))
+	resp = submit("p3", word)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = submit("p1", strings.Join(v.Board[0][:4], ""))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(HaveSuffix("is not in the dictionary for arg: word\n"))
+	resp = doRequest(h, "POST", target+"/words", bytes.NewReader([]byte(`foo`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(HavePrefix("Failed to unmarshal request data:"))
+
+	t.Log("Submit words without a session or as another player")
+	d := fmt.Sprintf(`{ "username": "p1", "word": %q }`, word)
+	resp = doRequest(h, "POST", target+"/words", bytes.NewReader([]byte(d)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(string(body)).To(Equal("Failed to update game: no session in request\n"))
+	resp = doUserRequest(h, "POST", target+"/words", "p2", bytes.NewReader([]byte(d)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to update game: user \"p2\" may not act as player \"p1\"\n"))
+
+	t.Log("Other players' words are hidden during the round")
+	resp = submit("p2", word)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Players["p1"].Words).To(BeNil())
+	g.Expect(v.Players["p1"].WordCount).To(Equal(1))
+	g.Expect(v.Players["p2"].Words).To(HaveLen(1))
+	resp = doAuthRequest(h, "GET", target, "Bearer secret", nil)
+	var game *boggle.Game
+	g.Expect(json.NewDecoder(resp.Body).Decode(&game)).To(Succeed())
+	g.Expect(game.Players["p1"].Words).To(HaveLen(1))
+
+	t.Log("Next round before the round ends")
+	resp = doUserRequest(h, "POST", target+"/next", "p1", nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid method: NextRound detail: round is not over\n"))
+
+	t.Log("End the round, cancelling the word both players found")
+	resp = doUserRequest(h, "POST", target+"/end", "p2", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.State).To(Equal(boggle.RoundOver))
+	g.Expect(v.Cancelled).To(Equal([]string{strings.ToUpper(word)}))
+	g.Expect(v.Solution).To(ContainElement(strings.ToUpper(word)))
+	g.Expect(v.Players["p1"].Words).To(HaveLen(1))
+	g.Expect(v.Players["p1"].Score).To(Equal(0))
+	resp = submit("p1", word)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+	t.Log("Start the next round")
+	resp = doUserRequest(h, "POST", target+"/next", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Round).To(Equal(2))
+	g.Expect(v.State).To(Equal(boggle.Playing))
+
+	t.Log("Add and remove players and spectators")
+	d = `{ "username": "p3" }`
+	resp = doUserRequest(h, "POST", target+"/spectators", "p3", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "POST", target+"/players", "p3", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Players).To(HaveKey("p3"))
+	g.Expect(v.Spectators).NotTo(HaveKey("p3"))
+	resp = doUserRequest(h, "DEL", target+"/players", "p3", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "DEL", target+"/spectators", "p3", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	resp = doAuthRequest(h, "POST", target+"/players", "Bearer secret", bytes.NewReader([]byte(`{ "username": "" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to update game: action must have a player\n"))
+
+	t.Log("Delete the game")
+	resp = doRequest(h, "DEL", target, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doRequest(h, "GET", target, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bbawn/boredgames/internal/rooms"
+	"github.com/bbawn/boredgames/internal/router"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// Chat history page sizes
+const (
+	defaultMessagesLimit = 50
+	maxMessagesLimit     = 200
+)
+
+// Room event types
+const (
+	roomEventRoom    = "room"
+	roomEventMessage = "message"
+)
+
+// roomEvent is the data of an event on a room's event stream
+type roomEvent struct {
+	// Type is the kind of event, determining which other field is set
+	Type    string         `json:"type"`
+	Room    *rooms.Room    `json:"room,omitempty"`
+	Message *rooms.Message `json:"message,omitempty"`
+}
+
+// publish publishes the updated room to its event stream subscribers
+func (rms *Rooms) publish(room *rooms.Room) {
+	rms.publishEvent(room.Name, roomEvent{Type: roomEventRoom, Room: room.Redacted()})
+}
+
+// publishEvent publishes the event to the subscribers of the named room.
+// The topic is the name's validate.Key, as any case variant names the room.
+func (rms *Rooms) publishEvent(name string, ev roomEvent) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("WARN: failed to encode %s event for room %s: %s", ev.Type, name, err)
+		return
+	}
+	rms.events.Publish(validate.Key(name), b)
+}
+
+// canView returns a forbiddenError unless the client making the request
+// may follow the room's chat. Anyone may follow a public room; only admins
+// and users in a private one may follow it.
+func canView(r *http.Request, room *rooms.Room) error {
+	username := requestUsername(r)
+	if !room.Private || requestRole(r) == AdminRole || room.Role(username) != "" || room.Waiting(username) {
+		return nil
+	}
+	return forbiddenError{fmt.Sprintf("user %q is not in private room %s", username, room.Name)}
+}
+
+// Events streams the room to the client as server-sent events: its
+// current state, then its new state after each change and each chat
+// message posted
+func (rms *Rooms) Events(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	// Subscribe before Get so no update between them is missed
+	ch, cancel := rms.events.Subscribe(validate.Key(name))
+	defer cancel()
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = canView(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to follow room: %s", err), httpStatus(err))
+		return
+	}
+	initial, err := json.Marshal(roomEvent{Type: roomEventRoom, Room: room.Redacted()})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode room from datastore: %s", err), http.StatusInternalServerError)
+		return
+	}
+	serveEvents(w, r, ch, initial)
+}
+
+// messageData is the payload of the post message request
+type messageData struct {
+	Text string `json:"text"`
+}
+
+// PostMessage posts a chat message from the requesting user, who must be
+// in the room
+func (rms *Rooms) PostMessage(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	var md messageData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&md)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal message data: %s", err), http.StatusBadRequest)
+		return
+	}
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	username := requestUsername(r)
+	if username == "" || (room.Role(username) == "" && !room.Waiting(username)) {
+		m := fmt.Sprintf("user %q is not in room %s", username, name)
+		http.Error(w, fmt.Sprintf("Failed to post message: %s", m), http.StatusForbidden)
+		return
+	}
+	msg, err := rooms.NewMessage(room.Name, username, md.Text)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid message: %s", err), httpStatus(err))
+		return
+	}
+	if !rms.chatLimiter.allow(validate.Key(room.Name)+"/"+username, time.Now()) {
+		m := fmt.Sprintf("user %q may post at most %d messages per %s", username, chatRateLimit, chatRateWindow)
+		http.Error(w, fmt.Sprintf("Failed to post message: %s", m), http.StatusTooManyRequests)
+		return
+	}
+	err = rms.messages.Insert(msg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to insert message into datastore: %s", err), httpStatus(err))
+		return
+	}
+	rms.publishEvent(room.Name, roomEvent{Type: roomEventMessage, Message: msg})
+	enc := json.NewEncoder(w)
+	err = enc.Encode(msg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode new message: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// messagesData is the payload of the list messages response
+type messagesData struct {
+	// Messages are the page of messages, oldest first
+	Messages []*rooms.Message `json:"messages"`
+	// Before is the before parameter to get the previous page of messages,
+	// or 0 if there are none
+	Before int64 `json:"before,omitempty"`
+}
+
+// ListMessages returns a page of the room's chat history: the latest
+// messages, or with the before query parameter the latest with lower IDs,
+// up to the limit query parameter
+func (rms *Rooms) ListMessages(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	var (
+		before int64
+		limit  = defaultMessagesLimit
+		err    error
+	)
+	q := r.URL.Query()
+	if v := q.Get("before"); v != "" {
+		before, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || before < 1 {
+			http.Error(w, fmt.Sprintf("Invalid before: %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxMessagesLimit {
+			http.Error(w, fmt.Sprintf("Invalid limit: %q, must be 1 to %d", v, maxMessagesLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = canView(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list messages: %s", err), httpStatus(err))
+		return
+	}
+	msgs, err := rms.messages.List(room.Name, before, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load messages from datastore: %s", err), httpStatus(err))
+		return
+	}
+	md := messagesData{Messages: msgs}
+	if len(msgs) == limit && msgs[0].ID > 1 {
+		md.Before = msgs[0].ID
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(md)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode messages: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
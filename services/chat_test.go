@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/rooms"
+	"github.com/bbawn/boredgames/internal/router"
+)
+
+func TestRoomChat(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(ram.NewSets(), ram.NewActions(), daoRooms, tr)), tr)
+	srv := httptest.NewServer(tr)
+	defer srv.Close()
+
+	d := `{ "name": "n1", "usernames": {"p1": true, "p2": true} }`
+	resp := doUserRequest(tr, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Subscribe to the room's events")
+	events := subscribe(t, srv.URL+"/rooms/n1/events")
+	defer events.Close()
+	var ev *roomEvent
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.Type).To(Equal(roomEventRoom))
+	g.Expect(ev.Room.Name).To(Equal("n1"))
+
+	t.Log("Only users in the room may post")
+	post := func(username, text string) *http.Response {
+		b, _ := json.Marshal(messageData{text})
+		return doUserRequest(tr, "POST", "http://example.com/rooms/n1/messages", username, bytes.NewReader(b))
+	}
+	resp = post("p9", "hi")
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to post message: user \"p9\" is not in room n1\n"))
+	resp = post("", "hi")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+	t.Log("Messages must be non-empty and not too long")
+	resp = post("p1", "  ")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	resp = post("p1", strings.Repeat("é", rooms.MaxMessageLen+1))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Invalid message: message text has 501 characters, more than the 500 allowed\n"))
+	resp = post("p1", strings.Repeat("é", rooms.MaxMessageLen))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Posted messages are delivered live")
+	resp = post("p2", " hello ")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var msg *rooms.Message
+	g.Expect(json.NewDecoder(resp.Body).Decode(&msg)).To(Succeed())
+	g.Expect(msg.ID).To(Equal(int64(2)))
+	g.Expect(msg.Username).To(Equal("p2"))
+	g.Expect(msg.Text).To(Equal("hello"))
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.Type).To(Equal(roomEventMessage))
+	g.Expect(ev.Message.ID).To(Equal(int64(1)))
+	ev = nil
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.Message).To(Equal(msg))
+
+	t.Log("Room changes are delivered live")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/players", "p3", bytes.NewReader([]byte(`{ "username": "p3" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	ev = nil
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.Type).To(Equal(roomEventRoom))
+	g.Expect(ev.Room.Usernames).To(HaveKey("p3"))
+
+	t.Log("Users posting too fast are rate limited")
+	for i := 0; i < chatRateLimit-1; i++ {
+		resp = post("p2", fmt.Sprintf("msg %d", i))
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+	resp = post("p2", "one too many")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+	resp = post("p3", "someone else")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("History is paginated, oldest first")
+	list := func(query string) (*http.Response, messagesData) {
+		var md messagesData
+		resp := doRequest(tr, "GET", "http://example.com/rooms/n1/messages"+query, nil)
+		if resp.StatusCode == http.StatusOK {
+			g.Expect(json.NewDecoder(resp.Body).Decode(&md)).To(Succeed())
+		}
+		return resp, md
+	}
+	resp, md := list("")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(md.Messages).To(HaveLen(7))
+	g.Expect(md.Before).To(BeZero())
+	g.Expect(md.Messages[6].Text).To(Equal("someone else"))
+	_, md = list("?limit=3")
+	g.Expect(md.Messages).To(HaveLen(3))
+	g.Expect(md.Messages[0].ID).To(Equal(int64(5)))
+	g.Expect(md.Before).To(Equal(int64(5)))
+	_, md = list(fmt.Sprintf("?limit=3&before=%d", md.Before))
+	g.Expect(md.Messages).To(HaveLen(3))
+	g.Expect(md.Messages[0].ID).To(Equal(int64(2)))
+	_, md = list(fmt.Sprintf("?limit=3&before=%d", md.Before))
+	g.Expect(md.Messages).To(HaveLen(1))
+	g.Expect(md.Before).To(BeZero())
+	resp, _ = list("?limit=0")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	resp, _ = list("?before=x")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+	t.Log("Deleting the room deletes its history")
+	resp = doUserRequest(tr, "DEL", "http://example.com/rooms/n1", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp, _ = list("")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+}
+
+func TestPrivateRoomChat(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(ram.NewSets(), ram.NewActions(), daoRooms, tr)), tr)
+	h := AdminAuth("secret", tr)
+
+	d := `{ "name": "n1", "usernames": {"p1": true}, "private": true }`
+	resp := doUserRequest(h, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/messages", "p1", bytes.NewReader([]byte(`{ "text": "psst" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	resp = doUserRequest(h, "GET", "http://example.com/rooms/n1/messages", "p2", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "GET", "http://example.com/rooms/n1/events", "p2", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "GET", "http://example.com/rooms/n1/messages", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doAuthRequest(h, "GET", "http://example.com/rooms/n1/messages", "Bearer secret", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var md messagesData
+	g.Expect(json.NewDecoder(resp.Body).Decode(&md)).To(Succeed())
+	g.Expect(md.Messages).To(HaveLen(1))
+}
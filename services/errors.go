@@ -1,25 +1,110 @@
 package services
 
 import (
+	"encoding/json"
 	"net/http"
 
 	daoerr "github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/dictionary"
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/games/boggle"
+	"github.com/bbawn/boredgames/internal/games/rrobots"
 	"github.com/bbawn/boredgames/internal/games/set"
+	"github.com/bbawn/boredgames/internal/rooms"
+	"github.com/bbawn/boredgames/internal/validate"
 )
 
+// badRequestError indicates a request is invalid, either in itself or
+// given the state of the resources it refers to
+type badRequestError struct {
+	details string
+}
+
+func (e badRequestError) Error() string {
+	return e.details
+}
+
+// unauthorizedError indicates the request must be made in a session
+type unauthorizedError struct {
+	details string
+}
+
+func (e unauthorizedError) Error() string {
+	return e.details
+}
+
+// forbiddenError indicates the client may not make the request
+type forbiddenError struct {
+	details string
+}
+
+func (e forbiddenError) Error() string {
+	return e.details
+}
+
+// conflictError indicates a request conflicts with the current state of
+// the resource it refers to
+type conflictError struct {
+	details string
+}
+
+func (e conflictError) Error() string {
+	return e.details
+}
+
 func httpStatus(err error) int {
 	switch err.(type) {
+	case badRequestError:
+		return http.StatusBadRequest
+	case unauthorizedError:
+		return http.StatusUnauthorized
+	case forbiddenError:
+		return http.StatusForbidden
+	case conflictError:
+		return http.StatusConflict
 	case daoerr.AlreadyExistsError:
 		return http.StatusConflict
 	case daoerr.InternalError:
 		return http.StatusInternalServerError
 	case daoerr.NotFoundError:
 		return http.StatusNotFound
-	case set.InvalidArgError:
+	case set.InvalidArgError, set.CardSyntaxError, set.CardAttrError:
+		return http.StatusBadRequest
+	case boggle.InvalidArgError, dictionary.LanguageError:
+		return http.StatusBadRequest
+	case rrobots.InvalidArgError:
+		return http.StatusBadRequest
+	case games.ActionError, games.TypeError:
 		return http.StatusBadRequest
-	case set.InvalidStateError:
+	case rooms.MessageError, validate.Error:
+		return http.StatusBadRequest
+	case set.InvalidStateError, boggle.InvalidStateError, rrobots.InvalidStateError:
 		return http.StatusConflict
 	default:
 		return http.StatusInternalServerError
 	}
 }
+
+// errorData is the json body of the response to a request with an invalid
+// name
+type errorData struct {
+	Error  string `json:"error"`
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// httpError replies to the request with the given message and the status
+// for err, like http.Error. Invalid names are replied to with errorData,
+// so clients can show the reason against the offending field.
+func httpError(w http.ResponseWriter, msg string, err error) {
+	verr, ok := err.(validate.Error)
+	if !ok {
+		http.Error(w, msg, httpStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(errorData{msg, verr.Field, verr.Value, verr.Reason})
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// serveEvents streams initial, then each message received on ch, to the
+// client as server-sent events until the client disconnects or ch is
+// closed
+func serveEvents(w http.ResponseWriter, r *http.Request, ch <-chan []byte, initial []byte) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported by connection", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprintf(w, "data: %s\n\n", initial)
+	f.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", msg)
+			f.Flush()
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// eventStream is a client connection to a server-sent event stream
+type eventStream struct {
+	resp *http.Response
+	r    *bufio.Reader
+}
+
+// subscribe opens the server-sent event stream at url
+func subscribe(t *testing.T, url string) *eventStream {
+	return subscribeAuth(t, url, "")
+}
+
+// subscribeAuth is subscribe with the given Authorization header, if
+// non-empty
+func subscribeAuth(t *testing.T, url, auth string) *eventStream {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("Unexpected NewRequest err %s", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected err %s subscribing to %s", err, url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status %d subscribing to %s", resp.StatusCode, url)
+	}
+	return &eventStream{resp, bufio.NewReader(resp.Body)}
+}
+
+// Close closes the event stream
+func (es *eventStream) Close() {
+	es.resp.Body.Close()
+}
+
+// nextEvent returns the data of the next event on the stream, failing the
+// test if none arrives within a few seconds
+func nextEvent(t *testing.T, es *eventStream) []byte {
+	ch := make(chan []byte, 1)
+	go func() {
+		var data []byte
+		for {
+			line, err := es.r.ReadBytes('\n')
+			if err != nil {
+				ch <- nil
+				return
+			}
+			line = bytes.TrimRight(line, "\n")
+			if len(line) == 0 && data != nil {
+				ch <- data
+				return
+			}
+			if bytes.HasPrefix(line, []byte("data: ")) {
+				data = append(data, line[len("data: "):]...)
+			}
+		}
+	}()
+	select {
+	case data := <-ch:
+		if data == nil {
+			t.Fatal("Event stream closed")
+		}
+		return data
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+	return nil
+}
+
+// decodeErrorData decodes the structured error response body, failing the
+// test if it is not one
+func decodeErrorData(t *testing.T, resp *http.Response) errorData {
+	var ed errorData
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Error response has Content-Type %q, expected application/json", ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ed); err != nil {
+		t.Fatalf("Unexpected err %s decoding error response", err)
+	}
+	return ed
+}
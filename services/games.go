@@ -0,0 +1,270 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao"
+	daoerr "github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/rooms"
+	"github.com/bbawn/boredgames/internal/router"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// Games provides the REST API for playing games of any registered type
+// through a single action route, recording the result of each action
+type Games struct {
+	types *games.Registry
+	// rooms are where games may be played, whose rules apply to them
+	rooms *Rooms
+	// actions are the logs of the actions applied to each game
+	actions dao.Actions
+}
+
+// GamesAddRoutes adds the routes for this service to the given router and
+// returns the service
+func GamesAddRoutes(types *games.Registry, rms *Rooms, actions dao.Actions, router *router.TableRouter) *Games {
+	gs := &Games{types: types, rooms: rms, actions: actions}
+	router.AddRoute("GET", "/games/([^/]+)/actions", http.HandlerFunc(gs.ListActions))
+	router.AddRoute("POST", "/games/([^/]+)/actions", http.HandlerFunc(gs.ApplyAction))
+	return gs
+}
+
+// find returns the game with the given ID and its Type, whichever it is
+func (gs *Games) find(id uuid.UUID) (games.Type, games.Game, error) {
+	for _, gt := range gs.types.GameTypes() {
+		t, err := gs.types.Get(gt)
+		if err != nil {
+			return nil, nil, err
+		}
+		game, err := t.Get(id)
+		if _, ok := err.(daoerr.NotFoundError); ok {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return t, game, nil
+	}
+	return nil, nil, daoerr.NotFoundError{Key: id.String()}
+}
+
+// gameRoom returns the room the game with the given ID is or was played
+// in, or nil if none
+func gameRoom(rms dao.Rooms, id uuid.UUID) (*rooms.Room, error) {
+	rs, err := rms.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, room := range rs {
+		for _, rec := range room.Games {
+			if rec.GameID == id {
+				return room, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// viewRoom returns the room the game with the given ID is or was played
+// in, if any, or a forbiddenError if the client making the request may not
+// view it
+func viewRoom(r *http.Request, rms dao.Rooms, id uuid.UUID) (*rooms.Room, error) {
+	room, err := gameRoom(rms, id)
+	if err != nil || room == nil {
+		return nil, err
+	}
+	err = canView(r, room)
+	if err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// hiddenGames returns the IDs of the games played in rooms the client
+// making the request may not view
+func hiddenGames(r *http.Request, rms dao.Rooms) (map[uuid.UUID]bool, error) {
+	rs, err := rms.List()
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[uuid.UUID]bool)
+	for _, room := range rs {
+		if canView(r, room) == nil {
+			continue
+		}
+		for _, rec := range room.Games {
+			hidden[rec.GameID] = true
+		}
+	}
+	return hidden, nil
+}
+
+// authorize returns an error unless the client making the request may
+// apply the action to the game with the given ID. The request must be made
+// by the action's Player, or by an admin, and the Player must be a player
+// in the game but for a membership action. A game played in a room
+// follows the room's rules: the requester must be allowed to view the
+// room, and roomRules must allow the action.
+func authorize(r *http.Request, rms dao.Rooms, id uuid.UUID, game games.Game, a games.Action) error {
+	if requestRole(r) != AdminRole {
+		username := requestUsername(r)
+		if username == "" {
+			return unauthorizedError{"no session in request"}
+		}
+		if username != a.Player {
+			return forbiddenError{fmt.Sprintf("user %q may not act as player %q", username, a.Player)}
+		}
+	}
+	if a.Player == "" {
+		return badRequestError{"action must have a player"}
+	}
+	room, err := viewRoom(r, rms, id)
+	if err != nil {
+		return err
+	}
+	err = roomRules(room, id, a)
+	if err != nil {
+		return err
+	}
+	if _, ok := game.Status().Scores[a.Player]; !ok && !games.MembershipAction(a.Type) {
+		return forbiddenError{fmt.Sprintf("user %q is not a player in game %s", a.Player, id)}
+	}
+	return nil
+}
+
+// roomRules returns an error if the rules of the room the game with the
+// given ID is or was played in, if any, forbid applying the action to it:
+// the game must still be the room's current game, and players join and
+// leave it only through the room
+func roomRules(room *rooms.Room, id uuid.UUID, a games.Action) error {
+	if room == nil {
+		return nil
+	}
+	if cur := room.CurrentGame(); cur == nil || cur.GameID != id {
+		return conflictError{fmt.Sprintf("game %s is over in room %s", id, room.Name)}
+	}
+	if games.MembershipAction(a.Type) {
+		return forbiddenError{fmt.Sprintf("players join and leave game %s through room %s", id, room.Name)}
+	}
+	return nil
+}
+
+// deletable returns a conflictError if the game with the given ID is the
+// current game of a room, which ends it only as a new game starts or the
+// room closes
+func deletable(rms dao.Rooms, id uuid.UUID) error {
+	room, err := gameRoom(rms, id)
+	if err != nil || room == nil {
+		return err
+	}
+	if cur := room.CurrentGame(); cur != nil && cur.GameID == id {
+		return conflictError{fmt.Sprintf("game %s is being played in room %s", id, room.Name)}
+	}
+	return nil
+}
+
+// actionResultData is the payload of the action response
+type actionResultData struct {
+	Result *games.Result `json:"result"`
+	Status games.Status  `json:"status"`
+	// View is the game as the acting player sees it
+	View interface{} `json:"view"`
+}
+
+// ApplyAction applies the action in the request body to the game and
+// records its result, whether or not it succeeds, once authorize allows
+// it. An action with a ClientVersion other than the game's current version
+// is rejected as stale, without being recorded.
+func (gs *Games) ApplyAction(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid game uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	var a games.Action
+	dec := json.NewDecoder(r.Body)
+	err = dec.Decode(&a)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal action: %s", err), http.StatusBadRequest)
+		return
+	}
+	if a.Type == "" || a.Player == "" {
+		http.Error(w, "Failed to apply action: action must have a type and player", http.StatusBadRequest)
+		return
+	}
+	a.Player = validate.Normal(a.Player)
+
+	typ, game, err := gs.find(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = authorize(r, gs.rooms.dao, id, game, a)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to apply action: %s", err), httpStatus(err))
+		return
+	}
+
+	result, game, err := typ.Apply(id, a)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to apply action: %s", err), err)
+		return
+	}
+	if requestRole(r) != AdminRole {
+		result.Action.Seed = 0
+	}
+	ard := actionResultData{Result: result, Status: game.Status(), View: game.View(a.Player)}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(ard)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode action result: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// ListActions returns the results of the actions applied to the game, in
+// the order applied. The log of a game played in a room is shown only to
+// those allowed to view the room. Only admins see everything: others see
+// no seeds, which could reveal what the game hides, such as the order of a
+// Set deck, and what a games.Redactor hides from them.
+func (gs *Games) ListActions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid game uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	_, game, err := gs.find(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
+		return
+	}
+	_, err = viewRoom(r, gs.rooms.dao, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list game actions: %s", err), httpStatus(err))
+		return
+	}
+	results, err := gs.actions.List(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game actions from datastore: %s", err), httpStatus(err))
+		return
+	}
+	if requestRole(r) != AdminRole {
+		for _, result := range results {
+			result.Action.Seed = 0
+		}
+		if red, ok := game.(games.Redactor); ok {
+			results = red.Redact(results, requestUsername(r))
+		}
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(results)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode game actions: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/games/set"
+	"github.com/bbawn/boredgames/internal/router"
+)
+
+func TestGames(t *testing.T) {
+	g := NewGomegaWithT(t)
+	daoSets := ram.NewSets()
+	actions := ram.NewActions()
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	types := setTypes(SetsAddRoutes(daoSets, actions, daoRooms, tr))
+	GamesAddRoutes(types, RoomsAddRoutes(daoRooms, ram.NewMessages(), types, tr), actions, tr)
+	h := AdminAuth("secret", tr)
+
+	// Create a game with a set on its board, so claiming it needs no
+	// expansion
+	var v *set.View
+	var initial *set.Game
+	for initial == nil || initial.Board.FindSet(true) == nil {
+		d := `{ "usernames": [ "p1", "p2" ] }`
+		resp := doRequest(h, "POST", "http://example.com/sets", bytes.NewReader([]byte(d)))
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+		var err error
+		initial, err = daoSets.Get(v.ID)
+		g.Expect(err).To(BeNil())
+	}
+	url := fmt.Sprintf("http://example.com/games/%s/actions", v.ID)
+	act := func(username string, a games.Action) *http.Response {
+		b, err := json.Marshal(a)
+		g.Expect(err).To(BeNil())
+		return doUserRequest(h, "POST", url, username, bytes.NewReader(b))
+	}
+	version := func(v int) *int {
+		return &v
+	}
+	expectError := func(resp *http.Response, status int, msg string) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		g.Expect(resp.StatusCode).To(Equal(status))
+		g.Expect(string(body)).To(Equal(msg + "\n"))
+	}
+
+	t.Log("List the actions of a game with none")
+	resp := doRequest(h, "GET", url, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var results []*games.Result
+	g.Expect(json.NewDecoder(resp.Body).Decode(&results)).To(Succeed())
+	g.Expect(results).To(BeEmpty())
+	resp = doRequest(h, "GET", fmt.Sprintf("http://example.com/games/%s/actions", uuid.New()), nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	t.Log("Apply actions that are not recorded")
+	resp = doRequest(h, "POST", "http://example.com/games/xyz/actions", bytes.NewReader([]byte(`{}`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	resp = doRequest(h, "POST", url, bytes.NewReader([]byte(`foo`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	expectError(act("", games.Action{Type: set.ExpandAction}), http.StatusBadRequest,
+		"Failed to apply action: action must have a type and player")
+	expectError(act("", games.Action{Type: set.ExpandAction, Player: "p1"}), http.StatusUnauthorized,
+		"Failed to apply action: no session in request")
+	expectError(act("p2", games.Action{Type: set.ExpandAction, Player: "p1"}), http.StatusForbidden,
+		`Failed to apply action: user "p2" may not act as player "p1"`)
+	expectError(act("p9", games.Action{Type: set.ExpandAction, Player: "p9"}), http.StatusForbidden,
+		fmt.Sprintf(`Failed to apply action: user "p9" is not a player in game %s`, v.ID))
+	resp = doUserRequest(h, "POST", fmt.Sprintf("http://example.com/games/%s/actions", uuid.New()), "p1",
+		bytes.NewReader([]byte(`{ "type": "expand", "player": "p1" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	t.Log("Apply invalid actions, which are recorded")
+	expectError(act("p1", games.Action{Type: "shuffle", Player: "p1"}), http.StatusBadRequest,
+		"Failed to apply action: Invalid action: shuffle detail: no such action")
+	expectError(act("p1", games.Action{Type: set.NextAction, Player: "p1"}), http.StatusConflict,
+		"Failed to apply action: Invalid method: NextRound detail: round not yet claimed")
+
+	t.Log("Claim a set against the version of a game with no successful actions")
+	cs := initial.FindExpandSet()
+	g.Expect(cs).NotTo(BeNil())
+	payload, err := json.Marshal(set.ClaimPayload{Cards: *cs})
+	g.Expect(err).To(BeNil())
+	resp = act("p1", games.Action{Type: set.ClaimAction, Player: "p1", Payload: payload, ClientVersion: version(0)})
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var ard struct {
+		Result *games.Result `json:"result"`
+		Status games.Status  `json:"status"`
+		View   *set.View     `json:"view"`
+	}
+	g.Expect(json.NewDecoder(resp.Body).Decode(&ard)).To(Succeed())
+	g.Expect(ard.Result.Seq).To(Equal(3))
+	g.Expect(ard.Result.Version).To(Equal(1))
+	g.Expect(ard.Result.Error).To(BeEmpty())
+	g.Expect(ard.Result.Action.Seed).To(BeZero())
+	g.Expect(ard.Status).To(Equal(games.Status{State: "SetClaimed", Scores: map[string]int{"p1": 1, "p2": 0}}))
+	g.Expect(ard.View.ID).To(Equal(v.ID))
+
+	t.Log("Apply actions against a client version")
+	expectError(act("p2", games.Action{Type: set.NextAction, Player: "p2", ClientVersion: version(0)}), http.StatusConflict,
+		"Failed to apply action: client version 0 is not the game version 1")
+	resp = act("p2", games.Action{Type: set.NextAction, Player: "p2", ClientVersion: version(1)})
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&ard)).To(Succeed())
+	g.Expect(ard.Result.Version).To(Equal(2))
+	g.Expect(ard.Status.State).To(Equal("Playing"))
+
+	t.Log("Changes through the game's own API are recorded too")
+	resp = doUserRequest(h, "POST", fmt.Sprintf("http://example.com/sets/%s/expand", v.ID), "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	d := `{ "username": "p1", "sets": "return" }`
+	resp = doUserRequest(h, "DEL", fmt.Sprintf("http://example.com/sets/%s/players", v.ID), "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	expectError(act("p3", games.Action{Type: games.JoinAction, Player: "p3", ClientVersion: version(3)}), http.StatusConflict,
+		"Failed to apply action: client version 3 is not the game version 4")
+	resp = act("p3", games.Action{Type: games.JoinAction, Player: "p3", ClientVersion: version(4)})
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("List the recorded actions, whose seeds only admins see")
+	resp = doRequest(h, "GET", url, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&results)).To(Succeed())
+	g.Expect(results).To(HaveLen(7))
+	for i, r := range results {
+		g.Expect(r.GameID).To(Equal(v.ID))
+		g.Expect(r.Seq).To(Equal(i + 1))
+		g.Expect(r.Action.Seed).To(BeZero())
+	}
+	g.Expect(results[0].Action).To(Equal(games.Action{Type: "shuffle", Player: "p1"}))
+	g.Expect(results[0].Succeeded()).To(BeFalse())
+	g.Expect(results[1].Version).To(Equal(0))
+	g.Expect(results[2].Action.Payload).To(MatchJSON(payload))
+	g.Expect(*results[3].Action.ClientVersion).To(Equal(1))
+	g.Expect(results[4].Action.Type).To(Equal(set.ExpandAction))
+	g.Expect(results[5].Action.Type).To(Equal(games.LeaveAction))
+	g.Expect(results[5].Action.Payload).To(MatchJSON(`{ "sets": "return" }`))
+	g.Expect(results[6].Version).To(Equal(5))
+	resp = doAuthRequest(h, "GET", url, "Bearer secret", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&results)).To(Succeed())
+	g.Expect(results[5].Action.Seed).NotTo(BeZero())
+
+	t.Log("Replay the actions on the game as created")
+	g.Expect(games.Replay(set.Generic{Game: initial}, results)).To(Succeed())
+	game, err := daoSets.Get(v.ID)
+	g.Expect(err).To(BeNil())
+	// Only the datastore saves the time of activity
+	initial.LastActivity = game.LastActivity
+	g.Expect(initial).To(Equal(game))
+}
+
+func TestGamesRoom(t *testing.T) {
+	g := NewGomegaWithT(t)
+	daoSets := ram.NewSets()
+	actions := ram.NewActions()
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	types := setTypes(SetsAddRoutes(daoSets, actions, daoRooms, tr))
+	GamesAddRoutes(types, RoomsAddRoutes(daoRooms, ram.NewMessages(), types, tr), actions, tr)
+
+	d := `{ "name": "n1", "owner": "p1", "usernames": {"p1": true, "p2": true}, "private": true }`
+	resp := doUserRequest(tr, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "p1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var rgd struct {
+		GameID uuid.UUID `json:"gameID"`
+	}
+	g.Expect(json.NewDecoder(resp.Body).Decode(&rgd)).To(Succeed())
+	url := fmt.Sprintf("http://example.com/games/%s/actions", rgd.GameID)
+
+	t.Log("Only users in a private room may see its game's actions")
+	resp = doUserRequest(tr, "GET", url, "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(tr, "GET", url, "p9", nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to list game actions: user \"p9\" is not in private room n1\n"))
+
+	t.Log("Players act in the room's current game only")
+	resp = doUserRequest(tr, "POST", url, "p2", bytes.NewReader([]byte(`{ "type": "next", "player": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	resp = doUserRequest(tr, "DEL", "http://example.com/rooms/n1/game", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(tr, "POST", url, "p2", bytes.NewReader([]byte(`{ "type": "next", "player": "p2" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Failed to apply action: game %s is over in room n1\n", rgd.GameID)))
+}
+
+func TestGamesFailedActions(t *testing.T) {
+	g := NewGomegaWithT(t)
+	daoSets := ram.NewSets()
+	actions := ram.NewActions()
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	types := setTypes(SetsAddRoutes(daoSets, actions, daoRooms, tr))
+	GamesAddRoutes(types, RoomsAddRoutes(daoRooms, ram.NewMessages(), types, tr), actions, tr)
+
+	game, err := set.NewGame("p1", "p2")
+	g.Expect(err).To(BeNil())
+	g.Expect(daoSets.Insert(game)).To(Succeed())
+	url := fmt.Sprintf("http://example.com/games/%s/actions", game.ID)
+	act := func(d string) *http.Response {
+		return doUserRequest(tr, "POST", url, "p1", bytes.NewReader([]byte(d)))
+	}
+
+	t.Log("Failed actions in a row are recorded up to a bound, but still fail")
+	for i := 0; i < maxFailedResults+5; i++ {
+		resp := act(`{ "type": "next", "player": "p1" }`)
+		g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	}
+	results, err := actions.List(game.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(results).To(HaveLen(maxFailedResults))
+
+	t.Log("A success starts the count again")
+	resp := act(`{ "type": "expand", "player": "p1" }`)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = act(`{ "type": "next", "player": "p1" }`)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	results, err = actions.List(game.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(results).To(HaveLen(maxFailedResults + 2))
+	g.Expect(results[maxFailedResults].Succeeded()).To(BeTrue())
+	g.Expect(results[maxFailedResults+1].Succeeded()).To(BeFalse())
+}
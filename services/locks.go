@@ -0,0 +1,48 @@
+package services
+
+import (
+	"sync"
+)
+
+// keyLocks serializes operations per key, such as the updates of a room or
+// game that read it, change it and write it back. The zero value is ready
+// to use.
+type keyLocks struct {
+	m     sync.Mutex
+	locks map[string]*keyLock
+}
+
+// keyLock is the lock of one key and the number of operations holding or
+// waiting for it
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks the given key, waiting for any other operation holding it,
+// and returns the function unlocking it
+func (kl *keyLocks) lock(key string) func() {
+	kl.m.Lock()
+	if kl.locks == nil {
+		kl.locks = make(map[string]*keyLock)
+	}
+	l, ok := kl.locks[key]
+	if !ok {
+		l = &keyLock{}
+		kl.locks[key] = l
+	}
+	l.refs++
+	kl.m.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		kl.m.Lock()
+		defer kl.m.Unlock()
+		// Drop unused locks so the map stays bounded
+		l.refs--
+		if l.refs == 0 {
+			delete(kl.locks, key)
+		}
+	}
+}
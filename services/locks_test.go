@@ -0,0 +1,31 @@
+package services
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestKeyLocks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var kl keyLocks
+	var wg sync.WaitGroup
+	// Each count is only changed holding the lock of its key
+	counts := map[string]*int{"a": new(int), "b": new(int)}
+	for i := 0; i < 100; i++ {
+		for key := range counts {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				defer kl.lock(key)()
+				n := *counts[key]
+				*counts[key] = n + 1
+			}(key)
+		}
+	}
+	wg.Wait()
+	g.Expect(*counts["a"]).To(Equal(100))
+	g.Expect(*counts["b"]).To(Equal(100))
+	g.Expect(kl.locks).To(BeEmpty())
+}
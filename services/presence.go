@@ -0,0 +1,114 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bbawn/boredgames/internal/router"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// presenceData is the payload of the heartbeat request
+type presenceData struct {
+	// Idle is whether the client reports the user inactive
+	Idle bool `json:"idle"`
+}
+
+// Heartbeat records that the requesting user, who must be in the room, is
+// still connected. Clients send one well within the presence timeout.
+func (rms *Rooms) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	var pd presenceData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&pd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal presence data: %s", err), http.StatusBadRequest)
+		return
+	}
+	username := requestUsername(r)
+	if username == "" {
+		http.Error(w, "Failed to record heartbeat: user must be identified", http.StatusForbidden)
+		return
+	}
+	prev, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	room, err := rms.dao.Heartbeat(name, username, pd.Idle)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to record heartbeat in datastore: %s", err), httpStatus(err))
+		return
+	}
+	if prev.Presence[username].Status != room.Presence[username].Status {
+		rms.publish(room)
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(room.Presence[username])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode presence: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// SweepPresence marks as disconnected the users who have sent no heartbeat
+// for timeout as of now and, if removeAfter is non-zero, removes from their
+// rooms the users other than owners who have been disconnected for longer
+// than removeAfter. Changed rooms are published to their event streams.
+func (rms *Rooms) SweepPresence(now time.Time, timeout, removeAfter time.Duration) {
+	changed, err := rms.dao.Disconnect(now.Add(-timeout))
+	if err != nil {
+		log.Printf("WARN: failed to mark disconnected users: %s", err)
+	}
+	for _, room := range changed {
+		rms.publish(room)
+	}
+	if removeAfter == 0 {
+		return
+	}
+	all, err := rms.dao.List()
+	if err != nil {
+		log.Printf("WARN: failed to load rooms to remove disconnected users: %s", err)
+		return
+	}
+	for _, room := range all {
+		rms.removeDisconnected(room.Name, now.Add(-removeAfter))
+	}
+}
+
+// removeDisconnected removes from the named room the users other than the
+// owner who have been disconnected since before the given time
+func (rms *Rooms) removeDisconnected(name string, before time.Time) {
+	defer rms.locks.lock(validate.Key(name))()
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		log.Printf("WARN: failed to load room %s to remove disconnected users: %s", name, err)
+		return
+	}
+	for _, u := range room.DisconnectedSince(before) {
+		if u == room.Owner {
+			continue
+		}
+		next, err := rms.dao.DeletePlayer(room.Name, u)
+		if err != nil {
+			log.Printf("WARN: failed to remove disconnected user %s from room %s: %s", u, room.Name, err)
+			continue
+		}
+		synced, err := rms.syncGamePlayer(next, u, false)
+		if err != nil {
+			log.Printf("WARN: failed to remove disconnected player %s from room %s game: %s", u, room.Name, err)
+		} else {
+			next = synced
+		}
+		next = rms.syncPromoted(room, next)
+		rms.publish(next)
+		room = next
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/rooms"
+	"github.com/bbawn/boredgames/internal/router"
+)
+
+func TestRoomPresence(t *testing.T) {
+	g := NewGomegaWithT(t)
+	daoSets := ram.NewSets()
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	rms := RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(daoSets, ram.NewActions(), daoRooms, tr)), tr)
+	srv := httptest.NewServer(tr)
+	defer srv.Close()
+
+	d := `{ "name": "n1", "usernames": {"p1": true, "p2": true}, "maxPlayers": 2 }`
+	resp := doUserRequest(tr, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "p1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var room *rooms.Room
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	gameID := room.GameID
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/players", "p3", bytes.NewReader([]byte(`{ "username": "p3" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+
+	events := subscribe(t, srv.URL+"/rooms/n1/events")
+	defer events.Close()
+	var ev *roomEvent
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.Room.Presence).To(BeNil())
+
+	heartbeat := func(username string, idle bool) *http.Response {
+		b, _ := json.Marshal(presenceData{idle})
+		return doUserRequest(tr, "POST", "http://example.com/rooms/n1/presence", username, bytes.NewReader(b))
+	}
+
+	t.Log("Only users in the room may send heartbeats")
+	resp = heartbeat("p9", false)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	resp = heartbeat("", false)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+	t.Log("Status changes are published")
+	resp = heartbeat("p1", false)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var p rooms.Presence
+	g.Expect(json.NewDecoder(resp.Body).Decode(&p)).To(Succeed())
+	g.Expect(p.Status).To(Equal(rooms.Online))
+	ev = nil
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.Room.Presence["p1"].Status).To(Equal(rooms.Online))
+	resp = heartbeat("p2", true)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	ev = nil
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.Room.Presence["p2"].Status).To(Equal(rooms.Idle))
+
+	t.Log("Users without heartbeats are disconnected, then removed")
+	rms.SweepPresence(time.Now().Add(time.Minute), 30*time.Second, 0)
+	ev = nil
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.Room.Presence["p1"].Status).To(Equal(rooms.Disconnected))
+	g.Expect(ev.Room.Presence["p2"].Status).To(Equal(rooms.Disconnected))
+	g.Expect(ev.Room.Usernames).To(HaveLen(2))
+
+	rms.SweepPresence(time.Now().Add(2*time.Minute), 30*time.Second, 5*time.Minute)
+	resp = doRequest(tr, "GET", "http://example.com/rooms/n1", nil)
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Usernames).To(HaveLen(2))
+
+	rms.SweepPresence(time.Now().Add(10*time.Minute), 30*time.Second, 5*time.Minute)
+	resp = doRequest(tr, "GET", "http://example.com/rooms/n1", nil)
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	// The owner stays, the waiting player takes the removed player's place
+	g.Expect(room.Usernames).To(Equal(map[string]bool{"p1": true, "p3": true}))
+	g.Expect(room.Presence).To(HaveKey("p1"))
+	g.Expect(room.Presence).NotTo(HaveKey("p2"))
+	game, err := daoSets.Get(gameID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).To(HaveKey("p3"))
+	g.Expect(game.Players).NotTo(HaveKey("p2"))
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter limits events per key to a number in a sliding time window
+type rateLimiter struct {
+	m      sync.Mutex
+	limit  int
+	window time.Duration
+	// times are the times of the events in the current window, by key
+	times map[string][]time.Time
+	// swept is when keys with no events in the window were last dropped
+	swept time.Time
+}
+
+// newRateLimiter creates a rateLimiter allowing limit events per key in
+// any window
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, times: make(map[string][]time.Time)}
+}
+
+// allow records an event for key at the given time and returns true,
+// unless key has already had its limit of events in the window ending then
+func (rl *rateLimiter) allow(key string, now time.Time) bool {
+	rl.m.Lock()
+	defer rl.m.Unlock()
+	start := now.Add(-rl.window)
+	if rl.swept.Before(start) {
+		// Drop idle keys now and then so the map stays bounded
+		for k, ts := range rl.times {
+			if ts[len(ts)-1].Before(start) {
+				delete(rl.times, k)
+			}
+		}
+		rl.swept = now
+	}
+	ts := rl.times[key]
+	for len(ts) > 0 && !ts[0].After(start) {
+		ts = ts[1:]
+	}
+	if len(ts) >= rl.limit {
+		rl.times[key] = ts
+		return false
+	}
+	rl.times[key] = append(ts, now)
+	return true
+}
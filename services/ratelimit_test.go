@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRateLimiter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	rl := newRateLimiter(2, 10*time.Second)
+	t0 := time.Now()
+
+	g.Expect(rl.allow("a", t0)).To(BeTrue())
+	g.Expect(rl.allow("a", t0.Add(time.Second))).To(BeTrue())
+	g.Expect(rl.allow("a", t0.Add(2*time.Second))).To(BeFalse())
+	g.Expect(rl.allow("b", t0.Add(2*time.Second))).To(BeTrue())
+
+	// The window slides past the first event
+	g.Expect(rl.allow("a", t0.Add(10*time.Second))).To(BeTrue())
+	g.Expect(rl.allow("a", t0.Add(10*time.Second))).To(BeFalse())
+
+	// Idle keys are dropped
+	g.Expect(rl.allow("c", t0.Add(time.Minute))).To(BeTrue())
+	g.Expect(rl.times).To(HaveLen(1))
+}
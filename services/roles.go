@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Role is the access level of an API client
+type Role int
+
+const (
+	// PublicRole is the role of players, spectators and anonymous clients
+	PublicRole Role = iota
+	// AdminRole may see hidden game state, such as the order of a Set Deck
+	AdminRole
+)
+
+type roleKey struct{}
+
+// AdminAuth returns a handler that grants AdminRole to requests bearing
+// the given token as "Authorization: Bearer <token>" before passing them to
+// next. All other requests, and all requests if token is empty, have
+// PublicRole.
+func AdminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := PublicRole
+		auth := r.Header.Get("Authorization")
+		if token != "" && strings.HasPrefix(auth, "Bearer ") &&
+			subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) == 1 {
+			role = AdminRole
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), roleKey{}, role)))
+	})
+}
+
+// requestRole returns the Role of the client making the given request
+func requestRole(r *http.Request) Role {
+	role, ok := r.Context().Value(roleKey{}).(Role)
+	if !ok {
+		return PublicRole
+	}
+	return role
+}
+
+// requestUsername returns the username of the user making the given
+// request, as identified by its session, or "" if unidentified
+func requestUsername(r *http.Request) string {
+	session := requestSession(r)
+	if session == nil {
+		return ""
+	}
+	return session.Username
+}
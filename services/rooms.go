@@ -1,65 +1,266 @@
 package services
 
 import (
+	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/bbawn/boredgames/internal/dao"
+	daoerr "github.com/bbawn/boredgames/internal/dao/errors"
+	"github.com/bbawn/boredgames/internal/dao/query"
+	"github.com/bbawn/boredgames/internal/events"
+	"github.com/bbawn/boredgames/internal/games"
 	"github.com/bbawn/boredgames/internal/rooms"
 	"github.com/bbawn/boredgames/internal/router"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// Chat rate limit: users may post chatRateLimit messages per chatRateWindow
+// in each room
+const (
+	chatRateLimit  = 5
+	chatRateWindow = 10 * time.Second
 )
 
 // Rooms provides the REST API for the game room resource
 type Rooms struct {
 	dao dao.Rooms
+	// messages are the rooms' chat messages
+	messages dao.Messages
+	// types are the types of games rooms may host
+	types *games.Registry
+	// events publishes room events to their event stream subscribers,
+	// keyed on room name
+	events *events.Broker
+	// chatLimiter limits the rate of chat messages, keyed on room and user
+	chatLimiter *rateLimiter
+	// locks serialize the changes to each room's players and game, keyed
+	// on the validate.Key of the room name
+	locks keyLocks
 }
 
-// RoomsAddRoutes adds the routes for this service to the given router
-func RoomsAddRoutes(dao dao.Rooms, router *router.TableRouter) {
-	rms := &Rooms{dao}
+// RoomsAddRoutes adds the routes for this service to the given router and
+// returns the service
+func RoomsAddRoutes(dao dao.Rooms, messages dao.Messages, types *games.Registry, router *router.TableRouter) *Rooms {
+	rms := &Rooms{
+		dao:         dao,
+		messages:    messages,
+		types:       types,
+		events:      events.NewBroker(),
+		chatLimiter: newRateLimiter(chatRateLimit, chatRateWindow),
+	}
 	router.AddRoute("GET", "/rooms", http.HandlerFunc(rms.List))
 	router.AddRoute("POST", "/rooms", http.HandlerFunc(rms.Create))
 	router.AddRoute("GET", "/rooms/([^/]+)", http.HandlerFunc(rms.Get))
 	router.AddRoute("DEL", "/rooms/([^/]+)", http.HandlerFunc(rms.Delete))
+	router.AddRoute("GET", "/rooms/([^/]+)/events", http.HandlerFunc(rms.Events))
+	router.AddRoute("GET", "/rooms/([^/]+)/messages", http.HandlerFunc(rms.ListMessages))
+	router.AddRoute("POST", "/rooms/([^/]+)/messages", http.HandlerFunc(rms.PostMessage))
+	router.AddRoute("POST", "/rooms/([^/]+)/presence", http.HandlerFunc(rms.Heartbeat))
 	router.AddRoute("POST", "/rooms/([^/]+)/players", http.HandlerFunc(rms.AddPlayer))
 	router.AddRoute("DEL", "/rooms/([^/]+)/players", http.HandlerFunc(rms.DeletePlayer))
+	router.AddRoute("PUT", "/rooms/([^/]+)/roles", http.HandlerFunc(rms.SetRole))
+	router.AddRoute("PUT", "/rooms/([^/]+)/owner", http.HandlerFunc(rms.SetOwner))
+	router.AddRoute("PUT", "/rooms/([^/]+)/capacity", http.HandlerFunc(rms.SetCapacity))
+	router.AddRoute("PUT", "/rooms/([^/]+)/access", http.HandlerFunc(rms.SetAccess))
+	router.AddRoute("POST", "/rooms/([^/]+)/invites", http.HandlerFunc(rms.CreateInvite))
+	router.AddRoute("DEL", "/rooms/([^/]+)/invites", http.HandlerFunc(rms.RevokeInvite))
+	router.AddRoute("DEL", "/rooms/([^/]+)/invites/([^/]+)", http.HandlerFunc(rms.RevokeInvite))
+	router.AddRoute("GET", "/rooms/([^/]+)/game", http.HandlerFunc(rms.GetGame))
 	router.AddRoute("PUT", "/rooms/([^/]+)/game", http.HandlerFunc(rms.SetGame))
+	router.AddRoute("DEL", "/rooms/([^/]+)/game", http.HandlerFunc(rms.EndGame))
+	router.AddRoute("GET", "/rooms/([^/]+)/games", http.HandlerFunc(rms.Games))
+	router.AddRoute("POST", "/rooms/([^/]+)/games", http.HandlerFunc(rms.CreateGame))
+	return rms
 }
 
-// List returns the list of all rooms
+// Room list page sizes
+const (
+	defaultRoomsLimit = 50
+	maxRoomsLimit     = 200
+)
+
+// List returns a page of the rooms, less the private rooms the requesting
+// user is not in, selected and ordered by the query parameters:
+//
+//	prefix    rooms with names starting with it, regardless of case
+//	gameType  rooms currently set to play the game type with this number
+//	open      if true, rooms that are not full
+//	sort      name (the default), activity (most recent first) or size
+//	          (most players first)
+//	limit     the most rooms to return, 1 to 200, 50 by default
+//	cursor    the rooms after the last of the previous page
+//
+// If there are more rooms, a Link header gives the URL of the next page.
 func (rms *Rooms) List(w http.ResponseWriter, r *http.Request) {
-	rooms, err := rms.dao.List()
+	role := requestRole(r)
+	q := query.Rooms{
+		Viewer:     requestUsername(r),
+		AllPrivate: role == AdminRole,
+		Order:      query.ByName,
+		Limit:      defaultRoomsLimit,
+	}
+	params := r.URL.Query()
+	q.Prefix = params.Get("prefix")
+	if v := params.Get("gameType"); v != "" {
+		typ, err := strconv.Atoi(v)
+		if err != nil || typ < int(rooms.None) || typ > int(rooms.RRobots) {
+			http.Error(w, fmt.Sprintf("Invalid gameType: %q", v), http.StatusBadRequest)
+			return
+		}
+		gameType := rooms.GameType(typ)
+		q.GameType = &gameType
+	}
+	if v := params.Get("open"); v != "" {
+		open, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid open: %q", v), http.StatusBadRequest)
+			return
+		}
+		q.OpenSeats = open
+	}
+	if v := params.Get("sort"); v != "" {
+		q.Order = query.Order(v)
+		if q.Order != query.ByName && q.Order != query.ByActivity && q.Order != query.BySize {
+			http.Error(w, fmt.Sprintf("Invalid sort: %q, must be name, activity or size", v), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxRoomsLimit {
+			http.Error(w, fmt.Sprintf("Invalid limit: %q, must be 1 to %d", v, maxRoomsLimit), http.StatusBadRequest)
+			return
+		}
+		q.Limit = limit
+	}
+	if v := params.Get("cursor"); v != "" {
+		c, err := decodeCursor(v, q.Order)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid cursor: %q: %s", v, err), http.StatusBadRequest)
+			return
+		}
+		q.After = c
+	}
+	page, next, err := rms.dao.Query(q)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to load rooms from datastore: %s", err), httpStatus(err))
 		return
 	}
+	// Empty slice, not nil so we always encode a json array
+	listed := []*rooms.Room{}
+	for _, room := range page {
+		listed = append(listed, roomView(room, role))
+	}
+	if next != nil {
+		v, err := encodeCursor(next, q.Order)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to encode cursor: %s", err), http.StatusInternalServerError)
+			return
+		}
+		params.Set("cursor", v)
+		w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", r.URL.Path, params.Encode()))
+	}
 	enc := json.NewEncoder(w)
-	err = enc.Encode(rooms)
+	err = enc.Encode(listed)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode rooms from datastore: %s", err), http.StatusInternalServerError)
 		return
 	}
 }
 
+// cursorData is the content of the cursor query parameter of the list
+// rooms request
+type cursorData struct {
+	Order query.Order `json:"o"`
+	query.Cursor
+}
+
+// encodeCursor returns the cursor query parameter for the given position
+// in the given order
+func encodeCursor(c *query.Cursor, order query.Order) (string, error) {
+	b, err := json.Marshal(cursorData{order, *c})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor returns the position given by the cursor query parameter,
+// or an error if it is malformed or for an order other than the given one
+func decodeCursor(v string, order query.Order) (*query.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, err
+	}
+	var cd cursorData
+	err = json.Unmarshal(b, &cd)
+	if err != nil {
+		return nil, err
+	}
+	if cd.Order != order {
+		return nil, fmt.Errorf("cursor is for sort %s, not %s", cd.Order, order)
+	}
+	return &cd.Cursor, nil
+}
+
 // postData is the data payload of the create room request
 type postData struct {
 	// Name is human-readable identifier of the Room
 	Name string `json:"name"`
 	// Usernames is the set of players in the room
 	Usernames map[string]bool `json:"usernames"`
+	// Owner is the username of the room owner, the requesting user if
+	// empty. Only admins may name another user.
+	Owner string `json:"owner"`
+	// Private rooms are unlisted and joinable only by invite or password
+	Private bool `json:"private"`
+	// Password is the password to join the room, if any
+	Password string `json:"password"`
+	// MaxPlayers is the most players the room allows, 0 for no limit but
+	// that of the game type
+	MaxPlayers int `json:"maxPlayers"`
 }
 
+// validate checks the data and normalizes its names
 func (pd *postData) validate() error {
-	if pd.Name == "" {
-		return errors.New("non-empty Name is required")
+	name, err := validate.RoomName(pd.Name)
+	if err != nil {
+		return err
 	}
+	pd.Name = name
+	if pd.MaxPlayers < 0 {
+		return badRequestError{"maxPlayers must not be negative"}
+	}
+	if pd.MaxPlayers > 0 && len(pd.Usernames) > pd.MaxPlayers {
+		return badRequestError{"usernames must not exceed maxPlayers"}
+	}
+	usernames := make([]string, 0, len(pd.Usernames))
 	for u := range pd.Usernames {
-		if u == "" {
-			return errors.New("usernames must be non-empty")
+		usernames = append(usernames, u)
+	}
+	sort.Strings(usernames)
+	usernames, err = validate.Usernames(usernames)
+	if err != nil {
+		return err
+	}
+	pd.Usernames = make(map[string]bool, len(usernames))
+	for _, u := range usernames {
+		pd.Usernames[u] = true
+	}
+	if pd.Owner != "" {
+		pd.Owner, err = validate.Username(pd.Owner)
+		if verr, ok := err.(validate.Error); ok {
+			verr.Field = "owner"
+			return verr
 		}
 	}
 	return nil
@@ -76,22 +277,42 @@ func (rms *Rooms) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	err = pd.validate()
 	if err != nil {
-		m := fmt.Sprintf("Invalid request payload err: %s", err)
-		http.Error(w, m, http.StatusBadRequest)
+		httpError(w, fmt.Sprintf("Invalid request payload err: %s", err), err)
 		return
 	}
-	room := rooms.NewRoom(pd.Name, pd.Usernames)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create new room: %s", err), http.StatusBadRequest)
+	// Only admins may create rooms on behalf of other users, or with no
+	// owner
+	requester := requestUsername(r)
+	if requester == "" && requestRole(r) != AdminRole {
+		http.Error(w, "Failed to create room: no session in request", http.StatusUnauthorized)
+		return
+	}
+	if pd.Owner != "" && validate.Key(pd.Owner) != validate.Key(requester) && requestRole(r) != AdminRole {
+		m := fmt.Sprintf("user %q may not create a room owned by %q", requester, pd.Owner)
+		http.Error(w, fmt.Sprintf("Failed to create room: %s", m), http.StatusForbidden)
 		return
 	}
+	room := rooms.NewRoom(pd.Name, pd.Usernames)
+	room.Owner = pd.Owner
+	if room.Owner == "" {
+		room.Owner = requester
+	}
+	room.Private = pd.Private
+	room.MaxPlayers = pd.MaxPlayers
+	if pd.Password != "" {
+		room.PasswordHash, err = rooms.HashPassword(pd.Password)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to hash room password: %s", err), http.StatusInternalServerError)
+			return
+		}
+	}
 	err = rms.dao.Insert(room)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to insert room into datastore: %s", err), httpStatus(err))
 		return
 	}
 	enc := json.NewEncoder(w)
-	err = enc.Encode(room)
+	err = enc.Encode(roomView(room, requestRole(r)))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode new room: %s", err), http.StatusInternalServerError)
 		return
@@ -105,13 +326,18 @@ func (rms *Rooms) Get(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
 		return
 	}
-	game, err := rms.dao.Get(name)
+	room, err := rms.dao.Get(name)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
 		return
 	}
+	err = canView(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room: %s", err), httpStatus(err))
+		return
+	}
 	enc := json.NewEncoder(w)
-	err = enc.Encode(game)
+	err = enc.Encode(roomView(room, requestRole(r)))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode room from datastore: %s", err), http.StatusInternalServerError)
 		return
@@ -125,16 +351,54 @@ func (rms *Rooms) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
 		return
 	}
-	err := rms.dao.Delete(name)
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = moderate(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete room: %s", err), httpStatus(err))
+		return
+	}
+	err = rms.dao.Delete(name)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to delete room from datastore: %s", err), httpStatus(err))
 		return
 	}
+	if err := rms.messages.Delete(room.Name); err != nil {
+		log.Printf("WARN: failed to delete messages of deleted room %s: %s", room.Name, err)
+	}
+}
+
+// moderate returns a forbiddenError unless the client making the request
+// may moderate the room
+func moderate(r *http.Request, room *rooms.Room) error {
+	username := requestUsername(r)
+	if requestRole(r) == AdminRole || room.CanModerate(username) {
+		return nil
+	}
+	return forbiddenError{fmt.Sprintf("user %q may not moderate room %s", username, room.Name)}
+}
+
+// roomView returns the projection of the room visible to a client with
+// the given role: admins see everything, others not the password and
+// invite code hashes
+func roomView(room *rooms.Room, role Role) *rooms.Room {
+	if role == AdminRole {
+		return room
+	}
+	return room.Redacted()
 }
 
 // playerData is the payload of the post and delete room player requests
 type playerData struct {
 	Username string
+	// Password is the room password, to join a restricted room
+	Password string `json:"password,omitempty"`
+	// Invite is an invite code, to join a restricted room. It is only
+	// accepted in the payload, never the URL, which may be logged.
+	Invite string `json:"invite,omitempty"`
 }
 
 // AddPlayer adds a player to the room
@@ -151,19 +415,78 @@ func (rms *Rooms) AddPlayer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to unmarshal player data: %s", err), http.StatusBadRequest)
 		return
 	}
-	room, err := rms.dao.AddPlayer(name, pd.Username)
+	pd.Username, err = validate.Username(pd.Username)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to add player: %s", err), err)
+		return
+	}
+	defer rms.locks.lock(validate.Key(name))()
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	if other := room.SameUser(pd.Username); other != "" && other != pd.Username {
+		err = validate.Error{Field: "username", Value: pd.Username, Reason: fmt.Sprintf("same as %q", other)}
+		httpError(w, fmt.Sprintf("Failed to add player: %s", err), err)
+		return
+	}
+	err = admit(r, room, pd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to add player: %s", err), httpStatus(err))
+		return
+	}
+	room, err = rms.dao.AddPlayer(name, pd.Username)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to add player into datastore: %s", err), httpStatus(err))
 		return
 	}
+	if !room.Usernames[pd.Username] {
+		// The room is full, so the player was waitlisted
+		rms.publish(room)
+		w.WriteHeader(http.StatusAccepted)
+		enc := json.NewEncoder(w)
+		err = enc.Encode(roomView(room, requestRole(r)))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	room, err = rms.syncGamePlayer(room, pd.Username, true)
+	if err != nil {
+		// Keep the room consistent with its game
+		if _, rerr := rms.dao.DeletePlayer(name, pd.Username); rerr != nil {
+			log.Printf("WARN: failed to roll back add of player %s to room %s: %s", pd.Username, name, rerr)
+		}
+		http.Error(w, fmt.Sprintf("Failed to add player to room game: %s", err), httpStatus(err))
+		return
+	}
+	rms.publish(room)
 	enc := json.NewEncoder(w)
-	err = enc.Encode(room)
+	err = enc.Encode(roomView(room, requestRole(r)))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
 		return
 	}
 }
 
+// admit returns a forbiddenError unless the player may join the room:
+// anyone may join an unrestricted room, and users rejoining the room
+// themselves or added by a moderator need no password or invite code
+func admit(r *http.Request, room *rooms.Room, pd playerData) error {
+	if room.Admits(pd.Password, pd.Invite) || requestRole(r) == AdminRole {
+		return nil
+	}
+	requester := requestUsername(r)
+	if room.CanModerate(requester) {
+		return nil
+	}
+	if room.SameUser(requester) != "" && validate.Key(requester) == validate.Key(pd.Username) {
+		return nil
+	}
+	return forbiddenError{fmt.Sprintf("room %s requires a valid password or invite code", room.Name)}
+}
+
 // DeletePlayer deletes a player from the room
 func (rms *Rooms) DeletePlayer(w http.ResponseWriter, r *http.Request) {
 	name := router.GetField(r, 0)
@@ -178,13 +501,361 @@ func (rms *Rooms) DeletePlayer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to unmarshal player data: %s", err), http.StatusBadRequest)
 		return
 	}
+	defer rms.locks.lock(validate.Key(name))()
+	prev, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	if validate.Key(pd.Username) != validate.Key(requestUsername(r)) {
+		// Removing anyone but yourself is a kick
+		err = moderate(r, prev)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to delete player: %s", err), httpStatus(err))
+			return
+		}
+	}
 	room, err := rms.dao.DeletePlayer(name, pd.Username)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to delete player from datastore: %s", err), httpStatus(err))
 		return
 	}
+	room, err = rms.syncGamePlayer(room, pd.Username, false)
+	if err == nil {
+		room = rms.syncPromoted(prev, room)
+	} else {
+		// Keep the room consistent with its game
+		if _, rerr := rms.dao.AddPlayer(name, pd.Username); rerr != nil {
+			log.Printf("WARN: failed to roll back delete of player %s from room %s: %s", pd.Username, name, rerr)
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete player from room game: %s", err), httpStatus(err))
+		return
+	}
+	rms.publish(room)
 	enc := json.NewEncoder(w)
-	err = enc.Encode(room)
+	err = enc.Encode(roomView(room, requestRole(r)))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// roleData is the payload of the role update PUT request
+type roleData struct {
+	Username string     `json:"username"`
+	Role     rooms.Role `json:"role"`
+}
+
+// SetRole sets the role of a user in the room. Moderators may make any
+// other user a player or spectator, and users may do so for themselves;
+// only the owner may grant or revoke the moderator role.
+func (rms *Rooms) SetRole(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	var rd roleData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&rd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal role data: %s", err), http.StatusBadRequest)
+		return
+	}
+	if !rd.Role.Valid() || rd.Role == rooms.Owner {
+		http.Error(w, fmt.Sprintf("Invalid role: %q", rd.Role), http.StatusBadRequest)
+		return
+	}
+	defer rms.locks.lock(validate.Key(name))()
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	prev := room
+	prevRole := room.Role(rd.Username)
+	requester := requestUsername(r)
+	if rd.Role == rooms.Moderator || prevRole == rooms.Moderator {
+		if requestRole(r) != AdminRole && (room.Owner == "" || validate.Key(requester) != validate.Key(room.Owner)) {
+			m := fmt.Sprintf("user %q may not change moderators of room %s", requester, name)
+			http.Error(w, fmt.Sprintf("Failed to set role: %s", m), http.StatusForbidden)
+			return
+		}
+	} else if validate.Key(rd.Username) != validate.Key(requester) {
+		err = moderate(r, room)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to set role: %s", err), httpStatus(err))
+			return
+		}
+	}
+	wasPlaying := room.Usernames[rd.Username]
+	room, err = rms.dao.SetRole(name, rd.Username, rd.Role)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to set role in datastore: %s", err), httpStatus(err))
+		return
+	}
+	if room.Usernames[rd.Username] != wasPlaying {
+		room, err = rms.syncGamePlayer(room, rd.Username, !wasPlaying)
+		if err != nil {
+			// Keep the room consistent with its game
+			if _, rerr := rms.dao.SetRole(name, rd.Username, prevRole); rerr != nil {
+				log.Printf("WARN: failed to roll back role of %s in room %s: %s", rd.Username, name, rerr)
+			}
+			http.Error(w, fmt.Sprintf("Failed to update room game: %s", err), httpStatus(err))
+			return
+		}
+	}
+	room = rms.syncPromoted(prev, room)
+	rms.publish(room)
+	enc := json.NewEncoder(w)
+	err = enc.Encode(roomView(room, requestRole(r)))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// SetOwner transfers ownership of the room to another user in it. Only the
+// owner may do so, or an admin.
+func (rms *Rooms) SetOwner(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	var pd playerData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&pd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal player data: %s", err), http.StatusBadRequest)
+		return
+	}
+	pd.Username, err = validate.Username(pd.Username)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to transfer ownership: %s", err), err)
+		return
+	}
+	defer rms.locks.lock(validate.Key(name))()
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	requester := requestUsername(r)
+	if requestRole(r) != AdminRole && (room.Owner == "" || validate.Key(requester) != validate.Key(room.Owner)) {
+		m := fmt.Sprintf("user %q is not the owner of room %s", requester, name)
+		http.Error(w, fmt.Sprintf("Failed to transfer ownership: %s", m), http.StatusForbidden)
+		return
+	}
+	// The new owner is named as they are in the room
+	username := room.SameUser(pd.Username)
+	if room.Role(username) == "" {
+		m := fmt.Sprintf("user %q is not in room %s", pd.Username, name)
+		http.Error(w, fmt.Sprintf("Failed to transfer ownership: %s", m), http.StatusNotFound)
+		return
+	}
+	room, err = rms.dao.SetOwner(name, username)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to transfer ownership in datastore: %s", err), httpStatus(err))
+		return
+	}
+	rms.publish(room)
+	enc := json.NewEncoder(w)
+	err = enc.Encode(roomView(room, requestRole(r)))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// capacityData is the payload of the capacity update PUT request
+type capacityData struct {
+	// MaxPlayers is the most players the room allows, 0 for no limit but
+	// that of the game type
+	MaxPlayers int `json:"maxPlayers"`
+}
+
+// SetCapacity sets the most players the room allows, promoting waiting
+// users into any places opened
+func (rms *Rooms) SetCapacity(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	var cd capacityData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&cd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal capacity data: %s", err), http.StatusBadRequest)
+		return
+	}
+	if cd.MaxPlayers < 0 {
+		http.Error(w, fmt.Sprintf("Invalid maxPlayers: %d", cd.MaxPlayers), http.StatusBadRequest)
+		return
+	}
+	defer rms.locks.lock(validate.Key(name))()
+	prev, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = moderate(r, prev)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to set capacity: %s", err), httpStatus(err))
+		return
+	}
+	room, err := rms.dao.SetMaxPlayers(name, cd.MaxPlayers)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to set capacity in datastore: %s", err), httpStatus(err))
+		return
+	}
+	room = rms.syncPromoted(prev, room)
+	rms.publish(room)
+	enc := json.NewEncoder(w)
+	err = enc.Encode(roomView(room, requestRole(r)))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// accessData is the payload of the access update PUT request
+type accessData struct {
+	// Private rooms are unlisted and joinable only by invite or password
+	Private bool `json:"private"`
+	// Password is the new room password, "" for none, or nil to keep the
+	// current one
+	Password *string `json:"password"`
+}
+
+// SetAccess sets whether the room is private and its password
+func (rms *Rooms) SetAccess(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	var ad accessData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&ad)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal access data: %s", err), http.StatusBadRequest)
+		return
+	}
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = moderate(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to set access: %s", err), httpStatus(err))
+		return
+	}
+	hash := room.PasswordHash
+	if ad.Password != nil {
+		hash = ""
+		if *ad.Password != "" {
+			hash, err = rooms.HashPassword(*ad.Password)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to hash room password: %s", err), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	room, err = rms.dao.SetAccess(name, ad.Private, hash)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to set access in datastore: %s", err), httpStatus(err))
+		return
+	}
+	rms.publish(room)
+	enc := json.NewEncoder(w)
+	err = enc.Encode(roomView(room, requestRole(r)))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// inviteData is the payload of the create invite response
+type inviteData struct {
+	// Code is the invite code. Only its hash is stored, so this is the
+	// one chance to see it.
+	Code string `json:"code"`
+	// Link is the path to which to POST player data, with the code as its
+	// invite, to join the room
+	Link string `json:"link"`
+}
+
+// CreateInvite generates a new invite code for the room
+func (rms *Rooms) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = moderate(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create invite: %s", err), httpStatus(err))
+		return
+	}
+	code, hash, err := rooms.NewInvite()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate invite code: %s", err), http.StatusInternalServerError)
+		return
+	}
+	_, err = rms.dao.AddInvite(name, hash)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to add invite into datastore: %s", err), httpStatus(err))
+		return
+	}
+	id := inviteData{
+		Code: code,
+		Link: fmt.Sprintf("/rooms/%s/players", url.PathEscape(name)),
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode invite: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// RevokeInvite revokes the invite code in the path, or all of the room's
+// invite codes if there is none
+func (rms *Rooms) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = moderate(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to revoke invite: %s", err), httpStatus(err))
+		return
+	}
+	var hash string
+	if code := router.GetField(r, 1); code != "" {
+		hash = rooms.HashInvite(code)
+	}
+	room, err = rms.dao.RevokeInvite(name, hash)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to revoke invite in datastore: %s", err), httpStatus(err))
+		return
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(roomView(room, requestRole(r)))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
 		return
@@ -211,15 +882,383 @@ func (rms *Rooms) SetGame(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to unmarshal game data: %s", err), http.StatusBadRequest)
 		return
 	}
-	room, err := rms.dao.SetGame(name, gd.Typ, gd.ID)
+	defer rms.locks.lock(validate.Key(name))()
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = moderate(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update game: %s", err), httpStatus(err))
+		return
+	}
+	err = rms.validateGame(room, gd.Typ, gd.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid game for room: %s", err), httpStatus(err))
+		return
+	}
+	if room.GameType != gd.Typ || room.GameID != gd.ID {
+		err = rms.endGame(room)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to end current game: %s", err), httpStatus(err))
+			return
+		}
+	}
+	room, err = rms.dao.SetGame(name, gd.Typ, gd.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update game: %s", err), httpStatus(err))
+		return
+	}
+	rms.publish(room)
+	enc := json.NewEncoder(w)
+	err = enc.Encode(roomView(room, requestRole(r)))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// newGameData is the payload of the create game request
+type newGameData struct {
+	GameType rooms.GameType `json:"gameType"`
+	// Options are the settings of the new game, as in the create request
+	// of the game type's own API, without the usernames
+	Options json.RawMessage `json:"options"`
+}
+
+// CreateGame starts a new game of the requested type with the players in
+// the room and makes it the room's current game
+func (rms *Rooms) CreateGame(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	var nd newGameData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&nd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal game data: %s", err), http.StatusBadRequest)
+		return
+	}
+	typ, err := rms.types.Get(nd.GameType)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unsupported game type: %d", nd.GameType), http.StatusBadRequest)
+		return
+	}
+	defer rms.locks.lock(validate.Key(name))()
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = moderate(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create game: %s", err), httpStatus(err))
+		return
+	}
+	err = checkCapacity(room, nd.GameType)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create game: %s", err), httpStatus(err))
+		return
+	}
+	err = rms.endGame(room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to end current game: %s", err), httpStatus(err))
+		return
+	}
+	game, err := typ.Create(roomUsernames(room), nd.Options)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create new game: %s", err), httpStatus(err))
+		return
+	}
+	room, err = rms.dao.SetGame(name, nd.GameType, game.GameID())
 	if err != nil {
+		// Don't orphan the new game
+		if derr := typ.Delete(game.GameID()); derr != nil {
+			log.Printf("WARN: failed to delete game %s orphaned from room %s: %s", game.GameID(), name, derr)
+		}
 		http.Error(w, fmt.Sprintf("Failed to update game: %s", err), httpStatus(err))
 		return
 	}
+	rms.publish(room)
+	enc := json.NewEncoder(w)
+	err = enc.Encode(roomView(room, requestRole(r)))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// EndGame ends the room's current game, recording its final scores in the
+// room's history
+func (rms *Rooms) EndGame(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	defer rms.locks.lock(validate.Key(name))()
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = moderate(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to end game: %s", err), httpStatus(err))
+		return
+	}
+	if room.CurrentGame() == nil {
+		http.Error(w, fmt.Sprintf("No current game in room %s", name), http.StatusConflict)
+		return
+	}
+	err = rms.endGame(room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to end current game: %s", err), httpStatus(err))
+		return
+	}
+	room, err = rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	rms.publish(room)
 	enc := json.NewEncoder(w)
-	err = enc.Encode(room)
+	err = enc.Encode(roomView(room, requestRole(r)))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode updated room: %s", err), http.StatusInternalServerError)
 		return
 	}
 }
+
+// endGame ends the room's current game, if any, with the final scores
+// from the game. A game that no longer exists is recorded without scores.
+func (rms *Rooms) endGame(room *rooms.Room) error {
+	cur := room.CurrentGame()
+	if cur == nil {
+		return nil
+	}
+	var scores map[string]int
+	if typ, err := rms.types.Get(cur.GameType); err == nil {
+		game, err := typ.Get(cur.GameID)
+		switch err.(type) {
+		case nil:
+			scores = game.Status().Scores
+		case daoerr.NotFoundError:
+		default:
+			return err
+		}
+	}
+	_, err := rms.dao.EndGame(room.Name, scores)
+	return err
+}
+
+// roomGameData is the payload of the room game response
+type roomGameData struct {
+	GameType rooms.GameType `json:"gameType"`
+	GameID   uuid.UUID      `json:"gameID"`
+	Status   games.Status   `json:"status"`
+	// View is the game as the requesting user sees it, in the form of the
+	// game type's own API
+	View interface{} `json:"view"`
+}
+
+// currentGame returns the Type and the game of the room's current game, or
+// a NotFoundError if it has none
+func (rms *Rooms) currentGame(room *rooms.Room) (games.Type, games.Game, error) {
+	if room.CurrentGame() == nil {
+		return nil, nil, daoerr.NotFoundError{Key: room.Name + "/game"}
+	}
+	typ, err := rms.types.Get(room.GameType)
+	if err != nil {
+		return nil, nil, err
+	}
+	game, err := typ.Get(room.GameID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return typ, game, nil
+}
+
+// writeGame writes the response to a room game request
+func writeGame(w http.ResponseWriter, r *http.Request, room *rooms.Room, game games.Game) {
+	gd := roomGameData{
+		GameType: room.GameType,
+		GameID:   room.GameID,
+		Status:   game.Status(),
+		View:     game.View(requestUsername(r)),
+	}
+	enc := json.NewEncoder(w)
+	err := enc.Encode(gd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode room game: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetGame returns the room's current game, of whichever type, as the
+// requesting user sees it
+func (rms *Rooms) GetGame(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = canView(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room game: %s", err), httpStatus(err))
+		return
+	}
+	_, game, err := rms.currentGame(room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room game: %s", err), httpStatus(err))
+		return
+	}
+	writeGame(w, r, room, game)
+}
+
+// historyData is the payload of the room games response
+type historyData struct {
+	// Games is the history of games played in the room, oldest first
+	Games []*rooms.GameRecord `json:"games"`
+	// Standings are the cumulative results of each user, best first
+	Standings []rooms.Standing `json:"standings"`
+}
+
+// Games returns the history of games played in the room and the
+// resulting standings
+func (rms *Rooms) Games(w http.ResponseWriter, r *http.Request) {
+	name := router.GetField(r, 0)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("Invalid room name %s:", router.GetField(r, 0)), http.StatusNotFound)
+		return
+	}
+	room, err := rms.dao.Get(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = canView(r, room)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get room games: %s", err), httpStatus(err))
+		return
+	}
+	hd := historyData{Games: room.Games, Standings: room.Standings()}
+	if hd.Games == nil {
+		hd.Games = []*rooms.GameRecord{}
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(hd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode room games: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// roomUsernames returns the usernames of the players in the room, sorted
+func roomUsernames(room *rooms.Room) []string {
+	usernames := make([]string, 0, len(room.Usernames))
+	for u := range room.Usernames {
+		usernames = append(usernames, u)
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
+// validateGame returns an error unless the game with the given type and id
+// exists and has the same players as the room
+func (rms *Rooms) validateGame(room *rooms.Room, typ rooms.GameType, id uuid.UUID) error {
+	if typ == rooms.None {
+		if id != uuid.Nil {
+			return badRequestError{fmt.Sprintf("game id %s given with no game type", id)}
+		}
+		return nil
+	}
+	t, err := rms.types.Get(typ)
+	if err != nil {
+		return err
+	}
+	err = checkCapacity(room, typ)
+	if err != nil {
+		return err
+	}
+	game, err := t.Get(id)
+	if err != nil {
+		return err
+	}
+	scores := game.Status().Scores
+	if len(scores) != len(room.Usernames) {
+		return badRequestError{fmt.Sprintf("game %s players do not match room %s", id, room.Name)}
+	}
+	for u := range room.Usernames {
+		if _, ok := scores[u]; !ok {
+			return badRequestError{fmt.Sprintf("game %s players do not match room %s", id, room.Name)}
+		}
+	}
+	return nil
+}
+
+// checkCapacity returns a badRequestError if the room has more players than
+// it allows for a game of the given type
+func checkCapacity(room *rooms.Room, typ rooms.GameType) error {
+	max := room.CapacityFor(typ)
+	if max > 0 && len(room.Usernames) > max {
+		return badRequestError{fmt.Sprintf("room %s has %d players, more than the %d allowed for game type %d",
+			room.Name, len(room.Usernames), max, typ)}
+	}
+	return nil
+}
+
+// syncPromoted propagates the joining of players promoted from the
+// waitlist, those in room but not in prev, to the room's current game, and
+// returns the room as updated. The change that promoted them stands
+// regardless, so failures are only logged.
+func (rms *Rooms) syncPromoted(prev, room *rooms.Room) *rooms.Room {
+	for _, u := range roomUsernames(room) {
+		if prev.Usernames[u] {
+			continue
+		}
+		next, err := rms.syncGamePlayer(room, u, true)
+		if err != nil {
+			log.Printf("WARN: failed to add player %s promoted from waitlist to room %s game: %s", u, room.Name, err)
+			continue
+		}
+		room = next
+	}
+	return room
+}
+
+// syncGamePlayer propagates the joining or leaving of the given player to
+// the room's current game, if any, through the game's log of actions, and
+// returns the room as updated. A leaving player's winnings, such as claimed
+// sets, are discarded. A current game that no longer exists, as once
+// expired, is ended without scores, so the room has no game to sync.
+func (rms *Rooms) syncGamePlayer(room *rooms.Room, username string, joined bool) (*rooms.Room, error) {
+	if room.GameType == rooms.None {
+		return room, nil
+	}
+	typ, game, err := rms.currentGame(room)
+	if _, ok := err.(daoerr.NotFoundError); ok {
+		return rms.dao.EndGame(room.Name, nil)
+	}
+	if err != nil {
+		return room, err
+	}
+	_, present := game.Status().Scores[username]
+	if joined == present {
+		return room, nil
+	}
+	a := games.Action{Type: games.LeaveAction, Player: username}
+	if joined {
+		a.Type = games.JoinAction
+	}
+	_, _, err = typ.Apply(game.GameID(), a)
+	return room, err
+}
@@ -9,20 +9,27 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	. "github.com/onsi/gomega"
 
 	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/dictionary"
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/games/boggle"
+	"github.com/bbawn/boredgames/internal/games/rrobots"
+	"github.com/bbawn/boredgames/internal/games/set"
 	"github.com/bbawn/boredgames/internal/rooms"
 	"github.com/bbawn/boredgames/internal/router"
 )
 
 func TestRooms(t *testing.T) {
 	g := NewGomegaWithT(t)
-	ram := ram.NewRooms()
 	tr := new(router.TableRouter)
-	RoomsAddRoutes(ram, tr)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(ram.NewSets(), ram.NewActions(), daoRooms, tr)), tr)
+	h := AdminAuth("secret", tr)
 
 	t.Log("List with no rooms")
 	resp := doRequest(tr, "GET", "http://example.com/rooms", nil)
@@ -32,35 +39,41 @@ func TestRooms(t *testing.T) {
 	g.Expect(strings.TrimSpace(string(body))).To(Equal(expBody))
 
 	t.Log("Create a room with no payload")
-	resp = doRequest(tr, "POST", "http://example.com/rooms", nil)
+	resp = doAuthRequest(h, "POST", "http://example.com/rooms", "Bearer secret", nil)
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
 	g.Expect(string(body)).To(HavePrefix("Failed to unmarshal create data:"))
 
 	t.Log("Create a room with invalid json payload")
 	d := `foo`
-	resp = doRequest(tr, "POST", "http://example.com/rooms", bytes.NewReader([]byte(d)))
+	resp = doAuthRequest(h, "POST", "http://example.com/rooms", "Bearer secret", bytes.NewReader([]byte(d)))
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
 	g.Expect(string(body)).To(HavePrefix("Failed to unmarshal create data:"))
 
 	t.Log("Create a room with no name")
 	d = `{ "usernames": {"p1": true, "p2": true } }`
-	resp = doRequest(tr, "POST", "http://example.com/rooms", bytes.NewReader([]byte(d)))
-	body, _ = ioutil.ReadAll(resp.Body)
+	resp = doAuthRequest(h, "POST", "http://example.com/rooms", "Bearer secret", bytes.NewReader([]byte(d)))
 	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
-	g.Expect(string(body)).To(Equal("Invalid request payload err: non-empty Name is required\n"))
+	g.Expect(decodeErrorData(t, resp)).To(Equal(errorData{
+		`Invalid request payload err: invalid name "": empty`, "name", "", "empty"}))
 
 	t.Log("Create a room with empty username")
 	d = `{ "name": "n1", "usernames": {"p1": true, "": true, "p3": true } }`
-	resp = doRequest(tr, "POST", "http://example.com/rooms", bytes.NewReader([]byte(d)))
-	body, _ = ioutil.ReadAll(resp.Body)
+	resp = doAuthRequest(h, "POST", "http://example.com/rooms", "Bearer secret", bytes.NewReader([]byte(d)))
 	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
-	g.Expect(string(body)).To(Equal("Invalid request payload err: usernames must be non-empty\n"))
+	g.Expect(decodeErrorData(t, resp)).To(Equal(errorData{
+		`Invalid request payload err: invalid usernames "": empty`, "usernames", "", "empty"}))
 
-	t.Log("Create a couple of valid rooms")
+	t.Log("Create a room with no session")
 	d = `{ "name": "n1", "usernames": {"p0": true, "p2": true} }`
-	resp = doRequest(tr, "POST", "http://example.com/rooms", bytes.NewReader([]byte(d)))
+	resp = doRequest(h, "POST", "http://example.com/rooms", bytes.NewReader([]byte(d)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(string(body)).To(Equal("Failed to create room: no session in request\n"))
+
+	t.Log("Create a couple of valid rooms")
+	resp = doAuthRequest(h, "POST", "http://example.com/rooms", "Bearer secret", bytes.NewReader([]byte(d)))
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
 	body, _ = ioutil.ReadAll(resp.Body)
 	var expRoom, r1 *rooms.Room
@@ -68,12 +81,14 @@ func TestRooms(t *testing.T) {
 	g.Expect(err).To(BeNil())
 	err = json.Unmarshal([]byte(d), &expRoom)
 	g.Expect(err).To(BeNil())
+	g.Expect(r1.LastActivity).NotTo(BeZero())
+	expRoom.LastActivity = r1.LastActivity
 	if !reflect.DeepEqual(expRoom, r1) {
 		t.Errorf("Post returned %#v, expected %#v", r1, expRoom)
 	}
 
 	d = `{ "name": "n2", "usernames": {"p1": true, "p2": true, "p3": true } }`
-	resp = doRequest(tr, "POST", "http://example.com/rooms", bytes.NewReader([]byte(d)))
+	resp = doAuthRequest(h, "POST", "http://example.com/rooms", "Bearer secret", bytes.NewReader([]byte(d)))
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
 	body, _ = ioutil.ReadAll(resp.Body)
 	var r2 *rooms.Room
@@ -82,10 +97,19 @@ func TestRooms(t *testing.T) {
 	expRoom = nil
 	err = json.Unmarshal([]byte(d), &expRoom)
 	g.Expect(err).To(BeNil())
+	expRoom.LastActivity = r2.LastActivity
 	if !reflect.DeepEqual(expRoom, r2) {
 		t.Errorf("Post returned %#v, expected %#v", r2, expRoom)
 	}
 
+	t.Log("Only admins moderate a room with no owner")
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/"+r2.Name, "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/"+r2.Name+"/owner", "p1", bytes.NewReader([]byte(`{ "username": "p1" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to transfer ownership: user \"p1\" is not the owner of room n2\n"))
+
 	t.Log("Fail to Get non-existent room")
 	uid := uuid.New()
 	resp = doRequest(tr, "GET", "http://example.com/rooms/"+uid.String(), nil)
@@ -124,14 +148,14 @@ func TestRooms(t *testing.T) {
 
 	t.Log("Fail to Delete non-existent room")
 	uid = uuid.New()
-	resp = doRequest(tr, "DEL", "http://example.com/rooms/"+uid.String(), nil)
+	resp = doAuthRequest(h, "DEL", "http://example.com/rooms/"+uid.String(), "Bearer secret", nil)
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
 	expBody = fmt.Sprintf("Failed to delete room from datastore: Key %s not found in datastore\n", uid.String())
 	g.Expect(string(body)).To(Equal(expBody))
 
 	t.Log("Delete a room")
-	resp = doRequest(tr, "DEL", "http://example.com/rooms/"+r2.Name, nil)
+	resp = doAuthRequest(h, "DEL", "http://example.com/rooms/"+r2.Name, "Bearer secret", nil)
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
 	g.Expect(string(body)).To(BeEmpty())
@@ -144,3 +168,799 @@ func roomMap(rs ...*rooms.Room) map[string]*rooms.Room {
 	}
 	return m
 }
+
+func TestRoomGames(t *testing.T) {
+	g := NewGomegaWithT(t)
+	daoSets := ram.NewSets()
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(daoSets, ram.NewActions(), daoRooms, tr)), tr)
+
+	d := `{ "name": "n1", "usernames": {"p1": true, "p2": true} }`
+	resp := doUserRequest(tr, "POST", "http://example.com/rooms", "o1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Create a game of unsupported type")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "o1", bytes.NewReader([]byte(`{ "gameType": 2 }`)))
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Unsupported game type: 2\n"))
+
+	t.Log("Create a game in a non-existent room")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n2/games", "o1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	t.Log("Create a Set game from the room")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "o1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var room *rooms.Room
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.GameType).To(Equal(rooms.Set))
+	game, err := daoSets.Get(room.GameID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).To(HaveLen(2))
+	g.Expect(game.Players).To(HaveKey("p1"))
+	g.Expect(game.Players).To(HaveKey("p2"))
+
+	t.Log("Player joining the room joins the game")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/players", "o1", bytes.NewReader([]byte(`{ "username": "p3" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	game, err = daoSets.Get(room.GameID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).To(HaveKey("p3"))
+
+	t.Log("Player leaving the room leaves the game")
+	resp = doUserRequest(tr, "DEL", "http://example.com/rooms/n1/players", "o1", bytes.NewReader([]byte(`{ "username": "p1" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	game, err = daoSets.Get(room.GameID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).NotTo(HaveKey("p1"))
+	g.Expect(game.Players).To(HaveLen(2))
+
+	t.Log("The room's game changes players and ends only through the room")
+	gameURL := "http://example.com/sets/" + room.GameID.String()
+	resp = doUserRequest(tr, "POST", gameURL+"/spectators", "q9", bytes.NewReader([]byte(`{ "username": "q9" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Failed to update game: players join and leave game %s through room n1\n", room.GameID)))
+	resp = doUserRequest(tr, "DEL", gameURL+"/players", "p2", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(tr, "DEL", gameURL, "o1", nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Failed to delete game: game %s is being played in room n1\n", room.GameID)))
+
+	t.Log("Player the game rejects is not added to the room")
+	game, err = daoSets.Get(room.GameID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.AddSpectator("q9")).To(Succeed())
+	g.Expect(daoSets.Update(game)).To(Succeed())
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/players", "o1", bytes.NewReader([]byte(`{ "username": "Q9" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to add player to room game: Invalid value: Q9 already present as q9 for arg: username\n"))
+	resp = doRequest(tr, "GET", "http://example.com/rooms/n1", nil)
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Usernames).To(Equal(map[string]bool{"p2": true, "p3": true}))
+
+	t.Log("Set a game that does not exist")
+	d = fmt.Sprintf(`{ "typ": 1, "id": "%s" }`, uuid.New())
+	resp = doUserRequest(tr, "PUT", "http://example.com/rooms/n1/game", "o1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	t.Log("Set a game whose players do not match the room")
+	other, _ := set.NewGame("p2", "p4")
+	g.Expect(daoSets.Insert(other)).To(Succeed())
+	d = fmt.Sprintf(`{ "typ": 1, "id": "%s" }`, other.ID)
+	resp = doUserRequest(tr, "PUT", "http://example.com/rooms/n1/game", "o1", bytes.NewReader([]byte(d)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Invalid game for room: game %s players do not match room n1\n", other.ID)))
+
+	t.Log("Set a game whose players match the room")
+	other, _ = set.NewGame("p2", "p3")
+	g.Expect(daoSets.Insert(other)).To(Succeed())
+	d = fmt.Sprintf(`{ "typ": 1, "id": "%s" }`, other.ID)
+	resp = doUserRequest(tr, "PUT", "http://example.com/rooms/n1/game", "o1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.GameID).To(Equal(other.ID))
+
+	t.Log("Player joining a room whose game no longer exists ends the game")
+	g.Expect(daoSets.Delete(other.ID)).To(Succeed())
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/players", "o1", bytes.NewReader([]byte(`{ "username": "p5" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.GameType).To(Equal(rooms.None))
+	g.Expect(room.Usernames).To(HaveKey("p5"))
+	last := room.Games[len(room.Games)-1]
+	g.Expect(last.GameID).To(Equal(other.ID))
+	g.Expect(last.End).NotTo(BeNil())
+	g.Expect(last.Scores).To(BeNil())
+
+	t.Log("Clear the room's game")
+	resp = doUserRequest(tr, "PUT", "http://example.com/rooms/n1/game", "o1", bytes.NewReader([]byte(`{ "typ": 0 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+}
+
+func TestRoomHistory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	daoSets := ram.NewSets()
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(daoSets, ram.NewActions(), daoRooms, tr)), tr)
+
+	d := `{ "name": "n1", "usernames": {"p1": true, "p2": true} }`
+	resp := doUserRequest(tr, "POST", "http://example.com/rooms", "o1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Empty history")
+	resp = doRequest(tr, "GET", "http://example.com/rooms/n1/games", nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(strings.TrimSpace(string(body))).To(Equal(`{"games":[],"standings":[]}`))
+
+	t.Log("End a game when there is none")
+	resp = doUserRequest(tr, "DEL", "http://example.com/rooms/n1/game", "o1", nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	g.Expect(string(body)).To(Equal("No current game in room n1\n"))
+
+	t.Log("Play a game in which p1 claims a set")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "o1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var room *rooms.Room
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	id1 := room.GameID
+	game, err := daoSets.Get(id1)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.ClaimSet("p1", *game.FindExpandSet())).To(Succeed())
+	g.Expect(daoSets.Update(game)).To(Succeed())
+
+	t.Log("Starting another game ends the first")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "o1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	id2 := room.GameID
+	g.Expect(id2).NotTo(Equal(id1))
+
+	t.Log("End the second game explicitly")
+	resp = doUserRequest(tr, "DEL", "http://example.com/rooms/n1/game", "o1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.GameType).To(Equal(rooms.None))
+
+	t.Log("History and standings")
+	resp = doRequest(tr, "GET", "http://example.com/rooms/n1/games", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var hd historyData
+	g.Expect(json.NewDecoder(resp.Body).Decode(&hd)).To(Succeed())
+	g.Expect(hd.Games).To(HaveLen(2))
+	g.Expect(hd.Games[0].GameID).To(Equal(id1))
+	g.Expect(hd.Games[0].Scores).To(Equal(map[string]int{"p1": 1, "p2": 0}))
+	g.Expect(hd.Games[0].Winners).To(Equal([]string{"p1"}))
+	g.Expect(hd.Games[0].End).NotTo(BeNil())
+	g.Expect(hd.Games[1].GameID).To(Equal(id2))
+	g.Expect(hd.Games[1].Winners).To(BeEmpty())
+	g.Expect(hd.Standings).To(Equal([]rooms.Standing{
+		{Username: "p1", Games: 2, Wins: 1, Points: 1},
+		{Username: "p2", Games: 2, Wins: 0, Points: 0},
+	}))
+
+	t.Log("Past games remain in the datastore")
+	_, err = daoSets.Get(id1)
+	g.Expect(err).To(BeNil())
+
+	t.Log("End a game that no longer exists")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "o1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	id3 := room.GameID
+	g.Expect(daoSets.Delete(id3)).To(Succeed())
+	resp = doUserRequest(tr, "DEL", "http://example.com/rooms/n1/game", "o1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.GameType).To(Equal(rooms.None))
+	g.Expect(room.Games).To(HaveLen(3))
+	g.Expect(room.Games[2].GameID).To(Equal(id3))
+	g.Expect(room.Games[2].End).NotTo(BeNil())
+	g.Expect(room.Games[2].Scores).To(BeNil())
+}
+
+func TestRoomModeration(t *testing.T) {
+	g := NewGomegaWithT(t)
+	daoSets := ram.NewSets()
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(daoSets, ram.NewActions(), daoRooms, tr)), tr)
+	h := AdminAuth("secret", tr)
+
+	t.Log("Create a room owned by the requesting user")
+	d := `{ "name": "n1", "usernames": {"p1": true, "p2": true, "p3": true} }`
+	resp := doUserRequest(h, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var room *rooms.Room
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Owner).To(Equal("p1"))
+
+	t.Log("Only admins may create a room owned by another user")
+	d = `{ "name": "n2", "owner": "p2" }`
+	resp = doUserRequest(h, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to create room: user \"p1\" may not create a room owned by \"p2\"\n"))
+	resp = doRequest(h, "POST", "http://example.com/rooms", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	resp = doAuthRequest(h, "POST", "http://example.com/rooms", "Bearer secret", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Owner).To(Equal("p2"))
+
+	t.Log("Players may not moderate")
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/n1", "p2", nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to delete room: user \"p2\" may not moderate room n1\n"))
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/n1/players", "p2", bytes.NewReader([]byte(`{ "username": "p3" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/games", "p2", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/game", "p2", bytes.NewReader([]byte(`{ "typ": 0 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doRequest(h, "DEL", "http://example.com/rooms/n1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+	t.Log("Only the owner may appoint moderators")
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/roles", "p2", bytes.NewReader([]byte(`{ "username": "p2", "role": "moderator" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to set role: user \"p2\" may not change moderators of room n1\n"))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/roles", "p1", bytes.NewReader([]byte(`{ "username": "p2", "role": "owner" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/roles", "p1", bytes.NewReader([]byte(`{ "username": "p2", "role": "moderator" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Role("p2")).To(Equal(rooms.Moderator))
+
+	t.Log("Moderators may start games and kick players")
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/games", "p2", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	gameID := room.GameID
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/n1/players", "p2", bytes.NewReader([]byte(`{ "username": "p3" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Players may leave and become spectators themselves")
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/players", "p4", bytes.NewReader([]byte(`{ "username": "p4" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/roles", "p4", bytes.NewReader([]byte(`{ "username": "p4", "role": "spectator" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Role("p4")).To(Equal(rooms.Spectator))
+	game, err := daoSets.Get(gameID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).NotTo(HaveKey("p4"))
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/n1/players", "p4", bytes.NewReader([]byte(`{ "username": "p4" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Only the owner may transfer ownership")
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/owner", "p2", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to transfer ownership: user \"p2\" is not the owner of room n1\n"))
+	resp = doRequest(h, "PUT", "http://example.com/rooms/n1/owner", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/owner", "p1", bytes.NewReader([]byte(`{ "username": "" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/owner", "p1", bytes.NewReader([]byte(`{ "username": "p9" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	g.Expect(string(body)).To(Equal("Failed to transfer ownership: user \"p9\" is not in room n1\n"))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/owner", "P1", bytes.NewReader([]byte(`{ "username": "P2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Owner).To(Equal("p2"))
+	g.Expect(room.Role("p1")).To(Equal(rooms.Moderator))
+
+	t.Log("Users are matched but for case")
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/roles", "P2", bytes.NewReader([]byte(`{ "username": "p1", "role": "player" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/n1/players", "P1", bytes.NewReader([]byte(`{ "username": "p1" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Role("p1")).To(BeEmpty())
+
+	t.Log("Admins may moderate any room")
+	resp = doAuthRequest(h, "DEL", "http://example.com/rooms/n1", "Bearer secret", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+}
+
+func TestPrivateRooms(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(ram.NewSets(), ram.NewActions(), daoRooms, tr)), tr)
+	h := AdminAuth("secret", tr)
+
+	t.Log("Create a private room and a public one with a password")
+	d := `{ "name": "n1", "usernames": {"p1": true}, "private": true }`
+	resp := doUserRequest(h, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	d = `{ "name": "n2", "usernames": {"p1": true}, "password": "sekrit" }`
+	resp = doUserRequest(h, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var room *rooms.Room
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.PasswordHash).To(BeEmpty())
+	g.Expect(room.HasPassword).To(BeTrue())
+
+	t.Log("Private rooms are listed only to their users and admins")
+	listNames := func(resp *http.Response) []string {
+		var rs []*rooms.Room
+		g.Expect(json.NewDecoder(resp.Body).Decode(&rs)).To(Succeed())
+		var names []string
+		for _, r := range rs {
+			g.Expect(r.PasswordHash).To(BeEmpty())
+			names = append(names, r.Name)
+		}
+		return names
+	}
+	resp = doUserRequest(h, "GET", "http://example.com/rooms", "p2", nil)
+	g.Expect(listNames(resp)).To(ConsistOf("n2"))
+	resp = doUserRequest(h, "GET", "http://example.com/rooms", "p1", nil)
+	g.Expect(listNames(resp)).To(ConsistOf("n1", "n2"))
+	resp = doAuthRequest(h, "GET", "http://example.com/rooms", "Bearer secret", nil)
+	var rs []*rooms.Room
+	g.Expect(json.NewDecoder(resp.Body).Decode(&rs)).To(Succeed())
+	g.Expect(rs).To(HaveLen(2))
+	for _, r := range rs {
+		if r.Name == "n2" {
+			g.Expect(r.PasswordHash).NotTo(BeEmpty())
+		}
+	}
+
+	t.Log("Private rooms and their games are shown only to their users and admins")
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/games", "p1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var rgd struct {
+		GameID uuid.UUID `json:"gameID"`
+	}
+	g.Expect(json.NewDecoder(resp.Body).Decode(&rgd)).To(Succeed())
+	resp = doUserRequest(h, "GET", "http://example.com/rooms/n1", "p2", nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to get room: user \"p2\" is not in private room n1\n"))
+	resp = doUserRequest(h, "GET", "http://example.com/rooms/n1/games", "p2", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "GET", "http://example.com/rooms/n1", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "GET", "http://example.com/rooms/n1/games", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	gameURL := fmt.Sprintf("http://example.com/sets/%s", rgd.GameID)
+	resp = doUserRequest(h, "GET", gameURL, "p2", nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to get game: user \"p2\" is not in private room n1\n"))
+	resp = doUserRequest(h, "GET", gameURL+"/events", "p2", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "GET", gameURL, "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doAuthRequest(h, "GET", gameURL, "Bearer secret", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	listGames := func(resp *http.Response) []json.RawMessage {
+		var views []json.RawMessage
+		g.Expect(json.NewDecoder(resp.Body).Decode(&views)).To(Succeed())
+		return views
+	}
+	resp = doUserRequest(h, "GET", "http://example.com/sets", "p2", nil)
+	g.Expect(listGames(resp)).To(BeEmpty())
+	resp = doUserRequest(h, "GET", "http://example.com/sets", "p1", nil)
+	g.Expect(listGames(resp)).To(HaveLen(1))
+
+	t.Log("Joining a restricted room requires the password or an invite")
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n2/players", "p2", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to add player: room n2 requires a valid password or invite code\n"))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n2/players", "p2", bytes.NewReader([]byte(`{ "username": "p2", "password": "wrong" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n2/players", "p2", bytes.NewReader([]byte(`{ "username": "p2", "password": "sekrit" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Users in a restricted room need no password to join only as themselves")
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n2/players", "p2", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n2/players", "p9", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doRequest(h, "POST", "http://example.com/rooms/n2/players", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/players", "p2", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/invites", "p2", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/invites", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var invite inviteData
+	g.Expect(json.NewDecoder(resp.Body).Decode(&invite)).To(Succeed())
+	g.Expect(invite.Link).To(Equal("/rooms/n1/players"))
+	resp = doUserRequest(h, "POST", "http://example.com"+invite.Link+"?invite="+invite.Code, "p2", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "POST", "http://example.com"+invite.Link, "p2", bytes.NewReader([]byte(`{ "username": "p2", "invite": "`+invite.Code+`" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Usernames).To(HaveKey("p2"))
+	g.Expect(room.Invites).To(BeNil())
+
+	t.Log("Revoked invites no longer admit")
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/n1/invites/"+invite.Code, "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/n1/invites/"+invite.Code, "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/players", "p3", bytes.NewReader([]byte(`{ "username": "p3", "invite": "`+invite.Code+`" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+	t.Log("Owners may add players and open up the room")
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/players", "p1", bytes.NewReader([]byte(`{ "username": "p3" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/access", "p3", bytes.NewReader([]byte(`{ "private": false }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/access", "p1", bytes.NewReader([]byte(`{ "private": false }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/players", "p4", bytes.NewReader([]byte(`{ "username": "p4" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Changing the password keeps or clears it as requested")
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n2/access", "p1", bytes.NewReader([]byte(`{ "private": true }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Private).To(BeTrue())
+	g.Expect(room.HasPassword).To(BeTrue())
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n2/access", "p1", bytes.NewReader([]byte(`{ "private": false, "password": "" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.HasPassword).To(BeFalse())
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n2/players", "p5", bytes.NewReader([]byte(`{ "username": "p5" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+}
+
+func TestRoomCapacity(t *testing.T) {
+	g := NewGomegaWithT(t)
+	daoSets := ram.NewSets()
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(daoSets, ram.NewActions(), daoRooms, tr)), tr)
+	h := AdminAuth("secret", tr)
+
+	d := `{ "name": "n1", "usernames": {"p1": true, "p2": true, "p3": true}, "maxPlayers": 2 }`
+	resp := doUserRequest(h, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	d = `{ "name": "n1", "usernames": {"p1": true, "p2": true}, "maxPlayers": 2 }`
+	resp = doUserRequest(h, "POST", "http://example.com/rooms", "p1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/games", "p1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var room *rooms.Room
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	gameID := room.GameID
+
+	t.Log("Players joining a full room are waitlisted")
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/players", "p3", bytes.NewReader([]byte(`{ "username": "p3" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Waitlist).To(Equal([]string{"p3"}))
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/players", "p4", bytes.NewReader([]byte(`{ "username": "p4" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+	game, err := daoSets.Get(gameID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).To(HaveLen(2))
+
+	t.Log("Leaving promotes the first waiting player into the game")
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/n1/players", "p2", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Usernames).To(Equal(map[string]bool{"p1": true, "p3": true}))
+	g.Expect(room.Waitlist).To(Equal([]string{"p4"}))
+	game, err = daoSets.Get(gameID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).To(HaveKey("p3"))
+	g.Expect(game.Players).NotTo(HaveKey("p2"))
+
+	t.Log("Only moderators may change the capacity")
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/capacity", "p3", bytes.NewReader([]byte(`{ "maxPlayers": 3 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/capacity", "p1", bytes.NewReader([]byte(`{ "maxPlayers": -1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/capacity", "p1", bytes.NewReader([]byte(`{ "maxPlayers": 3 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	room = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Usernames).To(HaveKey("p4"))
+	g.Expect(room.Waitlist).To(BeNil())
+	game, err = daoSets.Get(gameID)
+	g.Expect(err).To(BeNil())
+	g.Expect(game.Players).To(HaveKey("p4"))
+
+	t.Log("Games may not start with more players than the game type allows")
+	resp = doUserRequest(h, "PUT", "http://example.com/rooms/n1/capacity", "p1", bytes.NewReader([]byte(`{ "maxPlayers": 0 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "DEL", "http://example.com/rooms/n1/game", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	for i := 0; i < rooms.GameMaxPlayers[rooms.Set]; i++ {
+		u := fmt.Sprintf("q%d", i)
+		resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/players", u, bytes.NewReader([]byte(`{ "username": "`+u+`" }`)))
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+	resp = doUserRequest(h, "POST", "http://example.com/rooms/n1/games", "p1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to create game: room n1 has 11 players, more than the 8 allowed for game type 1\n"))
+}
+
+func TestListRoomsQuery(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tr := new(router.TableRouter)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(ram.NewSets(), ram.NewActions(), daoRooms, tr)), tr)
+
+	t.Log("Create rooms of different sizes, one playing Set and one full")
+	for _, d := range []string{
+		`{ "name": "beta", "usernames": {"p1": true, "p2": true, "p3": true} }`,
+		`{ "name": "Alpha", "usernames": {"p1": true}, "maxPlayers": 1 }`,
+		`{ "name": "alps", "usernames": {"p1": true, "p2": true} }`,
+		`{ "name": "gamma", "usernames": {} }`,
+	} {
+		resp := doUserRequest(tr, "POST", "http://example.com/rooms", "o1", bytes.NewReader([]byte(d)))
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+	resp := doUserRequest(tr, "POST", "http://example.com/rooms/alps/games", "o1", bytes.NewReader([]byte(`{ "gameType": 1 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	listNames := func(target string) ([]string, string) {
+		resp := doRequest(tr, "GET", target, nil)
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var rs []*rooms.Room
+		g.Expect(json.NewDecoder(resp.Body).Decode(&rs)).To(Succeed())
+		names := []string{}
+		for _, r := range rs {
+			names = append(names, r.Name)
+		}
+		return names, resp.Header.Get("Link")
+	}
+
+	t.Log("Rooms are listed by name by default")
+	names, link := listNames("http://example.com/rooms")
+	g.Expect(names).To(Equal([]string{"Alpha", "alps", "beta", "gamma"}))
+	g.Expect(link).To(BeEmpty())
+
+	t.Log("Filter by name prefix, game type and open seats")
+	names, _ = listNames("http://example.com/rooms?prefix=AL")
+	g.Expect(names).To(Equal([]string{"Alpha", "alps"}))
+	names, _ = listNames("http://example.com/rooms?gameType=1")
+	g.Expect(names).To(Equal([]string{"alps"}))
+	names, _ = listNames("http://example.com/rooms?gameType=0&open=true")
+	g.Expect(names).To(Equal([]string{"beta", "gamma"}))
+
+	t.Log("Sort by size")
+	names, _ = listNames("http://example.com/rooms?sort=size")
+	g.Expect(names).To(Equal([]string{"beta", "alps", "Alpha", "gamma"}))
+
+	t.Log("Page through rooms with the next link")
+	names, link = listNames("http://example.com/rooms?sort=size&limit=3")
+	g.Expect(names).To(Equal([]string{"beta", "alps", "Alpha"}))
+	g.Expect(link).To(MatchRegexp(`^</rooms\?.*cursor=.*>; rel="next"$`))
+	next := strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+	names, link = listNames("http://example.com" + next)
+	g.Expect(names).To(Equal([]string{"gamma"}))
+	g.Expect(link).To(BeEmpty())
+
+	t.Log("Invalid query parameters")
+	for _, q := range []string{"gameType=x", "gameType=9", "open=maybe", "sort=color", "limit=0", "limit=201", "cursor=%21"} {
+		resp = doRequest(tr, "GET", "http://example.com/rooms?"+q, nil)
+		g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), q)
+	}
+
+	t.Log("A cursor is only valid for the sort it came from")
+	_, link = listNames("http://example.com/rooms?limit=1")
+	next = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+	next = strings.Replace(next, "limit=1", "limit=1&sort=size", 1)
+	resp = doRequest(tr, "GET", "http://example.com"+next, nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(ContainSubstring("cursor is for sort name, not size"))
+}
+
+func TestRoomGenericGame(t *testing.T) {
+	g := NewGomegaWithT(t)
+	dicts, err := dictionary.NewRegistry()
+	g.Expect(err).To(BeNil())
+	tr := new(router.TableRouter)
+	actions := ram.NewActions()
+	daoRooms := ram.NewRooms()
+	types := games.NewRegistry()
+	types.Register(rooms.Set, SetsAddRoutes(ram.NewSets(), actions, daoRooms, tr).GameType())
+	types.Register(rooms.Boggle, BogglesAddRoutes(ram.NewBoggles(), actions, daoRooms, dicts, tr).GameType())
+	rr := RRobotsAddRoutes(ram.NewRRobots(), actions, daoRooms, tr)
+	types.Register(rooms.RRobots, rr.GameType())
+	rms := RoomsAddRoutes(daoRooms, ram.NewMessages(), types, tr)
+	GamesAddRoutes(types, rms, actions, tr)
+
+	// Control the clock and the countdown timers
+	clock := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	var timers []func()
+	rr.now = func() time.Time { return clock }
+	rr.afterFunc = func(d time.Duration, f func()) { timers = append(timers, f) }
+
+	// roomGame is roomGameData with the view left to decode by game type
+	type roomGame struct {
+		GameType rooms.GameType  `json:"gameType"`
+		GameID   uuid.UUID       `json:"gameID"`
+		Status   games.Status    `json:"status"`
+		View     json.RawMessage `json:"view"`
+	}
+	decodeGame := func(resp *http.Response) roomGame {
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var rg roomGame
+		g.Expect(json.NewDecoder(resp.Body).Decode(&rg)).To(Succeed())
+		return rg
+	}
+	// Actions are applied to the current game through the games service
+	var gameID uuid.UUID
+	act := func(username, action, payload string) *http.Response {
+		d := fmt.Sprintf(`{ "type": %q, "player": %q, "payload": %s }`, action, username, payload)
+		return doUserRequest(tr, "POST", fmt.Sprintf("http://example.com/games/%s/actions", gameID), username, bytes.NewReader([]byte(d)))
+	}
+	decodeAction := func(resp *http.Response) roomGame {
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var ard struct {
+			Status games.Status    `json:"status"`
+			View   json.RawMessage `json:"view"`
+		}
+		g.Expect(json.NewDecoder(resp.Body).Decode(&ard)).To(Succeed())
+		return roomGame{GameID: gameID, Status: ard.Status, View: ard.View}
+	}
+
+	d := `{ "name": "n1", "usernames": {"p1": true, "p2": true} }`
+	resp := doUserRequest(tr, "POST", "http://example.com/rooms", "o1", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	t.Log("Get the game of a room with none")
+	resp = doRequest(tr, "GET", "http://example.com/rooms/n1/game", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	resp = act("p1", "skip", "null")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	t.Log("Create a RRobots game with options")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "o1", bytes.NewReader([]byte(`{ "gameType": 3, "options": { "seed": "x" } }`)))
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(HavePrefix("Failed to create new game: invalid options:"))
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "o1", bytes.NewReader([]byte(`{ "gameType": 3, "options": { "seed": 42 } }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	rg := decodeGame(doUserRequest(tr, "GET", "http://example.com/rooms/n1/game", "p1", nil))
+	g.Expect(rg.GameType).To(Equal(rooms.RRobots))
+	gameID = rg.GameID
+	g.Expect(rg.Status).To(Equal(games.Status{State: "Bidding", Scores: map[string]int{"p1": 0, "p2": 0}}))
+	var v *rrobots.View
+	g.Expect(json.Unmarshal(rg.View, &v)).To(Succeed())
+	g.Expect(v.ID).To(Equal(rg.GameID))
+	moves := rrobots.NewSolver(v.Board, v.Target).Solve(v.Robots, rrobots.MaxSolveMoves)
+	g.Expect(moves).NotTo(BeNil())
+
+	t.Log("Apply invalid actions")
+	resp = act("p9", "bid", `{ "moves": 1 }`)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Failed to apply action: user \"p9\" is not a player in game %s\n", gameID)))
+	resp = act("p1", "fly", "null")
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to apply action: Invalid action: fly detail: no such action\n"))
+	resp = act("p1", "bid", "null")
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to apply action: Invalid action: bid detail: missing payload\n"))
+	resp = act("p1", "bid", `{ "moves": "x" }`)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	resp = act("p1", "next", "null")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+	t.Log("Play a round")
+	rg = decodeAction(act("p1", "bid", fmt.Sprintf(`{ "moves": %d }`, len(moves))))
+	g.Expect(rg.Status.State).To(Equal("Countdown"))
+	g.Expect(timers).To(HaveLen(1))
+	clock = clock.Add(rrobots.CountdownDuration)
+	payload, err := json.Marshal(rrobots.DemonstratePayload{Moves: moves})
+	g.Expect(err).To(BeNil())
+	rg = decodeAction(act("p1", "demonstrate", string(payload)))
+	g.Expect(rg.Status).To(Equal(games.Status{State: "RoundOver", Scores: map[string]int{"p1": 1, "p2": 0}}))
+
+	t.Log("Players leaving the room leave the game")
+	resp = doUserRequest(tr, "DEL", "http://example.com/rooms/n1/players", "o1", bytes.NewReader([]byte(`{ "username": "p2" }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	rg = decodeGame(doRequest(tr, "GET", "http://example.com/rooms/n1/game", nil))
+	g.Expect(rg.Status.Scores).To(Equal(map[string]int{"p1": 1}))
+
+	t.Log("The game's log records the players the room adds and removes")
+	resp = doRequest(tr, "GET", fmt.Sprintf("http://example.com/games/%s/actions", gameID), nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var results []*games.Result
+	g.Expect(json.NewDecoder(resp.Body).Decode(&results)).To(Succeed())
+	last := results[len(results)-1]
+	g.Expect(last.Action).To(Equal(games.Action{Type: games.LeaveAction, Player: "p2"}))
+	g.Expect(last.Version).To(Equal(results[len(results)-2].Version + 1))
+	resp = act("p1", games.LeaveAction, "null")
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Failed to apply action: players join and leave game %s through room n1\n", gameID)))
+
+	t.Log("Starting a Boggle game ends the RRobots game with its scores")
+	resp = doUserRequest(tr, "POST", "http://example.com/rooms/n1/games", "o1", bytes.NewReader([]byte(`{ "gameType": 2, "options": { "size": 5 } }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = act("p1", "next", "null")
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Failed to apply action: game %s is over in room n1\n", gameID)))
+	resp = doRequest(tr, "GET", "http://example.com/rooms/n1/games", nil)
+	var hd historyData
+	g.Expect(json.NewDecoder(resp.Body).Decode(&hd)).To(Succeed())
+	g.Expect(hd.Games).To(HaveLen(2))
+	g.Expect(hd.Games[0].Scores).To(Equal(map[string]int{"p1": 1}))
+
+	t.Log("Play a Boggle round")
+	rg = decodeGame(doUserRequest(tr, "GET", "http://example.com/rooms/n1/game", "p1", nil))
+	g.Expect(rg.GameType).To(Equal(rooms.Boggle))
+	gameID = rg.GameID
+	g.Expect(rg.Status.State).To(Equal("Playing"))
+	var bv *boggle.View
+	g.Expect(json.Unmarshal(rg.View, &bv)).To(Succeed())
+	g.Expect(bv.Size).To(Equal(boggle.BigSize))
+	resp = act("p1", "word", `{ "word": "QQQQ" }`)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	resp = act("p1", "bid", `{ "moves": 1 }`)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	listWord := func(username string) *games.Result {
+		resp := doUserRequest(tr, "GET", fmt.Sprintf("http://example.com/games/%s/actions", gameID), username, nil)
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var results []*games.Result
+		g.Expect(json.NewDecoder(resp.Body).Decode(&results)).To(Succeed())
+		for _, r := range results {
+			if r.Action.Type == boggle.WordAction {
+				return r
+			}
+		}
+		return nil
+	}
+
+	t.Log("Only its player sees a word until the round is over")
+	r := listWord("p1")
+	g.Expect(r.Action.Payload).To(MatchJSON(`{ "word": "QQQQ" }`))
+	g.Expect(r.Action.Seed).To(BeZero())
+	r = listWord("p2")
+	g.Expect(r.Action.Payload).To(BeNil())
+	g.Expect(r.Error).To(Equal("hidden until the round is over"))
+	rg = decodeAction(act("p1", "end", "null"))
+	g.Expect(rg.Status.State).To(Equal("RoundOver"))
+	g.Expect(json.Unmarshal(rg.View, &bv)).To(Succeed())
+	g.Expect(bv.Solution).NotTo(BeEmpty())
+	r = listWord("p2")
+	g.Expect(r.Action.Payload).To(MatchJSON(`{ "word": "QQQQ" }`))
+}
@@ -0,0 +1,495 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bbawn/boredgames/internal/dao"
+	"github.com/bbawn/boredgames/internal/events"
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/games/rrobots"
+	"github.com/bbawn/boredgames/internal/router"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// RRobots provides the REST API for the Ricochet Robots game
+type RRobots struct {
+	dao dao.RRobots
+	// events publishes updated games to their event stream subscribers,
+	// keyed on game ID and viewer role
+	events *events.Broker
+	// now returns the current time and afterFunc calls f after d, as
+	// time.Now and time.AfterFunc do, unless replaced by tests
+	now       func() time.Time
+	afterFunc func(d time.Duration, f func())
+	// locks serialize the updates to each game, including the ends of
+	// their countdowns, keyed on the game ID
+	locks keyLocks
+	// actions apply and record every change to the games
+	actions *actionLog
+	// rooms are where the games may be played, whose access rules apply
+	// to them
+	rooms dao.Rooms
+}
+
+// RRobotsAddRoutes adds the routes for this service to the given router and
+// returns the service
+func RRobotsAddRoutes(dao dao.RRobots, actions dao.Actions, rms dao.Rooms, router *router.TableRouter) *RRobots {
+	rr := &RRobots{
+		dao:     dao,
+		events:  events.NewBroker(),
+		actions: &actionLog{dao: actions},
+		rooms:   rms,
+		now:     func() time.Time { return time.Now().UTC().Round(0) },
+		afterFunc: func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		},
+	}
+	router.AddRoute("GET", "/rrobots", http.HandlerFunc(rr.List))
+	router.AddRoute("POST", "/rrobots", http.HandlerFunc(rr.Create))
+	router.AddRoute("GET", "/rrobots/([^/]+)", http.HandlerFunc(rr.Get))
+	router.AddRoute("DEL", "/rrobots/([^/]+)", http.HandlerFunc(rr.Delete))
+	router.AddRoute("POST", "/rrobots/([^/]+)/bids", http.HandlerFunc(rr.Bid))
+	router.AddRoute("POST", "/rrobots/([^/]+)/demonstrations", http.HandlerFunc(rr.Demonstrate))
+	router.AddRoute("POST", "/rrobots/([^/]+)/skip", http.HandlerFunc(rr.Skip))
+	router.AddRoute("POST", "/rrobots/([^/]+)/next", http.HandlerFunc(rr.Next))
+	router.AddRoute("GET", "/rrobots/([^/]+)/events", http.HandlerFunc(rr.Events))
+	router.AddRoute("POST", "/rrobots/([^/]+)/players", http.HandlerFunc(rr.AddPlayer))
+	router.AddRoute("DEL", "/rrobots/([^/]+)/players", http.HandlerFunc(rr.DeletePlayer))
+	router.AddRoute("POST", "/rrobots/([^/]+)/spectators", http.HandlerFunc(rr.AddSpectator))
+	router.AddRoute("DEL", "/rrobots/([^/]+)/spectators", http.HandlerFunc(rr.DeleteSpectator))
+	return rr
+}
+
+// rrobotsView returns the projection of the game presented to the client
+// making the request: the full game for admins, otherwise the View, which
+// hides the order of the targets to come
+func rrobotsView(game *rrobots.Game, r *http.Request) interface{} {
+	if requestRole(r) == AdminRole {
+		return game
+	}
+	return game.View()
+}
+
+// publish sends the given updated game to its event stream subscribers:
+// the full game to admins and the View to everyone else
+func (rr *RRobots) publish(game *rrobots.Game) {
+	views := map[Role]interface{}{PublicRole: game.View(), AdminRole: game}
+	for role, view := range views {
+		data, err := json.Marshal(view)
+		if err != nil {
+			log.Printf("WARN: failed to encode rrobots game %s for publish: %s", game.ID, err)
+			return
+		}
+		rr.events.Publish(eventTopic(game.ID, role), data)
+	}
+}
+
+func (rr *RRobots) List(w http.ResponseWriter, r *http.Request) {
+	games, err := rr.dao.List()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load games from datastore: %s", err), httpStatus(err))
+		return
+	}
+	// Games in rooms the client may not view are hidden from it
+	hidden, err := hiddenGames(r, rr.rooms)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load rooms from datastore: %s", err), httpStatus(err))
+		return
+	}
+	visible := games[:0]
+	for _, game := range games {
+		if !hidden[game.ID] {
+			visible = append(visible, game)
+		}
+	}
+	games = visible
+	for i, game := range games {
+		if !rr.due(game) {
+			continue
+		}
+		games[i], err = rr.getSettled(game.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to settle game in datastore: %s", err), httpStatus(err))
+			return
+		}
+	}
+	enc := json.NewEncoder(w)
+	views := make([]interface{}, len(games))
+	for i, game := range games {
+		views[i] = rrobotsView(game, r)
+	}
+	err = enc.Encode(views)
+	if err != nil {
+		m := fmt.Sprintf("Failed to encode games from datastore: %s", err)
+		http.Error(w, m, http.StatusInternalServerError)
+		return
+	}
+}
+
+// rrobotsOptions are the settings of a new rrobots game
+type rrobotsOptions struct {
+	// Seed lays out the board, places the robots and shuffles the targets,
+	// at random if 0
+	Seed int64 `json:"seed"`
+}
+
+// rrobotsCreateData is the payload of the create rrobots game request
+type rrobotsCreateData struct {
+	Usernames []string `json:"usernames"`
+	rrobotsOptions
+}
+
+func (rr *RRobots) Create(w http.ResponseWriter, r *http.Request) {
+	var cd rrobotsCreateData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&cd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal create data: %s", err), http.StatusBadRequest)
+		return
+	}
+	game, err := rrobots.NewGameOptions(rrobots.Options{Seed: cd.Seed}, cd.Usernames...)
+	if err != nil {
+		if _, ok := err.(validate.Error); !ok {
+			err = badRequestError{err.Error()}
+		}
+		httpError(w, fmt.Sprintf("Failed to create new game: %s", err), err)
+		return
+	}
+	err = rr.dao.Insert(game)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to insert game into datastore: %s", err), httpStatus(err))
+		return
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(rrobotsView(game, r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode new game: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (rr *RRobots) Get(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid rrobots uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	_, err = viewRoom(r, rr.rooms, uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game: %s", err), httpStatus(err))
+		return
+	}
+	game, err := rr.getSettled(uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
+		return
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(rrobotsView(game, r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode game from datastore: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (rr *RRobots) Delete(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid rrobots uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	err = deletable(rr.rooms, uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete game: %s", err), httpStatus(err))
+		return
+	}
+	err = rr.dao.Delete(uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete game from datastore: %s", err), httpStatus(err))
+		return
+	}
+}
+
+// bidData is the payload of the bid request
+type bidData struct {
+	Username string `json:"username"`
+	Moves    int    `json:"moves"`
+}
+
+// Bid places a player's bid of the moves they need to reach the target.
+// The first bid of a round starts the countdown, at the end of which the
+// lowest bidder is called on to demonstrate.
+func (rr *RRobots) Bid(w http.ResponseWriter, r *http.Request) {
+	var bd bidData
+	rr.update(w, r, &bd, func() (games.Action, error) {
+		return newAction(rrobots.BidAction, bd.Username, rrobots.BidPayload{Moves: bd.Moves})
+	})
+}
+
+// startCountdown schedules the end of the countdown the game started as of
+// the given time
+func (rr *RRobots) startCountdown(game *rrobots.Game, now time.Time) {
+	id := game.ID
+	rr.afterFunc(game.Deadline.Sub(now), func() { rr.endCountdown(id) })
+}
+
+// endCountdown ends the countdown of the game with the given ID, if it is
+// due, and publishes the game
+func (rr *RRobots) endCountdown(id uuid.UUID) {
+	if _, err := rr.getSettled(id); err != nil {
+		log.Printf("WARN: failed to end countdown of rrobots game %s: %s", id, err)
+	}
+}
+
+// getSettled returns the game with the given ID after ending its countdown
+// if due, through the game's log of actions, so a game is never seen
+// counting down past its deadline, as after a restart
+func (rr *RRobots) getSettled(id uuid.UUID) (*rrobots.Game, error) {
+	// Hold the log's lock from the check through the end, so a countdown
+	// is ended, and recorded, once
+	defer rr.actions.locks.lock(id.String())()
+	game, err := rr.dao.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !rr.due(game) {
+		return game, nil
+	}
+	a := games.Action{Type: rrobots.EndCountdownAction}
+	_, g, err := rr.actions.record(id, a, rrobotsType{rr}.update)
+	if err != nil {
+		return nil, err
+	}
+	return g.(rrobots.Generic).Game, nil
+}
+
+// due returns whether the countdown of the game is over but not yet ended,
+// in case its timer did not
+func (rr *RRobots) due(game *rrobots.Game) bool {
+	return game.State == rrobots.Countdown && !rr.now().Before(game.Deadline)
+}
+
+// demonstrationData is the payload of the demonstrate request
+type demonstrationData struct {
+	Username string         `json:"username"`
+	Moves    []rrobots.Move `json:"moves"`
+}
+
+// Demonstrate verifies the moves of the player called on to demonstrate
+// their bid. If they reach the target, the player wins it, otherwise the
+// next lowest bidder is called on.
+func (rr *RRobots) Demonstrate(w http.ResponseWriter, r *http.Request) {
+	var dd demonstrationData
+	rr.update(w, r, &dd, func() (games.Action, error) {
+		return newAction(rrobots.DemonstrateAction, dd.Username, rrobots.DemonstratePayload{Moves: dd.Moves})
+	})
+}
+
+// Skip ends a round no one has bid on, putting its target back to be
+// played last
+func (rr *RRobots) Skip(w http.ResponseWriter, r *http.Request) {
+	rr.update(w, r, nil, func() (games.Action, error) {
+		return newAction(rrobots.SkipAction, requestUsername(r), nil)
+	})
+}
+
+// Next starts bidding on the next target
+func (rr *RRobots) Next(w http.ResponseWriter, r *http.Request) {
+	rr.update(w, r, nil, func() (games.Action, error) {
+		return newAction(rrobots.NextAction, requestUsername(r), nil)
+	})
+}
+
+// Events streams the game to the client as server-sent events: its current
+// state, then its new state after each change
+func (rr *RRobots) Events(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid rrobots uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	_, err = viewRoom(r, rr.rooms, uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to follow game: %s", err), httpStatus(err))
+		return
+	}
+	// Subscribe before Get so no update between them is missed
+	ch, cancel := rr.events.Subscribe(eventTopic(uuid, requestRole(r)))
+	defer cancel()
+	game, err := rr.getSettled(uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
+		return
+	}
+	var view interface{} = game.View()
+	if requestRole(r) == AdminRole {
+		view = game
+	}
+	initial, err := json.Marshal(view)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode game from datastore: %s", err), http.StatusInternalServerError)
+		return
+	}
+	serveEvents(w, r, ch, initial)
+}
+
+// AddPlayer adds a player to the game
+func (rr *RRobots) AddPlayer(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	rr.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.JoinAction, md.Username, nil)
+	})
+}
+
+// DeletePlayer removes a player from the game
+func (rr *RRobots) DeletePlayer(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	rr.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.LeaveAction, md.Username, nil)
+	})
+}
+
+// AddSpectator adds a spectator to the game
+func (rr *RRobots) AddSpectator(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	rr.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.WatchAction, md.Username, nil)
+	})
+}
+
+// DeleteSpectator removes a spectator from the game
+func (rr *RRobots) DeleteSpectator(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	rr.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.UnwatchAction, md.Username, nil)
+	})
+}
+
+// update decodes the request payload into data, unless it is nil, then
+// applies the action made from it to the requested game through the
+// game's log of actions, and replies with the updated game. The action is
+// played by the user making the request unless it names its player, and
+// is applied only once authorize allows it, as through Games.
+func (rr *RRobots) update(w http.ResponseWriter, r *http.Request, data interface{}, action func() (games.Action, error)) {
+	uuid, err := uuid.Parse(router.GetField(r, 0))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid rrobots uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
+		return
+	}
+	if data != nil {
+		dec := json.NewDecoder(r.Body)
+		err = dec.Decode(data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to unmarshal request data: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+	a, err := action()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode action: %s", err), http.StatusInternalServerError)
+		return
+	}
+	if a.Player == "" {
+		a.Player = requestUsername(r)
+	}
+	a.Player = validate.Normal(a.Player)
+	game, err := rrobotsType{rr}.Get(uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
+		return
+	}
+	err = authorize(r, rr.rooms, uuid, game, a)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update game: %s", err), httpStatus(err))
+		return
+	}
+	_, game, err = rr.actions.apply(uuid, a, rrobotsType{rr}.update)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to update game: %s", err), err)
+		return
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(rrobotsView(game.(rrobots.Generic).Game, r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode updated game: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// rrobotsType is the games.Type of the rrobots games of the service
+type rrobotsType struct {
+	rr *RRobots
+}
+
+// GameType returns the games.Type rooms host the service's games through
+func (rr *RRobots) GameType() games.Type {
+	return rrobotsType{rr}
+}
+
+// Create creates a rrobots game with the options of the create request
+func (t rrobotsType) Create(usernames []string, options json.RawMessage) (games.Game, error) {
+	var ro rrobotsOptions
+	if len(options) > 0 {
+		err := json.Unmarshal(options, &ro)
+		if err != nil {
+			return nil, badRequestError{fmt.Sprintf("invalid options: %s", err)}
+		}
+	}
+	game, err := rrobots.NewGameOptions(rrobots.Options{Seed: ro.Seed}, usernames...)
+	if err != nil {
+		return nil, err
+	}
+	err = t.rr.dao.Insert(game)
+	if err != nil {
+		return nil, err
+	}
+	return rrobots.Generic{Game: game}, nil
+}
+
+func (t rrobotsType) Get(id uuid.UUID) (games.Game, error) {
+	game, err := t.rr.getSettled(id)
+	if err != nil {
+		return nil, err
+	}
+	return rrobots.Generic{Game: game}, nil
+}
+
+// Apply applies the action through the service's log of actions
+func (t rrobotsType) Apply(id uuid.UUID, a games.Action) (*games.Result, games.Game, error) {
+	return t.rr.actions.apply(id, a, t.update)
+}
+
+// update is the updateFunc of the service's games. It schedules the end
+// of a countdown the update starts.
+func (t rrobotsType) update(id uuid.UUID, update func(games.Game, time.Time) error) (games.Game, error) {
+	defer t.rr.locks.lock(id.String())()
+	game, err := t.rr.dao.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	now := t.rr.now()
+	counting := game.State == rrobots.Countdown
+	err = update(rrobots.Generic{Game: game}, now)
+	if err != nil {
+		return nil, err
+	}
+	err = t.rr.dao.Update(game)
+	if err != nil {
+		return nil, err
+	}
+	if !counting && game.State == rrobots.Countdown {
+		t.rr.startCountdown(game, now)
+	}
+	t.rr.publish(game)
+	return rrobots.Generic{Game: game}, nil
+}
+
+func (t rrobotsType) Delete(id uuid.UUID) error {
+	return t.rr.dao.Delete(id)
+}
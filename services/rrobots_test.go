@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/games/rrobots"
+	"github.com/bbawn/boredgames/internal/router"
+)
+
+func TestRRobots(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tr := new(router.TableRouter)
+	actions := ram.NewActions()
+	rr := RRobotsAddRoutes(ram.NewRRobots(), actions, ram.NewRooms(), tr)
+	h := AdminAuth("secret", tr)
+
+	// Control the clock and the countdown timers
+	clock := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	var timers []func()
+	rr.now = func() time.Time { return clock }
+	rr.afterFunc = func(d time.Duration, f func()) {
+		g.Expect(d).To(Equal(rrobots.CountdownDuration))
+		timers = append(timers, f)
+	}
+
+	t.Log("List with no games")
+	resp := doRequest(h, "GET", "http://example.com/rrobots", nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(strings.TrimSpace(string(body))).To(Equal("[]"))
+
+	t.Log("Create a game with invalid payloads")
+	resp = doRequest(h, "POST", "http://example.com/rrobots", nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(HavePrefix("Failed to unmarshal create data:"))
+	resp = doRequest(h, "POST", "http://example.com/rrobots", bytes.NewReader([]byte(`{ "usernames": ["p1", "P1"] }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to create new game: Invalid value: P1 already present as p1 for arg: username\n"))
+
+	t.Log("Create a game")
+	resp = doRequest(h, "POST", "http://example.com/rrobots", bytes.NewReader([]byte(`{ "usernames": ["p1", "p2"], "seed": 42 }`)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var v *rrobots.View
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Players).To(HaveLen(2))
+	g.Expect(v.Round).To(Equal(1))
+	g.Expect(v.State).To(Equal(rrobots.Bidding))
+	g.Expect(v.TargetsLeft).To(Equal(16))
+	target := "http://example.com/rrobots/" + v.ID.String()
+	solve := func(v *rrobots.View) []rrobots.Move {
+		moves := rrobots.NewSolver(v.Board, v.Target).Solve(v.Robots, rrobots.MaxSolveMoves)
+		g.Expect(moves).NotTo(BeNil())
+		return moves
+	}
+	moves := solve(v)
+
+	t.Log("Get non-existent and invalid games")
+	resp = doRequest(h, "GET", "http://example.com/rrobots/"+"6ba7b810-9dad-11d1-80b4-00c04fd430c8", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	resp = doRequest(h, "GET", "http://example.com/rrobots/foo", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	bid := func(username string, moves int) *http.Response {
+		d := fmt.Sprintf(`{ "username": %q, "moves": %d }`, username, moves)
+		return doUserRequest(h, "POST", target+"/bids", username, bytes.NewReader([]byte(d)))
+	}
+	demonstrate := func(username string, moves []rrobots.Move) *http.Response {
+		d, err := json.Marshal(demonstrationData{username, moves})
+		g.Expect(err).To(BeNil())
+		return doUserRequest(h, "POST", target+"/demonstrations", username, bytes.NewReader(d))
+	}
+
+	t.Log("The first bid starts the countdown")
+	resp = bid("p1", len(moves)+1)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.State).To(Equal(rrobots.Countdown))
+	g.Expect(v.Deadline).To(Equal(clock.Add(rrobots.CountdownDuration)))
+	g.Expect(timers).To(HaveLen(1))
+	resp = bid("p2", len(moves))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Bids).To(HaveLen(2))
+	g.Expect(v.Bids[0].Username).To(Equal("p2"))
+	g.Expect(timers).To(HaveLen(1))
+	resp = bid("p1", 0)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid action: bid detail: invalid payload: Invalid value: 0 is less than 1 for arg: moves\n"))
+	resp = bid("p3", 1)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	d := `{ "username": "p1", "moves": 1 }`
+	resp = doRequest(h, "POST", target+"/bids", bytes.NewReader([]byte(d)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(string(body)).To(Equal("Failed to update game: no session in request\n"))
+	resp = doUserRequest(h, "POST", target+"/bids", "p2", bytes.NewReader([]byte(d)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to update game: user \"p2\" may not act as player \"p1\"\n"))
+	resp = doRequest(h, "POST", target+"/bids", bytes.NewReader([]byte(`foo`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(HavePrefix("Failed to unmarshal request data:"))
+	resp = demonstrate("p2", moves)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid method: Demonstrate detail: round is not demonstrating\n"))
+
+	t.Log("The end of the countdown calls on the lowest bidder")
+	clock = clock.Add(rrobots.CountdownDuration)
+	timers[0]()
+	resp = doRequest(h, "GET", target, nil)
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.State).To(Equal(rrobots.Demonstrating))
+	g.Expect(v.Demonstrator).To(Equal("p2"))
+	results, err := actions.List(v.ID)
+	g.Expect(err).To(BeNil())
+	last := results[len(results)-1]
+	g.Expect(last.Action.Type).To(Equal(rrobots.EndCountdownAction))
+	g.Expect(last.Succeeded()).To(BeTrue())
+	g.Expect(last.Time).To(Equal(clock))
+	resp = bid("p1", 1)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+	t.Log("Failed demonstrations call on the next lowest bidder")
+	resp = demonstrate("p1", moves)
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid value: p1 is not the demonstrator for arg: username\n"))
+	resp = demonstrate("p2", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Failed).To(Equal([]string{"p2"}))
+	g.Expect(v.Demonstrator).To(Equal("p1"))
+
+	t.Log("A successful demonstration wins the target")
+	resp = demonstrate("p1", moves)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.State).To(Equal(rrobots.RoundOver))
+	g.Expect(v.Winner).To(Equal("p1"))
+	g.Expect(v.Solution).To(Equal(moves))
+	g.Expect(v.Players["p1"].Chips).To(Equal([]rrobots.Target{v.Target}))
+
+	t.Log("Skip a target")
+	resp = doUserRequest(h, "POST", target+"/skip", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	resp = doUserRequest(h, "POST", target+"/next", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Round).To(Equal(2))
+	g.Expect(v.State).To(Equal(rrobots.Bidding))
+	resp = doUserRequest(h, "POST", target+"/skip", "p2", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.State).To(Equal(rrobots.RoundOver))
+	g.Expect(v.TargetsLeft).To(Equal(16))
+
+	t.Log("A countdown due is ended even if its timer has not")
+	resp = doUserRequest(h, "POST", target+"/next", "p1", nil)
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	moves = solve(v)
+	resp = bid("p2", len(moves))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(timers).To(HaveLen(2))
+	clock = clock.Add(rrobots.CountdownDuration)
+	resp = demonstrate("p2", moves)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Winner).To(Equal("p2"))
+	timers[1]()
+	resp = doAuthRequest(h, "GET", target, "Bearer secret", nil)
+	var game *rrobots.Game
+	g.Expect(json.NewDecoder(resp.Body).Decode(&game)).To(Succeed())
+	g.Expect(game.State).To(Equal(rrobots.RoundOver))
+	g.Expect(game.Targets).To(HaveLen(15))
+
+	t.Log("Add and remove players and spectators")
+	d = `{ "username": "p3" }`
+	resp = doUserRequest(h, "POST", target+"/spectators", "p3", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "POST", target+"/players", "p3", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.Players).To(HaveKey("p3"))
+	g.Expect(v.Spectators).NotTo(HaveKey("p3"))
+	resp = doUserRequest(h, "DEL", target+"/players", "p3", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(h, "DEL", target+"/spectators", "p3", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	resp = doAuthRequest(h, "POST", target+"/players", "Bearer secret", bytes.NewReader([]byte(`{ "username": "" }`)))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to update game: action must have a player\n"))
+
+	t.Log("Reading a game ends its countdown if due, as after a restart")
+	resp = doUserRequest(h, "POST", target+"/next", "p1", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	resp = bid("p1", len(solve(v)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(timers).To(HaveLen(3))
+	clock = clock.Add(rrobots.CountdownDuration)
+	resp = doRequest(h, "GET", "http://example.com/rrobots", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var vs []*rrobots.View
+	g.Expect(json.NewDecoder(resp.Body).Decode(&vs)).To(Succeed())
+	g.Expect(vs).To(HaveLen(1))
+	g.Expect(vs[0].State).To(Equal(rrobots.Demonstrating))
+	g.Expect(vs[0].Demonstrator).To(Equal("p1"))
+	resp = doAuthRequest(h, "GET", target, "Bearer secret", nil)
+	game = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&game)).To(Succeed())
+	g.Expect(game.State).To(Equal(rrobots.Demonstrating))
+	timers[2]()
+	resp = doRequest(h, "GET", target, nil)
+	g.Expect(json.NewDecoder(resp.Body).Decode(&v)).To(Succeed())
+	g.Expect(v.State).To(Equal(rrobots.Demonstrating))
+
+	t.Log("Delete the game")
+	resp = doRequest(h, "DEL", target, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doRequest(h, "GET", target, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	timers[1]()
+}
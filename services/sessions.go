@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/bbawn/boredgames/internal/dao"
+	"github.com/bbawn/boredgames/internal/router"
+	"github.com/bbawn/boredgames/internal/sessions"
+	"github.com/bbawn/boredgames/internal/validate"
+)
+
+// SessionHeader is the request header bearing the token of the session
+// identifying the user making the request
+const SessionHeader = "X-Session"
+
+type sessionKey struct{}
+
+// Sessions is the service issuing the sessions that identify users. A
+// session claims its username until it is deleted or expires, so no other
+// client may act as that user meanwhile.
+type Sessions struct {
+	dao dao.Sessions
+}
+
+// SessionsAddRoutes creates the Sessions service and adds its routes to
+// the router
+func SessionsAddRoutes(dao dao.Sessions, router *router.TableRouter) *Sessions {
+	s := &Sessions{dao}
+	router.AddRoute("POST", "/sessions", http.HandlerFunc(s.Create))
+	router.AddRoute("GET", "/sessions", http.HandlerFunc(s.Get))
+	router.AddRoute("DEL", "/sessions", http.HandlerFunc(s.Delete))
+	return s
+}
+
+// Auth returns a handler that identifies requests bearing the token of a
+// session in SessionHeader as made by its user before passing them to
+// next. Requests without a token are anonymous; those with an unknown or
+// expired token are rejected, so the client knows to start a new session.
+func (s *Sessions) Auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(SessionHeader)
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		session, err := s.dao.Touch(sessions.ID(token))
+		if err != nil {
+			status := httpStatus(err)
+			if status == http.StatusNotFound {
+				status = http.StatusUnauthorized
+			}
+			http.Error(w, fmt.Sprintf("Failed to get session: %s", err), status)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, session)))
+	})
+}
+
+// requestSession returns the session of the user making the given
+// request, or nil if unidentified
+func requestSession(r *http.Request) *sessions.Session {
+	session, _ := r.Context().Value(sessionKey{}).(*sessions.Session)
+	return session
+}
+
+// sessionData is the payload of the session requests
+type sessionData struct {
+	Username string `json:"username"`
+	// Token is the new session's token, only in the response to Create
+	Token string `json:"token,omitempty"`
+}
+
+// Create starts a session for the requested username, unless another
+// session has it, and returns its token
+func (s *Sessions) Create(w http.ResponseWriter, r *http.Request) {
+	var sd sessionData
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&sd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal session data: %s", err), http.StatusBadRequest)
+		return
+	}
+	sd.Username, err = validate.Username(sd.Username)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Invalid request payload err: %s", err), err)
+		return
+	}
+	session, token, err := sessions.NewSession(sd.Username)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create session: %s", err), http.StatusInternalServerError)
+		return
+	}
+	err = s.dao.Insert(session)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to insert session into datastore: %s", err), httpStatus(err))
+		return
+	}
+	sd.Token = token
+	enc := json.NewEncoder(w)
+	err = enc.Encode(sd)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode new session: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// Get returns the username of the requesting session
+func (s *Sessions) Get(w http.ResponseWriter, r *http.Request) {
+	session := requestSession(r)
+	if session == nil {
+		http.Error(w, "No session in request", http.StatusUnauthorized)
+		return
+	}
+	enc := json.NewEncoder(w)
+	err := enc.Encode(sessionData{Username: session.Username})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode session: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// Delete ends the requesting session, releasing its username
+func (s *Sessions) Delete(w http.ResponseWriter, r *http.Request) {
+	session := requestSession(r)
+	if session == nil {
+		http.Error(w, "No session in request", http.StatusUnauthorized)
+		return
+	}
+	err := s.dao.Delete(session.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete session from datastore: %s", err), httpStatus(err))
+		return
+	}
+}
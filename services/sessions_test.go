@@ -0,0 +1,80 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bbawn/boredgames/internal/dao/ram"
+	"github.com/bbawn/boredgames/internal/rooms"
+	"github.com/bbawn/boredgames/internal/router"
+)
+
+func TestSessions(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tr := new(router.TableRouter)
+	sessions := SessionsAddRoutes(ram.NewSessions(), tr)
+	daoRooms := ram.NewRooms()
+	RoomsAddRoutes(daoRooms, ram.NewMessages(), setTypes(SetsAddRoutes(ram.NewSets(), ram.NewActions(), daoRooms, tr)), tr)
+	h := sessions.Auth(tr)
+	doSessionRequest := func(method, target, token string, body []byte) *http.Response {
+		header := make(http.Header)
+		if token != "" {
+			header.Set(SessionHeader, token)
+		}
+		return doHeaderRequest(h, method, target, header, bytes.NewReader(body))
+	}
+
+	t.Log("Start a session with an invalid username")
+	resp := doSessionRequest("POST", "http://example.com/sessions", "", []byte(`{ "username": "" }`))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+	t.Log("Start a session")
+	resp = doSessionRequest("POST", "http://example.com/sessions", "", []byte(`{ "username": "p1" }`))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var sd sessionData
+	g.Expect(json.NewDecoder(resp.Body).Decode(&sd)).To(Succeed())
+	g.Expect(sd.Username).To(Equal("p1"))
+	g.Expect(sd.Token).NotTo(BeEmpty())
+	token := sd.Token
+
+	t.Log("The username is claimed until the session ends")
+	resp = doSessionRequest("POST", "http://example.com/sessions", "", []byte(`{ "username": "P1" }`))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+	t.Log("The session identifies its user")
+	resp = doSessionRequest("GET", "http://example.com/sessions", token, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	sd = sessionData{}
+	g.Expect(json.NewDecoder(resp.Body).Decode(&sd)).To(Succeed())
+	g.Expect(sd).To(Equal(sessionData{Username: "p1"}))
+	resp = doSessionRequest("POST", "http://example.com/rooms", token, []byte(`{ "name": "n1" }`))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var room *rooms.Room
+	g.Expect(json.NewDecoder(resp.Body).Decode(&room)).To(Succeed())
+	g.Expect(room.Owner).To(Equal("p1"))
+
+	t.Log("Requests without a session are anonymous")
+	resp = doSessionRequest("GET", "http://example.com/sessions", "", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	resp = doSessionRequest("DEL", "http://example.com/rooms/n1", "", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+	t.Log("Requests with an unknown session are rejected")
+	resp = doSessionRequest("GET", "http://example.com/rooms/n1", "xyz", nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(string(body)).To(HavePrefix("Failed to get session:"))
+
+	t.Log("End the session, releasing its username")
+	resp = doSessionRequest("DEL", "http://example.com/sessions", token, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doSessionRequest("GET", "http://example.com/sessions", token, nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	resp = doSessionRequest("POST", "http://example.com/sessions", "", []byte(`{ "username": "P1" }`))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+}
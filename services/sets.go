@@ -3,22 +3,37 @@ package services
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/bbawn/boredgames/internal/dao"
+	"github.com/bbawn/boredgames/internal/events"
+	"github.com/bbawn/boredgames/internal/games"
 	"github.com/bbawn/boredgames/internal/games/set"
 	"github.com/bbawn/boredgames/internal/router"
+	"github.com/bbawn/boredgames/internal/validate"
 )
 
 // Sets provides the REST API for the Set board game
 type Sets struct {
 	dao dao.Sets
+	// events publishes updated games to their event stream subscribers,
+	// keyed on game ID and viewer role
+	events *events.Broker
+	// actions apply and record every change to the games
+	actions *actionLog
+	// rooms are where the games may be played, whose access rules apply
+	// to them
+	rooms dao.Rooms
 }
 
-func SetsAddRoutes(dao dao.Sets, router *router.TableRouter) {
-	s := &Sets{dao}
+// SetsAddRoutes adds the routes for this service to the given router and
+// returns the service
+func SetsAddRoutes(dao dao.Sets, actions dao.Actions, rms dao.Rooms, router *router.TableRouter) *Sets {
+	s := &Sets{dao: dao, events: events.NewBroker(), actions: &actionLog{dao: actions}, rooms: rms}
 	router.AddRoute("GET", "/sets", http.HandlerFunc(s.List))
 	router.AddRoute("POST", "/sets", http.HandlerFunc(s.Create))
 	router.AddRoute("GET", "/sets/([^/]+)", http.HandlerFunc(s.Get))
@@ -26,6 +41,40 @@ func SetsAddRoutes(dao dao.Sets, router *router.TableRouter) {
 	router.AddRoute("POST", "/sets/([^/]+)/claim", http.HandlerFunc(s.Claim))
 	router.AddRoute("POST", "/sets/([^/]+)/expand", http.HandlerFunc(s.Expand))
 	router.AddRoute("POST", "/sets/([^/]+)/next", http.HandlerFunc(s.Next))
+	router.AddRoute("GET", "/sets/([^/]+)/events", http.HandlerFunc(s.Events))
+	router.AddRoute("POST", "/sets/([^/]+)/players", http.HandlerFunc(s.AddPlayer))
+	router.AddRoute("DEL", "/sets/([^/]+)/players", http.HandlerFunc(s.DeletePlayer))
+	router.AddRoute("POST", "/sets/([^/]+)/spectators", http.HandlerFunc(s.AddSpectator))
+	router.AddRoute("DEL", "/sets/([^/]+)/spectators", http.HandlerFunc(s.DeleteSpectator))
+	return s
+}
+
+// gameView returns the projection of the game presented to clients with
+// the given role: the full game for admins, otherwise the public View
+// without the Deck
+func gameView(game *set.Game, role Role) interface{} {
+	if role == AdminRole {
+		return game
+	}
+	return game.View()
+}
+
+// eventTopic returns the events topic for the given game and viewer role
+func eventTopic(id uuid.UUID, role Role) string {
+	return fmt.Sprintf("%s/%d", id, role)
+}
+
+// publish sends the given updated game to its event stream subscribers,
+// each receiving the view for their role
+func (s *Sets) publish(game *set.Game) {
+	for _, role := range []Role{PublicRole, AdminRole} {
+		b, err := json.Marshal(gameView(game, role))
+		if err != nil {
+			log.Printf("WARN: failed to encode game %s for publish: %s", game.ID, err)
+			return
+		}
+		s.events.Publish(eventTopic(game.ID, role), b)
+	}
 }
 
 func (s *Sets) List(w http.ResponseWriter, r *http.Request) {
@@ -34,8 +83,25 @@ func (s *Sets) List(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to load games from datastore: %s", err), httpStatus(err))
 		return
 	}
+	// Games in rooms the client may not view are hidden from it
+	hidden, err := hiddenGames(r, s.rooms)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load rooms from datastore: %s", err), httpStatus(err))
+		return
+	}
+	visible := games[:0]
+	for _, game := range games {
+		if !hidden[game.ID] {
+			visible = append(visible, game)
+		}
+	}
+	games = visible
 	enc := json.NewEncoder(w)
-	err = enc.Encode(games)
+	views := make([]interface{}, len(games))
+	for i, game := range games {
+		views[i] = gameView(game, requestRole(r))
+	}
+	err = enc.Encode(views)
 	if err != nil {
 		m := fmt.Sprintf("Failed to encode games from datastore: %s", err)
 		http.Error(w, m, http.StatusInternalServerError)
@@ -57,7 +123,10 @@ func (s *Sets) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	game, err := set.NewGame(cd.Usernames...)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create new game: %s", err), http.StatusBadRequest)
+		if _, ok := err.(validate.Error); !ok {
+			err = badRequestError{err.Error()}
+		}
+		httpError(w, fmt.Sprintf("Failed to create new game: %s", err), err)
 		return
 	}
 	err = s.dao.Insert(game)
@@ -66,7 +135,7 @@ func (s *Sets) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	enc := json.NewEncoder(w)
-	err = enc.Encode(game)
+	err = enc.Encode(gameView(game, requestRole(r)))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode new game: %s", err), http.StatusInternalServerError)
 		return
@@ -79,13 +148,18 @@ func (s *Sets) Get(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Invalid set uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
 		return
 	}
+	_, err = viewRoom(r, s.rooms, uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get game: %s", err), httpStatus(err))
+		return
+	}
 	game, err := s.dao.Get(uuid)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
 		return
 	}
 	enc := json.NewEncoder(w)
-	err = enc.Encode(game)
+	err = enc.Encode(gameView(game, requestRole(r)))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode game from datastore: %s", err), http.StatusInternalServerError)
 		return
@@ -98,6 +172,11 @@ func (s *Sets) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Invalid set uuid %s: %s", router.GetField(r, 0), err), httpStatus(err))
 		return
 	}
+	err = deletable(s.rooms, uuid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete game: %s", err), httpStatus(err))
+		return
+	}
 	err = s.dao.Delete(uuid)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to delete game from datastore: %s", err), httpStatus(err))
@@ -110,96 +189,202 @@ type claimData struct {
 	Cards    set.CardTriple
 }
 
+// Claim claims a set for a player
 func (s *Sets) Claim(w http.ResponseWriter, r *http.Request) {
+	var cd claimData
+	s.update(w, r, &cd, func() (games.Action, error) {
+		return newAction(set.ClaimAction, cd.Username, set.ClaimPayload{Cards: cd.Cards})
+	})
+}
+
+// Expand expands the board when no one can find a set
+func (s *Sets) Expand(w http.ResponseWriter, r *http.Request) {
+	s.update(w, r, nil, func() (games.Action, error) {
+		return newAction(set.ExpandAction, requestUsername(r), nil)
+	})
+}
+
+// Next starts the next round after a set is claimed
+func (s *Sets) Next(w http.ResponseWriter, r *http.Request) {
+	s.update(w, r, nil, func() (games.Action, error) {
+		return newAction(set.NextAction, requestUsername(r), nil)
+	})
+}
+
+// Events streams the game to the client as server-sent events: its current
+// state, then its new state after each change
+func (s *Sets) Events(w http.ResponseWriter, r *http.Request) {
 	uuid, err := uuid.Parse(router.GetField(r, 0))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid set uuid %s: %s", router.GetField(r, 0), err), httpStatus(err))
+		http.Error(w, fmt.Sprintf("Invalid set uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
 		return
 	}
-	var cd claimData
-	dec := json.NewDecoder(r.Body)
-	err = dec.Decode(&cd)
+	_, err = viewRoom(r, s.rooms, uuid)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to unmarshal claim data: %s", err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Failed to follow game: %s", err), httpStatus(err))
 		return
 	}
+	// Subscribe before Get so no update between them is missed
+	ch, cancel := s.events.Subscribe(eventTopic(uuid, requestRole(r)))
+	defer cancel()
 	game, err := s.dao.Get(uuid)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
 		return
 	}
-	err = game.ClaimSet(cd.Username, cd.Cards)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to claim set in game: %s", err), httpStatus(err))
-		return
-	}
-	err = s.dao.Update(game)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update game in datastore: %s", err), httpStatus(err))
-		return
-	}
-	enc := json.NewEncoder(w)
-	err = enc.Encode(game)
+	initial, err := json.Marshal(gameView(game, requestRole(r)))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to encode game next game: %s", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to encode game from datastore: %s", err), http.StatusInternalServerError)
 		return
 	}
+	serveEvents(w, r, ch, initial)
 }
 
-func (s *Sets) Expand(w http.ResponseWriter, r *http.Request) {
+// membershipData is the payload of the post and delete player and
+// spectator requests
+type membershipData struct {
+	Username string `json:"username"`
+	// Sets is the policy for a departing player's claimed sets
+	Sets set.LeavePolicy `json:"sets"`
+}
+
+// AddPlayer adds a player to the game
+func (s *Sets) AddPlayer(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	s.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.JoinAction, md.Username, nil)
+	})
+}
+
+// DeletePlayer removes a player from the game
+func (s *Sets) DeletePlayer(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	s.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.LeaveAction, md.Username, set.LeavePayload{Sets: md.Sets})
+	})
+}
+
+// AddSpectator adds a spectator to the game
+func (s *Sets) AddSpectator(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	s.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.WatchAction, md.Username, nil)
+	})
+}
+
+// DeleteSpectator removes a spectator from the game
+func (s *Sets) DeleteSpectator(w http.ResponseWriter, r *http.Request) {
+	var md membershipData
+	s.update(w, r, &md, func() (games.Action, error) {
+		return newAction(games.UnwatchAction, md.Username, nil)
+	})
+}
+
+// update decodes the request payload into data, unless it is nil, then
+// applies the action made from it to the requested game through the
+// game's log of actions, and replies with the updated game. The action is
+// played by the user making the request unless it names its player, and
+// is applied only once authorize allows it, as through Games.
+func (s *Sets) update(w http.ResponseWriter, r *http.Request, data interface{}, action func() (games.Action, error)) {
 	uuid, err := uuid.Parse(router.GetField(r, 0))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Invalid set uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
 		return
 	}
-	game, err := s.dao.Get(uuid)
+	if data != nil {
+		dec := json.NewDecoder(r.Body)
+		err = dec.Decode(data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to unmarshal request data: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+	a, err := action()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode action: %s", err), http.StatusInternalServerError)
+		return
+	}
+	if a.Player == "" {
+		a.Player = requestUsername(r)
+	}
+	a.Player = validate.Normal(a.Player)
+	game, err := setType{s}.Get(uuid)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
 		return
 	}
-	err = game.Expand()
+	err = authorize(r, s.rooms, uuid, game, a)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to expand game board: %s", err), httpStatus(err))
+		http.Error(w, fmt.Sprintf("Failed to update game: %s", err), httpStatus(err))
 		return
 	}
-	err = s.dao.Update(game)
+	_, game, err = s.actions.apply(uuid, a, setType{s}.update)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update game in datastore: %s", err), httpStatus(err))
+		httpError(w, fmt.Sprintf("Failed to update game: %s", err), err)
 		return
 	}
 	enc := json.NewEncoder(w)
-	err = enc.Encode(game)
+	err = enc.Encode(gameView(game.(set.Generic).Game, requestRole(r)))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to encode game next game: %s", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to encode updated game: %s", err), http.StatusInternalServerError)
 		return
 	}
 }
 
-func (s *Sets) Next(w http.ResponseWriter, r *http.Request) {
-	uuid, err := uuid.Parse(router.GetField(r, 0))
+// setType is the games.Type of the Set games of the service
+type setType struct {
+	s *Sets
+}
+
+// GameType returns the games.Type rooms host the service's games through
+func (s *Sets) GameType() games.Type {
+	return setType{s}
+}
+
+// Create creates a Set game, which has no options
+func (t setType) Create(usernames []string, options json.RawMessage) (games.Game, error) {
+	game, err := set.NewGame(usernames...)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid set uuid %s: %s", router.GetField(r, 0), err), http.StatusNotFound)
-		return
+		return nil, err
 	}
-	game, err := s.dao.Get(uuid)
+	err = t.s.dao.Insert(game)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get game from datastore: %s", err), httpStatus(err))
-		return
+		return nil, err
 	}
-	err = game.NextRound()
+	return set.Generic{Game: game}, nil
+}
+
+func (t setType) Get(id uuid.UUID) (games.Game, error) {
+	game, err := t.s.dao.Get(id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to advance game to next round: %s", err), httpStatus(err))
-		return
+		return nil, err
+	}
+	return set.Generic{Game: game}, nil
+}
+
+// Apply applies the action through the service's log of actions
+func (t setType) Apply(id uuid.UUID, a games.Action) (*games.Result, games.Game, error) {
+	return t.s.actions.apply(id, a, t.update)
+}
+
+// update is the updateFunc of the service's games
+func (t setType) update(id uuid.UUID, update func(games.Game, time.Time) error) (games.Game, error) {
+	game, err := t.s.dao.Get(id)
+	if err != nil {
+		return nil, err
 	}
-	err = s.dao.Update(game)
+	err = update(set.Generic{Game: game}, time.Now().UTC())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update game in datastore: %s", err), httpStatus(err))
-		return
+		return nil, err
 	}
-	enc := json.NewEncoder(w)
-	err = enc.Encode(game)
+	err = t.s.dao.Update(game)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to encode game next game: %s", err), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
+	t.s.publish(game)
+	return set.Generic{Game: game}, nil
+}
+
+func (t setType) Delete(id uuid.UUID) error {
+	return t.s.dao.Delete(id)
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -19,9 +20,11 @@ import (
 
 func TestSets(t *testing.T) {
 	g := NewGomegaWithT(t)
+	actions := ram.NewActions()
+	daoRooms := ram.NewRooms()
 	ram := ram.NewSets()
 	tr := new(router.TableRouter)
-	SetsAddRoutes(ram, tr)
+	SetsAddRoutes(ram, actions, daoRooms, tr)
 
 	t.Log("List with no games")
 	resp := doRequest(tr, "GET", "http://example.com/sets", nil)
@@ -46,15 +49,15 @@ func TestSets(t *testing.T) {
 	t.Log("Create a game with empty username")
 	d = `{ "usernames": [ "p1", "", "p3" ] }`
 	resp = doRequest(tr, "POST", "http://example.com/sets", bytes.NewReader([]byte(d)))
-	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
-	g.Expect(string(body)).To(Equal("Failed to create new game: Invalid value: empty for arg: username\n"))
+	g.Expect(decodeErrorData(t, resp)).To(Equal(errorData{
+		`Failed to create new game: invalid username "": empty`, "username", "", "empty"}))
 
 	t.Log("Create a couple of games")
 	d = `{ "usernames": [ "p1", "p2", "p3" ] }`
 	resp = doRequest(tr, "POST", "http://example.com/sets", bytes.NewReader([]byte(d)))
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
-	var g1 *set.Game
+	var g1 *set.View
 	dec := json.NewDecoder(resp.Body)
 	err := dec.Decode(&g1)
 	g.Expect(err).To(BeNil())
@@ -64,7 +67,7 @@ func TestSets(t *testing.T) {
 	d = `{ "usernames": [ "p2", "p0" ] }`
 	resp = doRequest(tr, "POST", "http://example.com/sets", bytes.NewReader([]byte(d)))
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
-	var g2 *set.Game
+	var g2 *set.View
 	dec = json.NewDecoder(resp.Body)
 	err = dec.Decode(&g2)
 	g.Expect(err).To(BeNil())
@@ -82,7 +85,7 @@ func TestSets(t *testing.T) {
 	t.Log("Get each game")
 	resp = doRequest(tr, "GET", "http://example.com/sets/"+g1.ID.String(), nil)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
-	var g0 *set.Game
+	var g0 *set.View
 	dec = json.NewDecoder(resp.Body)
 	err = dec.Decode(&g0)
 	g.Expect(err).To(BeNil())
@@ -100,7 +103,7 @@ func TestSets(t *testing.T) {
 	resp = doRequest(tr, "GET", "http://example.com/sets", nil)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
 	dec = json.NewDecoder(resp.Body)
-	var gs []*set.Game
+	var gs []*set.View
 	err = dec.Decode(&gs)
 	g.Expect(err).To(BeNil())
 	expGs := gameMap(g1, g2)
@@ -122,53 +125,84 @@ func TestSets(t *testing.T) {
 	g.Expect(string(body)).To(BeEmpty())
 
 	t.Log("Next move in invalid state")
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/next", nil)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/next", "p1", nil)
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
-	g.Expect(string(body)).To(Equal("Failed to advance game to next round: Invalid method: NextRound detail: round not yet claimed\n"))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid method: NextRound detail: round not yet claimed\n"))
 
 	t.Log("Expand a set")
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/expand", nil)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/expand", "p1", nil)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
-	var g1Expanded *set.Game
+	var g1Expanded *set.View
 	dec = json.NewDecoder(resp.Body)
 	err = dec.Decode(&g1Expanded)
 	g.Expect(err).To(BeNil())
 
 	t.Log("Claim a set with no payload")
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", nil)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p1", nil)
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
-	g.Expect(string(body)).To(Equal("Failed to unmarshal claim data: EOF\n"))
+	g.Expect(string(body)).To(Equal("Failed to unmarshal request data: EOF\n"))
 
 	t.Log("Claim a set with invalid json payload")
 	payload := []byte(`foo`)
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", bytes.NewReader(payload))
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p1", bytes.NewReader(payload))
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
-	g.Expect(string(body)).To(Equal("Failed to unmarshal claim data: invalid character 'o' in literal false (expecting 'a')\n"))
+	g.Expect(string(body)).To(Equal("Failed to unmarshal request data: invalid character 'o' in literal false (expecting 'a')\n"))
 
 	t.Log("Claim a set with invalid card values")
 	payload = []byte(`{ "username": "p1", "cards": [ "foo", "bar", "baz" ] }`)
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", bytes.NewReader(payload))
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p1", bytes.NewReader(payload))
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
-	g.Expect(string(body)).To(Equal("Failed to unmarshal claim data: card string must have len 4: foo\n"))
+	g.Expect(string(body)).To(Equal("Failed to unmarshal request data: card string must have len 4: foo\n"))
 
-	s1 := g1.FindExpandSet()
+	// The public view has no Deck, so find sets on the full game
+	full, err := ram.Get(g1.ID)
+	g.Expect(err).To(BeNil())
+	s1 := full.FindExpandSet()
+	// FindExpandSet may have expanded the board, keep the datastore in step
+	g.Expect(ram.Update(full)).To(Succeed())
 	t.Log("Claim a set with invalid username in payload")
 	payload = claimPayload("nonplayer", *s1)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "nonplayer", bytes.NewReader(payload))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Failed to update game: user \"nonplayer\" is not a player in game %s\n", g1.ID)))
+
+	t.Log("Claim a set without a session or as another player")
+	payload = claimPayload("p1", *s1)
 	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", bytes.NewReader(payload))
 	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(string(body)).To(Equal("Failed to update game: no session in request\n"))
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p2", bytes.NewReader(payload))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to update game: user \"p2\" may not act as player \"p1\"\n"))
+
+	t.Log("Claim a set with too few cards in payload")
+	payload = []byte(`{ "username": "p1", "cards": [ "G1FD", "R2SO" ] }`)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p1", bytes.NewReader(payload))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(HavePrefix("Failed to unmarshal request data: card triple must have 3 cards:"))
+
+	t.Log("Claim a set with duplicate cards in payload (no penalty)")
+	dup := set.CardTriple{s1[0], s1[0], s1[1]}
+	payload = claimPayload("p1", dup)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p1", bytes.NewReader(payload))
+	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
-	g.Expect(string(body)).To(Equal("Failed to claim set in game: Invalid value: nonplayer for arg: username\n"))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid action: claim detail: invalid payload: card triple has duplicate cards: " + dup.String() + "\n"))
 
 	t.Log("Claim a set with non-set in payload (penalty)")
-	nonset := g1.Board.FindSet(false)
+	nonset := full.Board.FindSet(false)
 	payload = claimPayload("p1", *nonset)
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", bytes.NewReader(payload))
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p1", bytes.NewReader(payload))
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
-	var g1ClaimFail *set.Game
+	var g1ClaimFail *set.View
 	dec = json.NewDecoder(resp.Body)
 	err = dec.Decode(&g1ClaimFail)
 	g.Expect(err).To(BeNil())
@@ -177,33 +211,34 @@ func TestSets(t *testing.T) {
 
 	t.Log("Claim a set")
 	payload = claimPayload("p1", *s1)
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", bytes.NewReader(payload))
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p1", bytes.NewReader(payload))
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
-	var g1Claimed *set.Game
+	var g1Claimed *set.View
 	dec = json.NewDecoder(resp.Body)
 	err = dec.Decode(&g1Claimed)
 	g.Expect(err).To(BeNil())
 	err = checkNextGame(g1, g1Claimed)
 	g.Expect(err).To(BeNil())
 	g.Expect(len(g1Claimed.Players["p1"].Sets)).To(Equal(1))
+	g.Expect(g1Claimed.DeckLen).To(Equal(len(full.Deck)))
 	// TODO: DeepEqual sets
 
 	t.Log("Claim a set in invalid game state")
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", bytes.NewReader(payload))
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p1", bytes.NewReader(payload))
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
-	g.Expect(string(body)).To(Equal("Failed to claim set in game: Invalid method: ClaimSet detail: round already claimed by p1\n"))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid method: ClaimSet detail: round already claimed by p1\n"))
 
 	t.Log("Expand a set in invalid game state")
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/expand", bytes.NewReader(payload))
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/expand", "p1", bytes.NewReader(payload))
 	body, _ = ioutil.ReadAll(resp.Body)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusConflict))
-	g.Expect(string(body)).To(Equal("Failed to expand game board: Invalid method: Expand detail: only valid in claim state\n"))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid method: Expand detail: only valid in claim state\n"))
 
 	t.Log("Valid Next round request")
-	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/next", nil)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/next", "p1", nil)
 	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
-	var g1Next *set.Game
+	var g1Next *set.View
 	dec = json.NewDecoder(resp.Body)
 	err = dec.Decode(&g1Next)
 	g.Expect(err).To(BeNil())
@@ -212,7 +247,7 @@ func TestSets(t *testing.T) {
 }
 
 // checkNewGame validates that the given game is in a valid initial state
-func checkNewGame(g *set.Game, usernames ...string) error {
+func checkNewGame(g *set.View, usernames ...string) error {
 	if g.ID.URN() == "" {
 		return fmt.Errorf("Invalid ID: %s", g.ID)
 	}
@@ -238,7 +273,7 @@ func checkNewGame(g *set.Game, usernames ...string) error {
 }
 
 // checkNextGame validates that g1 is a valid next state of g0
-func checkNextGame(g0, g1 *set.Game) error {
+func checkNextGame(g0, g1 *set.View) error {
 	if g0.ID != g1.ID {
 		return fmt.Errorf("Expected g0 ID %s to equal g1 ID %s", g0.ID, g1.ID)
 	}
@@ -255,8 +290,8 @@ func checkNextGame(g0, g1 *set.Game) error {
 	return nil
 }
 
-func gameMap(gs ...*set.Game) map[uuid.UUID]*set.Game {
-	m := make(map[uuid.UUID]*set.Game)
+func gameMap(gs ...*set.View) map[uuid.UUID]*set.View {
+	m := make(map[uuid.UUID]*set.View)
 	for _, g := range gs {
 		m[g.ID] = g
 	}
@@ -274,3 +309,228 @@ func claimPayload(username string, cs set.CardTriple) []byte {
 	}
 	return payload
 }
+
+func TestSetsSpectators(t *testing.T) {
+	g := NewGomegaWithT(t)
+	actions := ram.NewActions()
+	daoRooms := ram.NewRooms()
+	ram := ram.NewSets()
+	tr := new(router.TableRouter)
+	SetsAddRoutes(ram, actions, daoRooms, tr)
+	srv := httptest.NewServer(tr)
+	defer srv.Close()
+
+	t.Log("Create a game")
+	d := `{ "usernames": [ "p1", "p2" ] }`
+	resp := doRequest(tr, "POST", "http://example.com/sets", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var g1 *set.View
+	err := json.NewDecoder(resp.Body).Decode(&g1)
+	g.Expect(err).To(BeNil())
+
+	t.Log("Subscribe to game events")
+	events := subscribe(t, srv.URL+"/sets/"+g1.ID.String()+"/events")
+	defer events.Close()
+	var ev *set.View
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev).To(Equal(g1))
+
+	t.Log("Add a spectator")
+	payload := []byte(`{ "username": "s1" }`)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/spectators", "s1", bytes.NewReader(payload))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	err = json.NewDecoder(resp.Body).Decode(&g1)
+	g.Expect(err).To(BeNil())
+	g.Expect(g1.Spectators).To(Equal(map[string]bool{"s1": true}))
+	ev = nil
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev).To(Equal(g1))
+
+	t.Log("Add a player as spectator")
+	payload = []byte(`{ "username": "p1" }`)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/spectators", "p1", bytes.NewReader(payload))
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid value: p1 is a player for arg: username\n"))
+
+	t.Log("Spectator claims a set")
+	full, err := ram.Get(g1.ID)
+	g.Expect(err).To(BeNil())
+	s1 := full.FindExpandSet()
+	// FindExpandSet may have expanded the board, keep the datastore in step
+	g.Expect(ram.Update(full)).To(Succeed())
+	payload = claimPayload("s1", *s1)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "s1", bytes.NewReader(payload))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal(fmt.Sprintf("Failed to update game: user \"s1\" is not a player in game %s\n", g1.ID)))
+
+	t.Log("Player claims a set, spectator sees it")
+	payload = claimPayload("p1", *s1)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p1", bytes.NewReader(payload))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	ev = nil
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.ClaimedUsername).To(Equal("p1"))
+
+	t.Log("Remove the spectator")
+	payload = []byte(`{ "username": "s1" }`)
+	resp = doUserRequest(tr, "DEL", "http://example.com/sets/"+g1.ID.String()+"/spectators", "s1", bytes.NewReader(payload))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	ev = nil
+	g.Expect(json.Unmarshal(nextEvent(t, events), &ev)).To(Succeed())
+	g.Expect(ev.Spectators).To(BeEmpty())
+
+	t.Log("Remove a non-spectator")
+	resp = doUserRequest(tr, "DEL", "http://example.com/sets/"+g1.ID.String()+"/spectators", "s1", bytes.NewReader(payload))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+}
+
+func TestSetsAdmin(t *testing.T) {
+	g := NewGomegaWithT(t)
+	actions := ram.NewActions()
+	daoRooms := ram.NewRooms()
+	ram := ram.NewSets()
+	tr := new(router.TableRouter)
+	SetsAddRoutes(ram, actions, daoRooms, tr)
+	h := AdminAuth("secret", tr)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	t.Log("Create a game")
+	d := `{ "usernames": [ "p1", "p2" ] }`
+	resp := doRequest(h, "POST", "http://example.com/sets", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var fields map[string]json.RawMessage
+	err := json.NewDecoder(resp.Body).Decode(&fields)
+	g.Expect(err).To(BeNil())
+	g.Expect(fields).NotTo(HaveKey("deck"))
+	g.Expect(string(fields["deckLen"])).To(Equal(fmt.Sprint(set.FullDeckLen - set.InitBoardLen)))
+	var id uuid.UUID
+	g.Expect(json.Unmarshal(fields["id"], &id)).To(Succeed())
+	full, err := ram.Get(id)
+	g.Expect(err).To(BeNil())
+
+	t.Log("Public and wrong-token clients do not see the deck")
+	for _, auth := range []string{"", "Bearer wrong", "secret"} {
+		resp = doAuthRequest(h, "GET", "http://example.com/sets/"+id.String(), auth, nil)
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		fields = nil
+		g.Expect(json.NewDecoder(resp.Body).Decode(&fields)).To(Succeed())
+		g.Expect(fields).NotTo(HaveKey("deck"))
+	}
+
+	t.Log("Admin sees the full game in each response")
+	resp = doAuthRequest(h, "GET", "http://example.com/sets/"+id.String(), "Bearer secret", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var admin *set.Game
+	g.Expect(json.NewDecoder(resp.Body).Decode(&admin)).To(Succeed())
+	g.Expect(admin).To(Equal(full))
+
+	resp = doAuthRequest(h, "GET", "http://example.com/sets", "Bearer secret", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var admins []*set.Game
+	g.Expect(json.NewDecoder(resp.Body).Decode(&admins)).To(Succeed())
+	g.Expect(admins).To(Equal([]*set.Game{full}))
+
+	resp = doUserAuthRequest(h, "POST", "http://example.com/sets/"+id.String()+"/expand", "p1", "Bearer secret", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	admin = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&admin)).To(Succeed())
+	g.Expect(admin.Deck).To(HaveLen(set.FullDeckLen - set.InitBoardLen - set.SetLen))
+
+	t.Log("Admin event stream sees the deck, public stream does not")
+	adminEvents := subscribeAuth(t, srv.URL+"/sets/"+id.String()+"/events", "Bearer secret")
+	defer adminEvents.Close()
+	publicEvents := subscribe(t, srv.URL+"/sets/"+id.String()+"/events")
+	defer publicEvents.Close()
+	admin = nil
+	g.Expect(json.Unmarshal(nextEvent(t, adminEvents), &admin)).To(Succeed())
+	g.Expect(admin.Deck).To(HaveLen(set.FullDeckLen - set.InitBoardLen - set.SetLen))
+	fields = nil
+	g.Expect(json.Unmarshal(nextEvent(t, publicEvents), &fields)).To(Succeed())
+	g.Expect(fields).NotTo(HaveKey("deck"))
+}
+
+func TestSetsPlayers(t *testing.T) {
+	g := NewGomegaWithT(t)
+	actions := ram.NewActions()
+	daoRooms := ram.NewRooms()
+	ram := ram.NewSets()
+	tr := new(router.TableRouter)
+	SetsAddRoutes(ram, actions, daoRooms, tr)
+
+	d := `{ "usernames": [ "p1", "p2" ] }`
+	resp := doRequest(tr, "POST", "http://example.com/sets", bytes.NewReader([]byte(d)))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	var g1 *set.View
+	g.Expect(json.NewDecoder(resp.Body).Decode(&g1)).To(Succeed())
+
+	t.Log("Add a player mid-game")
+	payload := []byte(`{ "username": "p3" }`)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/players", "p3", bytes.NewReader(payload))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&g1)).To(Succeed())
+	g.Expect(g1.Players).To(HaveKey("p3"))
+	g.Expect(g1.Players["p3"].Sets).To(BeEmpty())
+
+	t.Log("Add a duplicate player")
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/players", "p3", bytes.NewReader(payload))
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid value: p3 already present for arg: username\n"))
+
+	t.Log("Add a player without a session or as another user")
+	payload = []byte(`{ "username": "p4" }`)
+	resp = doRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/players", bytes.NewReader(payload))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(string(body)).To(Equal("Failed to update game: no session in request\n"))
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/players", "p3", bytes.NewReader(payload))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(string(body)).To(Equal("Failed to update game: user \"p3\" may not act as player \"p4\"\n"))
+
+	t.Log("Add the user making the request when no player is named")
+	payload = []byte(`{ "username": "" }`)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/players", "p4", bytes.NewReader(payload))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&g1)).To(Succeed())
+	g.Expect(g1.Players).To(HaveKey("p4"))
+
+	t.Log("p3 claims a set")
+	full, err := ram.Get(g1.ID)
+	g.Expect(err).To(BeNil())
+	s1 := full.FindExpandSet()
+	// FindExpandSet may have expanded the board, keep the datastore in step
+	g.Expect(ram.Update(full)).To(Succeed())
+	payload = claimPayload("p3", *s1)
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/claim", "p3", bytes.NewReader(payload))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = doUserRequest(tr, "POST", "http://example.com/sets/"+g1.ID.String()+"/next", "p3", nil)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(json.NewDecoder(resp.Body).Decode(&g1)).To(Succeed())
+	deckLen := g1.DeckLen
+
+	t.Log("Remove a player with an invalid policy")
+	payload = []byte(`{ "username": "p3", "sets": "keep" }`)
+	resp = doUserRequest(tr, "DEL", "http://example.com/sets/"+g1.ID.String()+"/players", "p3", bytes.NewReader(payload))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid value: keep for arg: policy\n"))
+
+	t.Log("Remove a player, returning their sets")
+	payload = []byte(`{ "username": "p3", "sets": "return" }`)
+	resp = doUserRequest(tr, "DEL", "http://example.com/sets/"+g1.ID.String()+"/players", "p3", bytes.NewReader(payload))
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g1 = nil
+	g.Expect(json.NewDecoder(resp.Body).Decode(&g1)).To(Succeed())
+	g.Expect(g1.Players).NotTo(HaveKey("p3"))
+	g.Expect(g1.DeckLen).To(Equal(deckLen + set.SetLen))
+
+	t.Log("Remove a non-player")
+	resp = doUserRequest(tr, "DEL", "http://example.com/sets/"+g1.ID.String()+"/players", "p3", bytes.NewReader(payload))
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	g.Expect(string(body)).To(Equal("Failed to update game: Invalid value: p3 for arg: username\n"))
+}
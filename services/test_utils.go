@@ -1,20 +1,86 @@
 package services
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
 
-	"github.com/bbawn/boredgames/internal/router"
+	"github.com/bbawn/boredgames/internal/games"
+	"github.com/bbawn/boredgames/internal/rooms"
+	"github.com/bbawn/boredgames/internal/sessions"
 )
 
 func doRequest(
-	tr *router.TableRouter,
+	h http.Handler,
 	method, target string,
 	reqBody io.Reader,
+) *http.Response {
+	return doAuthRequest(h, method, target, "", reqBody)
+}
+
+// doAuthRequest is doRequest with the given Authorization header, if
+// non-empty
+func doAuthRequest(
+	h http.Handler,
+	method, target, auth string,
+	reqBody io.Reader,
+) *http.Response {
+	header := make(http.Header)
+	if auth != "" {
+		header.Set("Authorization", auth)
+	}
+	return doHeaderRequest(h, method, target, header, reqBody)
+}
+
+// doUserRequest is doRequest made by the given user, if non-empty, as if
+// identified by a session
+func doUserRequest(
+	h http.Handler,
+	method, target, username string,
+	reqBody io.Reader,
+) *http.Response {
+	return doUserAuthRequest(h, method, target, username, "", reqBody)
+}
+
+// doUserAuthRequest is doUserRequest with the given Authorization header,
+// if non-empty
+func doUserAuthRequest(
+	h http.Handler,
+	method, target, username, auth string,
+	reqBody io.Reader,
 ) *http.Response {
 	r := httptest.NewRequest(method, target, reqBody)
+	if username != "" {
+		r = r.WithContext(context.WithValue(r.Context(), sessionKey{}, &sessions.Session{Username: username}))
+	}
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
 	w := httptest.NewRecorder()
-	tr.ServeHTTP(w, r)
+	h.ServeHTTP(w, r)
 	return w.Result()
 }
+
+// doHeaderRequest is doRequest with the given request headers
+func doHeaderRequest(
+	h http.Handler,
+	method, target string,
+	header http.Header,
+	reqBody io.Reader,
+) *http.Response {
+	r := httptest.NewRequest(method, target, reqBody)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Result()
+}
+
+// setTypes returns a registry of the Set games of the given service alone
+func setTypes(s *Sets) *games.Registry {
+	types := games.NewRegistry()
+	types.Register(rooms.Set, s.GameType())
+	return types
+}